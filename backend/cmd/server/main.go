@@ -14,6 +14,9 @@ import (
 	"rent-help-backend/internal/middleware"
 	"rent-help-backend/internal/services"
 	"rent-help-backend/pkg/database"
+	"rent-help-backend/pkg/mailer"
+	"rent-help-backend/pkg/sms"
+	"rent-help-backend/pkg/storage"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -38,9 +41,27 @@ func main() {
 
 	// Initialize services
 	userService := services.NewUserService(db)
+	auditService := services.NewAuditService(db)
+	tokenService := services.NewTokenService(db, cfg)
+	mail := newMailer(cfg)
+	verificationService := services.NewVerificationService(userService, mail, cfg)
+	passwordResetService := services.NewPasswordResetService(db, userService, tokenService, mail, cfg)
+	loginThrottleService := services.NewLoginThrottleService(db, auditService)
+	twoFactorService := services.NewTwoFactorService(db, cfg)
+	apiKeyService := services.NewAPIKeyService(db)
 	propertyService := services.NewPropertyService(db)
-	bookingService := services.NewBookingService(db)
+	propertyImageService := services.NewPropertyImageService(db, newBlobStore(cfg), cfg.MaxImages)
+	calendarService := services.NewCalendarService(db)
+	bookingService := services.NewBookingService(db, calendarService)
+	favoriteService := services.NewFavoriteService(db)
+	analyticsService := services.NewAnalyticsService(db)
+	savedSearchService := services.NewSavedSearchService(db)
+	notificationService := services.NewNotificationService(db, mail, sms.NewOutboxSender(cfg.SMSOutboxDir))
+	searchAlertService := services.NewSearchAlertService(savedSearchService, propertyService, userService, notificationService, handlers.ParseSavedSearch, cfg)
 	seedService := services.NewSeedService(db)
+	publicProfileService := services.NewPublicProfileService(db)
+	oidcService := services.NewOIDCService(db, userService, tokenService, cfg)
+	accountService := services.NewAccountService(db, userService, propertyService, bookingService, favoriteService, savedSearchService, notificationService, tokenService, apiKeyService, oidcService, auditService)
 
 	// Seed database with initial data if empty
 	ctx := context.Background()
@@ -48,29 +69,107 @@ func main() {
 		log.Printf("Warning: Failed to seed database: %v", err)
 	}
 
+	// Ensure indexes
+	if err := tokenService.EnsureIndexes(); err != nil {
+		log.Printf("Warning: Failed to create refresh token indexes: %v", err)
+	}
+	if err := passwordResetService.EnsureIndexes(); err != nil {
+		log.Printf("Warning: Failed to create password reset indexes: %v", err)
+	}
+	if err := auditService.EnsureIndexes(); err != nil {
+		log.Printf("Warning: Failed to create audit log indexes: %v", err)
+	}
+	if err := userService.BackfillActive(auditService); err != nil {
+		log.Printf("Warning: Failed to backfill active users: %v", err)
+	}
+	if err := loginThrottleService.EnsureIndexes(); err != nil {
+		log.Printf("Warning: Failed to create login throttle indexes: %v", err)
+	}
+	if err := propertyService.EnsureIndexes(); err != nil {
+		log.Printf("Warning: Failed to create property indexes: %v", err)
+	}
+	if err := propertyService.BackfillSearchTerms(); err != nil {
+		log.Printf("Warning: Failed to backfill property search terms: %v", err)
+	}
+	if err := propertyService.BackfillStatus(); err != nil {
+		log.Printf("Warning: Failed to backfill property status: %v", err)
+	}
+	if err := calendarService.EnsureIndexes(); err != nil {
+		log.Printf("Warning: Failed to create calendar indexes: %v", err)
+	}
+	if err := favoriteService.EnsureIndexes(); err != nil {
+		log.Printf("Warning: Failed to create favorite indexes: %v", err)
+	}
+	if err := analyticsService.EnsureIndexes(); err != nil {
+		log.Printf("Warning: Failed to create analytics indexes: %v", err)
+	}
+	if err := savedSearchService.EnsureIndexes(); err != nil {
+		log.Printf("Warning: Failed to create saved search indexes: %v", err)
+	}
+	if err := notificationService.EnsureIndexes(); err != nil {
+		log.Printf("Warning: Failed to create notification indexes: %v", err)
+	}
+	if err := apiKeyService.EnsureIndexes(); err != nil {
+		log.Printf("Warning: Failed to create API key indexes: %v", err)
+	}
+	if err := oidcService.EnsureIndexes(); err != nil {
+		log.Printf("Warning: Failed to create OIDC indexes: %v", err)
+	}
+
 	// Initialize handlers
-	userHandler := handlers.NewUserHandler(userService, cfg)
-	propertyHandler := handlers.NewPropertyHandler(propertyService)
-	bookingHandler := handlers.NewBookingHandler(bookingService)
+	userHandler := handlers.NewUserHandler(userService, tokenService, verificationService, passwordResetService, loginThrottleService, twoFactorService, cfg)
+	propertyHandler := handlers.NewPropertyHandler(propertyService, propertyImageService, calendarService, favoriteService, analyticsService)
+	propertyImageHandler := handlers.NewPropertyImageHandler(propertyService, propertyImageService, cfg.MaxUploadSize, cfg.MaxImages)
+	calendarHandler := handlers.NewCalendarHandler(propertyService, calendarService)
+	favoriteHandler := handlers.NewFavoriteHandler(propertyService, favoriteService)
+	analyticsHandler := handlers.NewAnalyticsHandler(propertyService, analyticsService)
+	savedSearchHandler := handlers.NewSavedSearchHandler(savedSearchService)
+	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	bookingHandler := handlers.NewBookingHandler(bookingService, propertyService, calendarService)
+	adminHandler := handlers.NewAdminHandler(userService, propertyService, bookingService, tokenService, passwordResetService, auditService)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+	accountHandler := handlers.NewAccountHandler(accountService, userService, twoFactorService, verificationService)
+	sessionHandler := handlers.NewSessionHandler(tokenService)
+	publicProfileHandler := handlers.NewPublicProfileHandler(publicProfileService)
+	oidcHandler := handlers.NewOIDCHandler(oidcService, userService, tokenService, twoFactorService, cfg)
 
 	// Setup Gin router
 	router := gin.Default()
 
+	// Only trust X-Forwarded-For from the configured proxies, so that
+	// c.ClientIP() cannot be spoofed by clients.
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+
 	// CORS middleware
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3001", "http://localhost:3000"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "Accept", "X-Requested-With"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "Accept", "X-Requested-With", "X-API-Key"},
+		ExposeHeaders:    []string{"Content-Length", "Retry-After"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
+	// Uploaded files
+	if cfg.StorageDriver == "local" {
+		router.Static("/uploads", cfg.UploadDir)
+	}
+
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
 
+	authMiddleware := middleware.AuthMiddleware(cfg, tokenService, userService, apiKeyService)
+	requireVerified := middleware.RequireVerifiedEmail()
+	denyAPIKeys := middleware.DenyAPIKeys()
+	propertiesRead := middleware.RequireScope(services.ScopePropertiesRead)
+	propertiesWrite := middleware.RequireScope(services.ScopePropertiesWrite)
+	bookingsRead := middleware.RequireScope(services.ScopeBookingsRead)
+	bookingsWrite := middleware.RequireScope(services.ScopeBookingsWrite)
+
 	// API routes
 	api := router.Group("/api/v1")
 	{
@@ -80,37 +179,99 @@ func main() {
 			auth.POST("/register", userHandler.Register)
 			auth.POST("/login", userHandler.Login)
 			auth.POST("/refresh", userHandler.RefreshToken)
+			auth.POST("/logout", authMiddleware, denyAPIKeys, userHandler.Logout)
+			auth.POST("/2fa/verify", userHandler.VerifyTwoFactorLogin)
+			auth.GET("/verify", userHandler.VerifyEmail)
+			auth.POST("/password/forgot", userHandler.ForgotPassword)
+			auth.POST("/password/reset", userHandler.ResetPassword)
+			auth.GET("/oidc/providers", oidcHandler.GetProviders)
+			auth.GET("/oidc/:provider/login", oidcHandler.Login)
+			auth.GET("/oidc/:provider/callback", oidcHandler.Callback)
 		}
 
 		// Protected routes
 		protected := api.Group("")
-		protected.Use(middleware.AuthMiddleware(cfg))
+		protected.Use(authMiddleware)
 		{
 			// User routes
 			users := protected.Group("/users")
+			users.Use(denyAPIKeys)
 			{
 				users.GET("/profile", userHandler.GetProfile)
+				users.GET("/:id/public", publicProfileHandler.GetPublicProfile)
 				users.PUT("/profile", userHandler.UpdateProfile)
+				users.PATCH("/profile", userHandler.UpdateProfile)
+				users.POST("/verification/resend", userHandler.ResendVerification)
+				users.POST("/me/2fa/setup", userHandler.SetupTwoFactor)
+				users.POST("/me/2fa/confirm", userHandler.ConfirmTwoFactor)
+				users.POST("/me/2fa/disable", userHandler.DisableTwoFactor)
+				users.GET("/me/api-keys", apiKeyHandler.GetAPIKeys)
+				users.POST("/me/api-keys", apiKeyHandler.CreateAPIKey)
+				users.DELETE("/me/api-keys/:id", apiKeyHandler.RevokeAPIKey)
+				users.GET("/me/export", accountHandler.ExportAccount)
+				users.POST("/me/delete-confirmation", accountHandler.SendDeletionConfirmation)
+				users.DELETE("/me", accountHandler.DeleteAccount)
+				users.GET("/me/sessions", sessionHandler.GetSessions)
+				users.DELETE("/me/sessions/:id", sessionHandler.RevokeSession)
+				users.GET("/me/identities", oidcHandler.GetIdentities)
+				users.POST("/me/identities/:provider", oidcHandler.LinkIdentity)
+				users.DELETE("/me/identities/:provider", oidcHandler.UnlinkIdentity)
+				users.GET("/me/favorites", favoriteHandler.GetFavorites)
+				users.POST("/me/favorites", favoriteHandler.AddFavorite)
+				users.DELETE("/me/favorites/:propertyId", favoriteHandler.RemoveFavorite)
+				users.GET("/me/saved-searches", savedSearchHandler.GetSavedSearches)
+				users.POST("/me/saved-searches", savedSearchHandler.CreateSavedSearch)
+				users.PATCH("/me/saved-searches/:id", savedSearchHandler.UpdateSavedSearch)
+				users.DELETE("/me/saved-searches/:id", savedSearchHandler.DeleteSavedSearch)
+				users.GET("/me/notifications", notificationHandler.GetNotifications)
 			}
 
 			// Property routes
 			properties := protected.Group("/properties")
 			{
-				properties.GET("", propertyHandler.GetProperties)
-				properties.GET("/:id", propertyHandler.GetProperty)
-				properties.POST("", propertyHandler.CreateProperty)
-				properties.PUT("/:id", propertyHandler.UpdateProperty)
-				properties.DELETE("/:id", propertyHandler.DeleteProperty)
+				properties.GET("", propertiesRead, propertyHandler.GetProperties)
+				properties.GET("/:id", propertiesRead, propertyHandler.GetProperty)
+				properties.POST("", propertiesWrite, middleware.RequireRole(middleware.RoleLandlord), requireVerified, propertyHandler.CreateProperty)
+				properties.PUT("/:id", propertiesWrite, propertyHandler.UpdateProperty)
+				properties.PATCH("/:id", propertiesWrite, propertyHandler.UpdateProperty)
+				properties.DELETE("/:id", propertiesWrite, propertyHandler.DeleteProperty)
+				properties.PUT("/:id/status", propertiesWrite, propertyHandler.UpdatePropertyStatus)
+				properties.GET("/:id/images", propertiesRead, propertyImageHandler.GetImages)
+				properties.POST("/:id/images", propertiesWrite, propertyImageHandler.UploadImages)
+				properties.PUT("/:id/images/order", propertiesWrite, propertyImageHandler.ReorderImages)
+				properties.PUT("/:id/images/:imageId/primary", propertiesWrite, propertyImageHandler.SetPrimaryImage)
+				properties.DELETE("/:id/images/:imageId", propertiesWrite, propertyImageHandler.DeleteImage)
+				properties.GET("/:id/calendar", propertiesRead, calendarHandler.GetCalendar)
+				properties.PUT("/:id/calendar", propertiesWrite, calendarHandler.UpdateCalendar)
+				properties.GET("/:id/stats", propertiesRead, analyticsHandler.GetPropertyStats)
 			}
 
 			// Booking routes
 			bookings := protected.Group("/bookings")
 			{
-				bookings.GET("", bookingHandler.GetBookings)
-				bookings.GET("/:id", bookingHandler.GetBooking)
-				bookings.POST("", bookingHandler.CreateBooking)
-				bookings.PUT("/:id", bookingHandler.UpdateBooking)
-				bookings.DELETE("/:id", bookingHandler.DeleteBooking)
+				bookings.GET("", bookingsRead, bookingHandler.GetBookings)
+				bookings.GET("/:id", bookingsRead, bookingHandler.GetBooking)
+				bookings.POST("", bookingsWrite, requireVerified, bookingHandler.CreateBooking)
+				bookings.PUT("/:id", bookingsWrite, bookingHandler.UpdateBooking)
+				bookings.PATCH("/:id", bookingsWrite, bookingHandler.UpdateBooking)
+				bookings.PUT("/:id/status", bookingsWrite, bookingHandler.UpdateBookingStatus)
+				bookings.DELETE("/:id", bookingsWrite, bookingHandler.DeleteBooking)
+			}
+
+			// Admin routes
+			admin := protected.Group("/admin")
+			admin.Use(denyAPIKeys, middleware.RequireRole(middleware.RoleAdmin))
+			{
+				admin.GET("/stats", adminHandler.GetStats)
+				admin.GET("/users", adminHandler.GetUsers)
+				admin.GET("/users/:id", adminHandler.GetUser)
+				admin.PUT("/users/:id/status", adminHandler.UpdateUserStatus)
+				admin.PUT("/users/:id/role", adminHandler.UpdateUserRole)
+				admin.POST("/users/:id/password-reset", adminHandler.ForcePasswordReset)
+				admin.GET("/users/:id/properties", adminHandler.GetUserProperties)
+				admin.GET("/users/:id/bookings", adminHandler.GetUserBookings)
+				admin.GET("/properties", adminHandler.GetProperties)
+				admin.PUT("/properties/:id/status", adminHandler.UpdatePropertyStatus)
 			}
 		}
 	}
@@ -130,6 +291,13 @@ func main() {
 
 	log.Printf("Server started on port %s", cfg.Port)
 
+	// New-listing alerts for saved searches
+	alertCtx, stopAlerts := context.WithCancel(context.Background())
+	defer stopAlerts()
+	if cfg.AlertInterval > 0 {
+		go searchAlertService.Run(alertCtx, cfg.AlertInterval)
+	}
+
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -145,3 +313,20 @@ func main() {
 
 	log.Println("Server exiting")
 }
+
+func newMailer(cfg *config.Config) mailer.Mailer {
+	if cfg.MailDriver == "smtp" {
+		return mailer.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	}
+	return mailer.NewOutboxMailer(cfg.MailOutboxDir, cfg.MailFrom)
+}
+
+// newBlobStore returns the store for uploaded files. Only local disk storage
+// is available so far; the files are served from /uploads.
+func newBlobStore(cfg *config.Config) storage.BlobStore {
+	if cfg.StorageDriver != "local" {
+		log.Printf("Warning: Unknown STORAGE_DRIVER %q, using local storage", cfg.StorageDriver)
+		cfg.StorageDriver = "local"
+	}
+	return storage.NewLocalStore(cfg.UploadDir, cfg.UploadURL)
+}
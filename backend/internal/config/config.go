@@ -2,22 +2,107 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	Port      string
-	MongoURI  string
-	JWTSecret string
-	DBName    string
+	Port            string
+	MongoURI        string
+	JWTSecret       string
+	DBName          string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	PublicURL       string
+	TrustedProxies  []string
+	AppURL          string
+	MailDriver      string
+	MailFrom        string
+	MailOutboxDir   string
+	SMTPHost        string
+	SMTPPort        string
+	SMTPUsername    string
+	SMTPPassword    string
+	SMSOutboxDir    string
+	OIDCProviders   []OIDCProvider
+	StorageDriver   string
+	UploadDir       string
+	UploadURL       string
+	MaxUploadSize   int64
+	MaxImages       int
+	AlertInterval   time.Duration
+}
+
+// OIDCProvider is an OpenID Connect provider users can sign in with. The
+// endpoints are discovered from the issuer.
+type OIDCProvider struct {
+	Name         string
+	DisplayName  string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
 }
 
 func Load() *Config {
+	publicURL := getEnv("PUBLIC_URL", "http://localhost:8080")
+
 	return &Config{
-		Port:      getEnv("PORT", "8080"),
-		MongoURI:  getEnv("MONGODB_URI", "mongodb://localhost:27017"),
-		JWTSecret: getEnv("JWT_SECRET", "your-secret-key"),
-		DBName:    getEnv("DB_NAME", "rent_help"),
+		Port:            getEnv("PORT", "8080"),
+		MongoURI:        getEnv("MONGODB_URI", "mongodb://localhost:27017"),
+		JWTSecret:       getEnv("JWT_SECRET", "your-secret-key"),
+		DBName:          getEnv("DB_NAME", "rent_help"),
+		AccessTokenTTL:  getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		PublicURL:       publicURL,
+		TrustedProxies:  getEnvList("TRUSTED_PROXIES"),
+		AppURL:          getEnv("APP_URL", "http://localhost:3000"),
+		MailDriver:      getEnv("MAIL_DRIVER", "outbox"),
+		MailFrom:        getEnv("MAIL_FROM", "RentHelp <no-reply@renthelp.com>"),
+		MailOutboxDir:   getEnv("MAIL_OUTBOX_DIR", "./tmp/outbox"),
+		SMTPHost:        getEnv("SMTP_HOST", ""),
+		SMTPPort:        getEnv("SMTP_PORT", "587"),
+		SMTPUsername:    getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:    getEnv("SMTP_PASSWORD", ""),
+		SMSOutboxDir:    getEnv("SMS_OUTBOX_DIR", "./tmp/sms"),
+		OIDCProviders:   loadOIDCProviders(),
+		StorageDriver:   getEnv("STORAGE_DRIVER", "local"),
+		UploadDir:       getEnv("UPLOAD_DIR", "./uploads"),
+		UploadURL:       getEnv("UPLOAD_URL", strings.TrimSuffix(publicURL, "/")+"/uploads"),
+		MaxUploadSize:   getEnvInt64("MAX_UPLOAD_SIZE", 10<<20),
+		MaxImages:       int(getEnvInt64("MAX_PROPERTY_IMAGES", 20)),
+		AlertInterval:   getEnvDuration("SEARCH_ALERT_INTERVAL", 5*time.Minute),
+	}
+}
+
+// loadOIDCProviders reads OIDC_PROVIDERS, a comma separated list of provider
+// names, and OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET, _SCOPES and
+// _DISPLAY_NAME for each of them. Providers without an issuer or client ID
+// are skipped.
+func loadOIDCProviders() []OIDCProvider {
+	var providers []OIDCProvider
+	for _, name := range strings.Split(getEnv("OIDC_PROVIDERS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		provider := OIDCProvider{
+			Name:         name,
+			DisplayName:  getEnv(prefix+"DISPLAY_NAME", name),
+			Issuer:       getEnv(prefix+"ISSUER", ""),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			Scopes:       strings.Fields(getEnv(prefix+"SCOPES", "openid email profile")),
+		}
+		if provider.Issuer == "" || provider.ClientID == "" {
+			continue
+		}
+		providers = append(providers, provider)
 	}
+	return providers
 }
 
 func getEnv(key, defaultValue string) string {
@@ -26,3 +111,33 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvList reads a comma separated list. It is nil when the variable is unset
+// or empty.
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
+
+func getEnvInt64(key string, defaultValue int64) int64 {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultValue
+}
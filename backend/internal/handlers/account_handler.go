@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"rent-help-backend/internal/models"
+	"rent-help-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AccountHandler struct {
+	accountService      *services.AccountService
+	userService         *services.UserService
+	twoFactorService    *services.TwoFactorService
+	verificationService *services.VerificationService
+}
+
+func NewAccountHandler(accountService *services.AccountService, userService *services.UserService, twoFactorService *services.TwoFactorService, verificationService *services.VerificationService) *AccountHandler {
+	return &AccountHandler{
+		accountService:      accountService,
+		userService:         userService,
+		twoFactorService:    twoFactorService,
+		verificationService: verificationService,
+	}
+}
+
+// ExportAccount downloads everything stored about the current user as a
+// single JSON document, or as a ZIP with one JSON file per section when
+// format=zip.
+func (h *AccountHandler) ExportAccount(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format must be json or zip"})
+		return
+	}
+
+	export, err := h.accountService.Export(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export account data"})
+		return
+	}
+
+	filename := fmt.Sprintf("renthelp-export-%s-%s", user.ID.Hex(), export.ExportedAt.Format("20060102"))
+	if format == "json" {
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`.json"`)
+		c.IndentedJSON(http.StatusOK, export)
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`.zip"`)
+	c.Header("Content-Type", "application/zip")
+	c.Status(http.StatusOK)
+	if err := writeExportZip(c.Writer, export); err != nil {
+		// Headers are already sent, so the client sees a truncated archive.
+		log.Printf("Failed to write account export for %s: %v", user.ID.Hex(), err)
+	}
+}
+
+// SendDeletionConfirmation emails the current user a token that confirms
+// DeleteAccount instead of the password, for accounts created through an
+// identity provider that never had a password of their own.
+func (h *AccountHandler) SendDeletionConfirmation(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+
+	if err := h.verificationService.SendAccountDeletionEmail(user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send confirmation email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Confirmation email sent"})
+}
+
+// DeleteAccount anonymises the current user after re-checking their password
+// or an emailed confirmation token and, if enabled, a two-factor code.
+func (h *AccountHandler) DeleteAccount(c *gin.Context) {
+	var req models.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+
+	switch {
+	case req.ConfirmationToken != "":
+		if err := h.verificationService.VerifyAccountDeletion(user, req.ConfirmationToken); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired confirmation token"})
+			return
+		}
+	case req.Password != "":
+		if err := h.userService.ValidatePassword(user.Password, req.Password); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password or confirmation token is required"})
+		return
+	}
+
+	if user.TwoFactor.Enabled {
+		if err := h.twoFactorService.VerifyCode(user, req.Code); err != nil {
+			if err == services.ErrInvalidTwoFactorCode {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify two-factor code"})
+			return
+		}
+	}
+
+	if err := h.accountService.Delete(user); err != nil {
+		if err == services.ErrAccountHasActiveBookings {
+			c.JSON(http.StatusConflict, gin.H{"error": "Account has bookings that are still in progress"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account deleted successfully"})
+}
+
+func writeExportZip(w http.ResponseWriter, export *models.AccountExport) error {
+	archive := zip.NewWriter(w)
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"properties.json", export.Properties},
+		{"bookings.json", export.Bookings},
+		{"messages.json", export.Messages},
+		{"payments.json", export.Payments},
+	}
+	for _, file := range files {
+		entry, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     file.name,
+			Method:   zip.Deflate,
+			Modified: export.ExportedAt.In(time.UTC),
+		})
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(entry)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
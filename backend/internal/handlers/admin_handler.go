@@ -0,0 +1,418 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"rent-help-backend/internal/models"
+	"rent-help-backend/internal/services"
+	"rent-help-backend/pkg/listing"
+	"rent-help-backend/pkg/pagination"
+	"rent-help-backend/pkg/validation"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type AdminHandler struct {
+	userService          *services.UserService
+	propertyService      *services.PropertyService
+	bookingService       *services.BookingService
+	tokenService         *services.TokenService
+	passwordResetService *services.PasswordResetService
+	auditService         *services.AuditService
+}
+
+func NewAdminHandler(userService *services.UserService, propertyService *services.PropertyService, bookingService *services.BookingService, tokenService *services.TokenService, passwordResetService *services.PasswordResetService, auditService *services.AuditService) *AdminHandler {
+	return &AdminHandler{
+		userService:          userService,
+		propertyService:      propertyService,
+		bookingService:       bookingService,
+		tokenService:         tokenService,
+		passwordResetService: passwordResetService,
+		auditService:         auditService,
+	}
+}
+
+func (h *AdminHandler) GetStats(c *gin.Context) {
+	users, err := h.userService.CountUsers(bson.M{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count users"})
+		return
+	}
+
+	landlords, err := h.userService.CountUsers(bson.M{"role": "landlord"})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count users"})
+		return
+	}
+
+	properties, err := h.propertyService.CountProperties(bson.M{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count properties"})
+		return
+	}
+
+	bookings, err := h.bookingService.CountBookings(bson.M{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count bookings"})
+		return
+	}
+
+	pendingBookings, err := h.bookingService.CountBookings(bson.M{"status": "pending"})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count bookings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users":            users,
+		"landlords":        landlords,
+		"properties":       properties,
+		"bookings":         bookings,
+		"pending_bookings": pendingBookings,
+	})
+}
+
+// GetUsers searches users by email or name (q) and role, newest first.
+func (h *AdminHandler) GetUsers(c *gin.Context) {
+	validator := validation.NewValidator()
+	page := parsePage(c, validator, services.UserSorts, newestFirst)
+
+	filter := bson.M{}
+	if q := c.Query("q"); q != "" {
+		pattern := bson.M{"$regex": regexp.QuoteMeta(q), "$options": "i"}
+		filter["$or"] = bson.A{
+			bson.M{"email": pattern},
+			bson.M{"full_name": pattern},
+		}
+	}
+	if role := c.Query("role"); role != "" {
+		filter["role"] = role
+	}
+	if active := c.Query("is_active"); active != "" {
+		isActive, err := strconv.ParseBool(active)
+		if err != nil {
+			validator.AddError("is_active", "is_active must be true or false")
+		} else {
+			filter["is_active"] = isActive
+		}
+	}
+	if validator.HasErrors() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": validator.GetErrors()})
+		return
+	}
+
+	users, nextCursor, err := h.userService.ListUsers(filter, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get users"})
+		return
+	}
+
+	total, err := h.userService.CountUsers(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count users"})
+		return
+	}
+
+	h.audit(c, "admin.users.search", "", map[string]interface{}{
+		"q":         c.Query("q"),
+		"role":      c.Query("role"),
+		"is_active": c.Query("is_active"),
+	})
+
+	c.JSON(http.StatusOK, listResponse(users, nextCursor, total))
+}
+
+func (h *AdminHandler) GetUser(c *gin.Context) {
+	user, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
+
+	h.audit(c, "admin.users.view", user.ID.Hex(), nil)
+
+	c.JSON(http.StatusOK, user)
+}
+
+// UpdateUserStatus activates or deactivates an account. Deactivated users are
+// rejected by the auth middleware and their refresh tokens are revoked.
+func (h *AdminHandler) UpdateUserStatus(c *gin.Context) {
+	var req models.UpdateUserStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
+
+	if user.ID.Hex() == c.GetString("user_id") && !*req.IsActive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot deactivate your own account"})
+		return
+	}
+
+	// deactivated_at tells an admin's decision apart from accounts that were
+	// never activated, which UserService.BackfillActive turns on.
+	var deactivatedAt *time.Time
+	if !*req.IsActive {
+		now := time.Now()
+		deactivatedAt = &now
+	}
+	if err := h.userService.UpdateUser(user.ID, bson.M{"is_active": *req.IsActive, "deactivated_at": deactivatedAt}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return
+	}
+
+	if !*req.IsActive {
+		if err := h.tokenService.RevokeAllForUser(user.ID); err != nil {
+			log.Printf("Failed to revoke tokens for deactivated user %s: %v", user.ID.Hex(), err)
+		}
+	}
+
+	h.audit(c, "admin.users.status", user.ID.Hex(), map[string]interface{}{
+		"from": user.IsActive,
+		"to":   *req.IsActive,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "User status updated successfully"})
+}
+
+func (h *AdminHandler) UpdateUserRole(c *gin.Context) {
+	var req models.UpdateUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
+
+	if user.ID.Hex() == c.GetString("user_id") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot change your own role"})
+		return
+	}
+
+	if err := h.userService.UpdateUser(user.ID, bson.M{"role": req.Role}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return
+	}
+
+	h.audit(c, "admin.users.role", user.ID.Hex(), map[string]interface{}{
+		"from": user.Role,
+		"to":   req.Role,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "User role updated successfully"})
+}
+
+// ForcePasswordReset invalidates the user's password and sessions and emails
+// them a reset link.
+func (h *AdminHandler) ForcePasswordReset(c *gin.Context) {
+	user, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.passwordResetService.ForceReset(user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	h.audit(c, "admin.users.password_reset", user.ID.Hex(), nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset email sent"})
+}
+
+func (h *AdminHandler) GetUserProperties(c *gin.Context) {
+	validator := validation.NewValidator()
+	page := parsePage(c, validator, services.PropertySorts, newestFirst)
+	if page.Sort.Field == "distance" || page.Sort.Field == "relevance" {
+		validator.AddError("sort", "Sorting by "+page.Sort.Field+" is only available when searching")
+	}
+	if validator.HasErrors() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": validator.GetErrors()})
+		return
+	}
+
+	user, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
+
+	filter := bson.M{"owner_id": user.ID}
+	properties, nextCursor, err := h.propertyService.ListProperties(filter, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get properties"})
+		return
+	}
+
+	total, err := h.propertyService.CountProperties(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count properties"})
+		return
+	}
+
+	h.audit(c, "admin.users.properties", user.ID.Hex(), nil)
+
+	c.JSON(http.StatusOK, listResponse(properties, nextCursor, total))
+}
+
+// GetProperties lists listings in any state, by default those waiting for
+// review, oldest first.
+func (h *AdminHandler) GetProperties(c *gin.Context) {
+	validator := validation.NewValidator()
+	page := parsePage(c, validator, services.PropertySorts, pagination.Sort{Field: "created_at"})
+	if page.Sort.Field == "distance" || page.Sort.Field == "relevance" {
+		validator.AddError("sort", "Sorting by "+page.Sort.Field+" is only available when searching")
+	}
+	status := c.DefaultQuery("status", listing.PendingReview)
+	validator.ValidateOneOf("status", status, listing.States, "Status")
+	if validator.HasErrors() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": validator.GetErrors()})
+		return
+	}
+
+	filter := bson.M{"status": status}
+	properties, nextCursor, err := h.propertyService.ListProperties(filter, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get properties"})
+		return
+	}
+
+	total, err := h.propertyService.CountProperties(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count properties"})
+		return
+	}
+
+	c.JSON(http.StatusOK, listResponse(properties, nextCursor, total))
+}
+
+// UpdatePropertyStatus publishes a listing that is waiting for review, or
+// sends it back to draft with a reason.
+func (h *AdminHandler) UpdatePropertyStatus(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid property ID"})
+		return
+	}
+
+	adminID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.UpdatePropertyStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	validator := validatePropertyStatus(&req)
+	if validator.HasErrors() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": validator.GetErrors()})
+		return
+	}
+
+	property, err := h.propertyService.GetPropertyByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Property not found"})
+		return
+	}
+
+	from := property.Status
+	if changeStatus(c, h.propertyService, property, &req, listing.Reviewer, adminID) {
+		h.auditTarget(c, "admin.properties.status", "property", id.Hex(), map[string]interface{}{
+			"from":   from,
+			"to":     req.Status,
+			"reason": req.Reason,
+		})
+	}
+}
+
+// GetUserBookings returns bookings where the user is either tenant or landlord.
+func (h *AdminHandler) GetUserBookings(c *gin.Context) {
+	validator := validation.NewValidator()
+	page := parsePage(c, validator, services.BookingSorts, newestFirst)
+	if validator.HasErrors() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": validator.GetErrors()})
+		return
+	}
+
+	user, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
+
+	filter := bson.M{"$or": bson.A{
+		bson.M{"tenant_id": user.ID},
+		bson.M{"landlord_id": user.ID},
+	}}
+	bookings, nextCursor, err := h.bookingService.ListBookings(filter, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get bookings"})
+		return
+	}
+
+	total, err := h.bookingService.CountBookings(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count bookings"})
+		return
+	}
+
+	h.audit(c, "admin.users.bookings", user.ID.Hex(), nil)
+
+	c.JSON(http.StatusOK, listResponse(bookings, nextCursor, total))
+}
+
+func (h *AdminHandler) targetUser(c *gin.Context) (*models.User, bool) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return nil, false
+	}
+
+	user, err := h.userService.GetUserByID(id)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return nil, false
+	}
+	return user, true
+}
+
+// audit records an admin action on a user. Failures are logged rather than
+// returned so that the action itself is not reported as failed.
+func (h *AdminHandler) audit(c *gin.Context, action, targetID string, metadata map[string]interface{}) {
+	h.auditTarget(c, action, "user", targetID, metadata)
+}
+
+func (h *AdminHandler) auditTarget(c *gin.Context, action, targetType, targetID string, metadata map[string]interface{}) {
+	entry := &models.AuditLog{
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		IP:         c.ClientIP(),
+		Metadata:   metadata,
+	}
+	if actorID, err := primitive.ObjectIDFromHex(c.GetString("user_id")); err == nil {
+		entry.ActorID = &actorID
+	}
+
+	if err := h.auditService.Record(entry); err != nil {
+		log.Printf("Failed to audit %s: %v", action, err)
+	}
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"rent-help-backend/internal/services"
+	"rent-help-backend/pkg/calendar"
+	"rent-help-backend/pkg/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Statistics cover the last 30 days by default and at most a year.
+const (
+	defaultStatsDays = 30
+	maxStatsDays     = 366
+)
+
+type AnalyticsHandler struct {
+	propertyService  *services.PropertyService
+	analyticsService *services.AnalyticsService
+}
+
+func NewAnalyticsHandler(propertyService *services.PropertyService, analyticsService *services.AnalyticsService) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		propertyService:  propertyService,
+		analyticsService: analyticsService,
+	}
+}
+
+// GetPropertyStats returns daily views, favorites, booking requests and
+// conversion rates of a listing to its owner.
+func (h *AnalyticsHandler) GetPropertyStats(c *gin.Context) {
+	validator := validation.NewValidator()
+	today := calendar.Date(time.Now().UTC())
+	days := parseDateRange(c, validator, today.AddDate(0, 0, 1-defaultStatsDays), defaultStatsDays, maxStatsDays)
+	if validator.HasErrors() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": validator.GetErrors()})
+		return
+	}
+
+	property, ok := ownedProperty(c, h.propertyService)
+	if !ok {
+		return
+	}
+
+	stats, err := h.analyticsService.PropertyStats(property.ID, days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get property statistics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
@@ -2,10 +2,13 @@ package handlers
 
 import (
 	"net/http"
-	"strconv"
+	"time"
 
 	"rent-help-backend/internal/models"
 	"rent-help-backend/internal/services"
+	"rent-help-backend/pkg/calendar"
+	"rent-help-backend/pkg/listing"
+	"rent-help-backend/pkg/validation"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
@@ -13,12 +16,16 @@ import (
 )
 
 type BookingHandler struct {
-	bookingService *services.BookingService
+	bookingService  *services.BookingService
+	propertyService *services.PropertyService
+	calendarService *services.CalendarService
 }
 
-func NewBookingHandler(bookingService *services.BookingService) *BookingHandler {
+func NewBookingHandler(bookingService *services.BookingService, propertyService *services.PropertyService, calendarService *services.CalendarService) *BookingHandler {
 	return &BookingHandler{
-		bookingService: bookingService,
+		bookingService:  bookingService,
+		propertyService: propertyService,
+		calendarService: calendarService,
 	}
 }
 
@@ -30,13 +37,56 @@ func (h *BookingHandler) CreateBooking(c *gin.Context) {
 		return
 	}
 
-	var booking models.Booking
-	if err := c.ShouldBindJSON(&booking); err != nil {
+	var req models.CreateBookingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	booking := models.Booking{
+		PropertyID:      req.PropertyID,
+		StartDate:       req.StartDate,
+		EndDate:         req.EndDate,
+		CheckInTime:     req.CheckInTime,
+		CheckOutTime:    req.CheckOutTime,
+		Message:         req.Message,
+		SpecialRequests: req.SpecialRequests,
+		GuestInfo:       req.GuestInfo,
+	}
+
+	validator := validation.NewValidator()
+	validateStayDates(validator, booking.StartDate, booking.EndDate)
+	if validator.HasErrors() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": validator.GetErrors()})
+		return
+	}
+
+	property, err := h.propertyService.GetPropertyByID(booking.PropertyID)
+	if err != nil || !canViewProperty(c, property) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Property not found"})
+		return
+	}
+	if !listing.Public(property.Status) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This property is not accepting bookings"})
+		return
+	}
 
 	booking.TenantID = tenantID
+	booking.LandlordID = property.OwnerID
+
+	if !h.checkAvailable(c, booking.PropertyID, booking.StartDate, booking.EndDate) {
+		return
+	}
+
+	// Amounts are priced from the listing and its calendar, never taken
+	// from the request. There are no fees yet.
+	rent, ok := h.stayPrice(c, property, booking.StartDate, booking.EndDate)
+	if !ok {
+		return
+	}
+	booking.RentAmount = rent
+	booking.TotalAmount = rent
+	booking.Currency = property.Currency
+	booking.PaymentStatus = "pending"
 
 	if err := h.bookingService.CreateBooking(&booking); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking"})
@@ -54,27 +104,32 @@ func (h *BookingHandler) GetBookings(c *gin.Context) {
 		return
 	}
 
-	// Parse query parameters
-	limitStr := c.DefaultQuery("limit", "10")
-	skipStr := c.DefaultQuery("skip", "0")
-	status := c.Query("status")
-
-	limit, _ := strconv.ParseInt(limitStr, 10, 64)
-	skip, _ := strconv.ParseInt(skipStr, 10, 64)
+	validator := validation.NewValidator()
+	page := parsePage(c, validator, services.BookingSorts, newestFirst)
+	if validator.HasErrors() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": validator.GetErrors()})
+		return
+	}
 
 	// Build filter - user can see their own bookings
 	filter := bson.M{"tenant_id": userID}
-	if status != "" {
+	if status := c.Query("status"); status != "" {
 		filter["status"] = status
 	}
 
-	bookings, err := h.bookingService.GetBookings(filter, limit, skip)
+	bookings, nextCursor, err := h.bookingService.ListBookings(filter, page)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get bookings"})
 		return
 	}
 
-	c.JSON(http.StatusOK, bookings)
+	total, err := h.bookingService.CountBookings(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count bookings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, listResponse(bookings, nextCursor, total))
 }
 
 func (h *BookingHandler) GetBooking(c *gin.Context) {
@@ -134,16 +189,47 @@ func (h *BookingHandler) UpdateBooking(c *gin.Context) {
 		return
 	}
 
-	var updates bson.M
-	if err := c.ShouldBindJSON(&updates); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var req models.UpdateBookingRequest
+	if !bindPatch(c, &req) {
+		return
+	}
+
+	// Dates are part of what the landlord agreed to, so they are fixed once
+	// the booking leaves the pending state.
+	if (req.StartDate != nil || req.EndDate != nil) && booking.Status != "pending" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Dates can only be changed while the booking is pending"})
 		return
 	}
 
-	// Remove sensitive fields
-	delete(updates, "_id")
-	delete(updates, "tenant_id")
-	delete(updates, "property_id")
+	updates, ok := patchUpdates(c, &req, validateBookingUpdate(&req, booking))
+	if !ok {
+		return
+	}
+
+	if req.StartDate != nil || req.EndDate != nil {
+		start, end := booking.StartDate, booking.EndDate
+		if req.StartDate != nil {
+			start = *req.StartDate
+		}
+		if req.EndDate != nil {
+			end = *req.EndDate
+		}
+		if !h.checkAvailable(c, booking.PropertyID, start, end) {
+			return
+		}
+
+		property, err := h.propertyService.GetPropertyByID(booking.PropertyID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Property not found"})
+			return
+		}
+		rent, ok := h.stayPrice(c, property, start, end)
+		if !ok {
+			return
+		}
+		updates["rent_amount"] = rent
+		updates["total_amount"] = rent + booking.ServiceFee + booking.CleaningFee
+	}
 
 	if err := h.bookingService.UpdateBooking(id, updates); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking"})
@@ -187,3 +273,161 @@ func (h *BookingHandler) DeleteBooking(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Booking deleted successfully"})
 }
+
+// UpdateBookingStatus confirms or cancels a booking. Only the landlord can
+// confirm a pending booking, which books its nights on the property
+// calendar; either party can cancel a pending or confirmed one.
+func (h *BookingHandler) UpdateBookingStatus(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.UpdateBookingStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	validator := validation.NewValidator()
+	validator.ValidateOneOf("status", req.Status, []string{"confirmed", "cancelled"}, "Status")
+	validator.ValidateMaxLength("reason", req.Reason, 1000, "Reason")
+	if validator.HasErrors() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": validator.GetErrors()})
+		return
+	}
+
+	booking, err := h.bookingService.GetBookingByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+
+	// Older bookings have no landlord_id, so the landlord is looked up from
+	// the property.
+	isLandlord := false
+	if property, err := h.propertyService.GetPropertyByID(booking.PropertyID); err == nil {
+		isLandlord = property.OwnerID == userID
+	}
+	if !isLandlord && booking.TenantID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to update this booking"})
+		return
+	}
+
+	switch req.Status {
+	case "confirmed":
+		if !isLandlord {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the landlord can confirm a booking"})
+			return
+		}
+		if booking.Status != "pending" {
+			c.JSON(http.StatusConflict, gin.H{"error": "Only pending bookings can be confirmed"})
+			return
+		}
+		err = h.bookingService.ConfirmBooking(booking)
+	case "cancelled":
+		if booking.Status != "pending" && booking.Status != "confirmed" {
+			c.JSON(http.StatusConflict, gin.H{"error": "Only pending or confirmed bookings can be cancelled"})
+			return
+		}
+		err = h.bookingService.CancelBooking(booking, userID, req.Reason)
+	}
+
+	switch err {
+	case nil:
+		c.JSON(http.StatusOK, booking)
+	case services.ErrDatesUnavailable:
+		c.JSON(http.StatusConflict, gin.H{"error": "The property is not available for the selected dates"})
+	case services.ErrBookingStatusChanged:
+		c.JSON(http.StatusConflict, gin.H{"error": "The booking was changed by another request, please retry"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking status"})
+	}
+}
+
+// stayPrice returns the rent for staying at property from start to end. On
+// failure it responds with 500.
+func (h *BookingHandler) stayPrice(c *gin.Context, property *models.Property, start, end time.Time) (float64, bool) {
+	rent, err := h.calendarService.StayPrice(property, calendar.NewRange(start, end))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to price booking"})
+		return 0, false
+	}
+	return rent, true
+}
+
+// checkAvailable responds with 409 if any night between start and end is
+// blocked or booked on the property calendar.
+func (h *BookingHandler) checkAvailable(c *gin.Context, propertyID primitive.ObjectID, start, end time.Time) bool {
+	err := h.calendarService.CheckAvailable(propertyID, calendar.NewRange(start, end))
+	switch err {
+	case nil:
+		return true
+	case services.ErrDatesUnavailable:
+		c.JSON(http.StatusConflict, gin.H{"error": "The property is not available for the selected dates"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check availability"})
+	}
+	return false
+}
+
+// validateStayDates checks a requested stay: it must end after it starts and
+// cannot start before today (UTC).
+func validateStayDates(validator *validation.Validator, start, end time.Time) {
+	if calendar.Date(start).Before(calendar.Date(time.Now().UTC())) {
+		validator.AddError("start_date", "Start date cannot be in the past")
+	}
+	validator.ValidateDateRange("end_date", start, end)
+}
+
+func validateBookingUpdate(req *models.UpdateBookingRequest, booking *models.Booking) *validation.Validator {
+	validator := validation.NewValidator()
+
+	if req.StartDate != nil || req.EndDate != nil {
+		start, end := booking.StartDate, booking.EndDate
+		if req.StartDate != nil {
+			start = *req.StartDate
+		}
+		if req.EndDate != nil {
+			end = *req.EndDate
+		}
+		if req.StartDate != nil {
+			validateStayDates(validator, start, end)
+		} else {
+			validator.ValidateDateRange("end_date", start, end)
+		}
+	}
+	if req.CheckInTime != nil {
+		validator.ValidateTimeOfDay("check_in_time", *req.CheckInTime, "Check-in time")
+	}
+	if req.CheckOutTime != nil {
+		validator.ValidateTimeOfDay("check_out_time", *req.CheckOutTime, "Check-out time")
+	}
+	if req.Message != nil {
+		validator.ValidateMaxLength("message", *req.Message, 2000, "Message")
+	}
+
+	if guests := req.GuestInfo; guests != nil {
+		if guests.Adults != nil && *guests.Adults < 1 {
+			validator.AddError("guest_info.adults", "At least one adult is required")
+		}
+		if guests.Children != nil && *guests.Children < 0 {
+			validator.AddError("guest_info.children", "Children cannot be negative")
+		}
+		if guests.Infants != nil && *guests.Infants < 0 {
+			validator.AddError("guest_info.infants", "Infants cannot be negative")
+		}
+		if guests.Purpose != nil {
+			validator.ValidateOneOf("guest_info.purpose", *guests.Purpose, []string{"vacation", "business", "relocation", "other"}, "Purpose")
+		}
+	}
+
+	return validator
+}
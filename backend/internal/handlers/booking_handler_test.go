@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"rent-help-backend/pkg/validation"
+)
+
+func TestValidateStayDates(t *testing.T) {
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+		want       []string
+	}{
+		{"starts today", today, today.Add(30 * day), nil},
+		{"starts later today", now, now.Add(day), nil},
+		{"starts tomorrow", today.Add(day), today.Add(2 * day), nil},
+		{"starts yesterday", today.Add(-day), today.Add(day), []string{"start_date"}},
+		{"ends before it starts", today.Add(2 * day), today.Add(day), []string{"end_date"}},
+		{"past and empty", today.Add(-day), today.Add(-day), []string{"start_date", "end_date"}},
+	}
+	for _, tt := range tests {
+		validator := validation.NewValidator()
+		validateStayDates(validator, tt.start, tt.end)
+		var fields []string
+		for _, e := range validator.GetErrors() {
+			fields = append(fields, e.Field)
+		}
+		if !reflect.DeepEqual(fields, tt.want) {
+			t.Errorf("%s: errors on fields %v, want %v", tt.name, fields, tt.want)
+		}
+	}
+}
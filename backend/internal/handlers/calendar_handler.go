@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"rent-help-backend/internal/models"
+	"rent-help-backend/internal/services"
+	"rent-help-backend/pkg/calendar"
+	"rent-help-backend/pkg/validation"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxCalendarRanges bounds the number of ranges a landlord can set in one
+// request.
+const maxCalendarRanges = 500
+
+// Calendar requests read a year by default and at most two years.
+const (
+	defaultCalendarDays = 365
+	maxCalendarDays     = 731
+)
+
+var calendarStatuses = []string{services.CalendarAvailable, services.CalendarBlocked}
+
+type CalendarHandler struct {
+	propertyService *services.PropertyService
+	calendarService *services.CalendarService
+}
+
+func NewCalendarHandler(propertyService *services.PropertyService, calendarService *services.CalendarService) *CalendarHandler {
+	return &CalendarHandler{
+		propertyService: propertyService,
+		calendarService: calendarService,
+	}
+}
+
+// GetCalendar returns the ranges between the from and to dates, by default
+// the year starting today. Only the owner sees notes and booking IDs.
+func (h *CalendarHandler) GetCalendar(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid property ID"})
+		return
+	}
+
+	validator := validation.NewValidator()
+	days := parseDateRange(c, validator, calendar.Date(time.Now()), defaultCalendarDays, maxCalendarDays)
+	if validator.HasErrors() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": validator.GetErrors()})
+		return
+	}
+
+	property, err := h.propertyService.GetPropertyByID(id)
+	if err != nil || !canViewProperty(c, property) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Property not found"})
+		return
+	}
+
+	ranges, err := h.calendarService.GetRanges(id, days.Start, days.End)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get calendar"})
+		return
+	}
+	if property.OwnerID.Hex() != c.GetString("user_id") {
+		for i := range ranges {
+			ranges[i].Note = ""
+			ranges[i].BookingID = nil
+		}
+	}
+
+	c.JSON(http.StatusOK, models.PropertyCalendar{
+		PropertyID: property.ID,
+		Price:      property.Price,
+		Currency:   property.Currency,
+		From:       days.Start,
+		To:         days.End,
+		Ranges:     ranges,
+	})
+}
+
+// UpdateCalendar replaces the available and blocked ranges of a property.
+// Dates are reduced to the calendar day; end_date is the first night after
+// the range.
+func (h *CalendarHandler) UpdateCalendar(c *gin.Context) {
+	var req models.UpdateCalendarRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ranges, validator := calendarRanges(req.Ranges)
+	if validator.HasErrors() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": validator.GetErrors()})
+		return
+	}
+
+	property, ok := ownedProperty(c, h.propertyService)
+	if !ok {
+		return
+	}
+
+	if err := h.calendarService.ReplaceRanges(property.ID, ranges); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update calendar"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Calendar updated successfully"})
+}
+
+// parseDateRange reads the from and to query parameters, YYYY-MM-DD dates
+// with to exclusive. Without from the range starts at defaultFrom, or
+// defaultDays before to; without to it spans defaultDays.
+func parseDateRange(c *gin.Context, validator *validation.Validator, defaultFrom time.Time, defaultDays, maxDays int) calendar.Range {
+	var days calendar.Range
+	var err error
+	from, to := c.Query("from"), c.Query("to")
+	if from != "" {
+		if days.Start, err = calendar.ParseDate(from); err != nil {
+			validator.AddError("from", "from must be a date in YYYY-MM-DD format")
+		}
+	}
+	if to != "" {
+		if days.End, err = calendar.ParseDate(to); err != nil {
+			validator.AddError("to", "to must be a date in YYYY-MM-DD format")
+		}
+	}
+	if validator.HasErrors() {
+		return days
+	}
+
+	switch {
+	case from == "" && to == "":
+		days.Start = defaultFrom
+		days.End = defaultFrom.AddDate(0, 0, defaultDays)
+	case from == "":
+		days.Start = days.End.AddDate(0, 0, -defaultDays)
+	case to == "":
+		days.End = days.Start.AddDate(0, 0, defaultDays)
+	}
+
+	if !days.Valid() {
+		validator.AddError("to", "to must be after from")
+	} else if days.Nights() > maxDays {
+		validator.AddError("to", "The range can span at most "+strconv.Itoa(maxDays)+" days")
+	}
+	return days
+}
+
+// calendarRanges validates the ranges of an update and converts them to
+// calendar ranges.
+func calendarRanges(inputs []models.CalendarRangeInput) ([]models.CalendarRange, *validation.Validator) {
+	validator := validation.NewValidator()
+	if len(inputs) > maxCalendarRanges {
+		validator.AddError("ranges", "At most "+strconv.Itoa(maxCalendarRanges)+" ranges can be set")
+		return nil, validator
+	}
+
+	ranges := make([]models.CalendarRange, 0, len(inputs))
+	spans := make([]calendar.Range, 0, len(inputs))
+	for i, input := range inputs {
+		field := "ranges[" + strconv.Itoa(i) + "]"
+		span := calendar.NewRange(input.StartDate, input.EndDate)
+		validator.ValidateDateRange(field+".end_date", span.Start, span.End)
+		validator.ValidateOneOf(field+".status", input.Status, calendarStatuses, "Status")
+		validator.ValidateMaxLength(field+".note", input.Note, 500, "Note")
+		validateCalendarPrice(validator, field+".nightly_price", input.Status, input.NightlyPrice)
+		validateCalendarPrice(validator, field+".monthly_price", input.Status, input.MonthlyPrice)
+
+		ranges = append(ranges, models.CalendarRange{
+			StartDate:    span.Start,
+			EndDate:      span.End,
+			Status:       input.Status,
+			NightlyPrice: input.NightlyPrice,
+			MonthlyPrice: input.MonthlyPrice,
+			Note:         input.Note,
+		})
+		spans = append(spans, span)
+	}
+
+	if !validator.HasErrors() {
+		if i, j := calendar.FirstOverlap(spans); i >= 0 {
+			validator.AddError("ranges["+strconv.Itoa(j)+"]", "Range overlaps ranges["+strconv.Itoa(i)+"]")
+		}
+	}
+	return ranges, validator
+}
+
+func validateCalendarPrice(validator *validation.Validator, field, status string, price *float64) {
+	switch {
+	case price == nil:
+	case status != services.CalendarAvailable:
+		validator.AddError(field, "Prices can only be set on available ranges")
+	case *price <= 0:
+		validator.AddError(field, "Price must be greater than 0")
+	}
+}
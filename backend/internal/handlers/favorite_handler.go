@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"net/http"
+
+	"rent-help-backend/internal/models"
+	"rent-help-backend/internal/services"
+	"rent-help-backend/pkg/validation"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type FavoriteHandler struct {
+	propertyService *services.PropertyService
+	favoriteService *services.FavoriteService
+}
+
+func NewFavoriteHandler(propertyService *services.PropertyService, favoriteService *services.FavoriteService) *FavoriteHandler {
+	return &FavoriteHandler{
+		propertyService: propertyService,
+		favoriteService: favoriteService,
+	}
+}
+
+// GetFavorites lists the listings the current user saved, most recently
+// saved first.
+func (h *FavoriteHandler) GetFavorites(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	validator := validation.NewValidator()
+	page := parsePage(c, validator, []string{"created_at"}, newestFirst)
+	if validator.HasErrors() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": validator.GetErrors()})
+		return
+	}
+
+	properties, nextCursor, err := h.favoriteService.ListFavorites(userID, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get favorites"})
+		return
+	}
+
+	total, err := h.favoriteService.CountFavorites(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count favorites"})
+		return
+	}
+
+	if !prepareProperties(c, h.favoriteService, properties) {
+		return
+	}
+
+	c.JSON(http.StatusOK, listResponse(properties, nextCursor, total))
+}
+
+// AddFavorite saves a listing. Saving a listing again returns the existing
+// favorite with 200 instead of 201.
+func (h *FavoriteHandler) AddFavorite(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.AddFavoriteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	propertyID, err := primitive.ObjectIDFromHex(req.PropertyID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid property ID"})
+		return
+	}
+
+	property, err := h.propertyService.GetPropertyByID(propertyID)
+	if err != nil || !canViewProperty(c, property) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Property not found"})
+		return
+	}
+
+	favorite, created, err := h.favoriteService.AddFavorite(userID, propertyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add favorite"})
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, favorite)
+}
+
+func (h *FavoriteHandler) RemoveFavorite(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	propertyID, err := primitive.ObjectIDFromHex(c.Param("propertyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid property ID"})
+		return
+	}
+
+	switch err := h.favoriteService.RemoveFavorite(userID, propertyID); err {
+	case nil:
+		c.JSON(http.StatusOK, gin.H{"message": "Favorite removed successfully"})
+	case services.ErrFavoriteNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Favorite not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove favorite"})
+	}
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"rent-help-backend/internal/config"
+	"rent-help-backend/internal/models"
+	"rent-help-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The state of a started sign-in is also kept in a cookie, so the callback
+// only completes in the browser that started it. Without it an attacker could
+// send a victim to the callback with the attacker's own code and state and
+// sign the victim into the attacker's account.
+const (
+	oidcStateCookie     = "oidc_state"
+	oidcStateCookiePath = "/api/v1/auth/oidc"
+)
+
+type OIDCHandler struct {
+	oidcService      *services.OIDCService
+	userService      *services.UserService
+	tokenService     *services.TokenService
+	twoFactorService *services.TwoFactorService
+	appURL           string
+	secureCookies    bool
+}
+
+func NewOIDCHandler(oidcService *services.OIDCService, userService *services.UserService, tokenService *services.TokenService, twoFactorService *services.TwoFactorService, cfg *config.Config) *OIDCHandler {
+	return &OIDCHandler{
+		oidcService:      oidcService,
+		userService:      userService,
+		tokenService:     tokenService,
+		twoFactorService: twoFactorService,
+		appURL:           cfg.AppURL,
+		secureCookies:    strings.HasPrefix(cfg.PublicURL, "https://"),
+	}
+}
+
+func (h *OIDCHandler) GetProviders(c *gin.Context) {
+	providers := h.oidcService.Providers()
+	c.JSON(http.StatusOK, listResponse(providers, nil, int64(len(providers))))
+}
+
+// Login redirects the browser to the provider's sign-in page.
+func (h *OIDCHandler) Login(c *gin.Context) {
+	authURL, state, err := h.oidcService.BeginAuth(c.Param("provider"), nil)
+	if err != nil {
+		if err == services.ErrUnknownProvider {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unknown identity provider"})
+			return
+		}
+		log.Printf("Failed to start %s sign-in: %v", c.Param("provider"), err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider is unavailable"})
+		return
+	}
+
+	h.setStateCookie(c, state, int(services.OIDCStateTTL.Seconds()))
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback is where the provider sends the browser back to. The result is
+// passed on to the web app in the URL fragment so that tokens do not end up
+// in server logs or Referer headers.
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	provider := c.Param("provider")
+	if !h.oidcService.HasProvider(provider) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown identity provider"})
+		return
+	}
+
+	browserState, _ := c.Cookie(oidcStateCookie)
+	h.setStateCookie(c, "", -1)
+
+	if providerErr := c.Query("error"); providerErr != "" {
+		h.redirectToApp(c, url.Values{"error": {providerErr}})
+		return
+	}
+
+	state := c.Query("state")
+	if browserState == "" || subtle.ConstantTimeCompare([]byte(browserState), []byte(state)) != 1 {
+		h.redirectToApp(c, url.Values{"error": {"invalid_state"}})
+		return
+	}
+
+	result, err := h.oidcService.CompleteAuth(provider, c.Query("code"), state)
+	if err != nil {
+		code := "login_failed"
+		switch err {
+		case services.ErrUnknownProvider:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unknown identity provider"})
+			return
+		case services.ErrInvalidOIDCState:
+			code = "invalid_state"
+		case services.ErrOIDCEmailNotVerified:
+			code = "email_not_verified"
+		case services.ErrIdentityAlreadyLinked:
+			code = "identity_already_linked"
+		case services.ErrProviderAlreadyLinked:
+			code = "provider_already_linked"
+		default:
+			log.Printf("Failed to complete %s sign-in: %v", provider, err)
+		}
+		h.redirectToApp(c, url.Values{"error": {code}})
+		return
+	}
+
+	if result.Linking {
+		h.redirectToApp(c, url.Values{"linked": {provider}})
+		return
+	}
+
+	user := result.User
+	if !user.IsActive {
+		h.redirectToApp(c, url.Values{"error": {"account_inactive"}})
+		return
+	}
+
+	// Signing in through a provider does not skip the user's own second
+	// factor.
+	if user.TwoFactor.Enabled {
+		challenge, err := h.twoFactorService.CreateChallenge(user)
+		if err != nil {
+			h.redirectToApp(c, url.Values{"error": {"login_failed"}})
+			return
+		}
+		h.redirectToApp(c, url.Values{
+			"two_factor_required": {"true"},
+			"challenge_token":     {challenge},
+		})
+		return
+	}
+
+	tokens, err := h.tokenService.IssueTokens(user, sessionClient(c, "oidc:"+provider))
+	if err != nil {
+		h.redirectToApp(c, url.Values{"error": {"login_failed"}})
+		return
+	}
+
+	if err := h.userService.RecordLogin(user.ID); err != nil {
+		log.Printf("Failed to record login for %s: %v", user.Email, err)
+	}
+
+	h.redirectToApp(c, url.Values{
+		"access_token":  {tokens.AccessToken},
+		"refresh_token": {tokens.RefreshToken},
+		"expires_in":    {strconv.FormatInt(tokens.ExpiresIn, 10)},
+		"new_user":      {strconv.FormatBool(result.Created)},
+	})
+}
+
+func (h *OIDCHandler) GetIdentities(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+
+	identities, err := h.oidcService.GetIdentities(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get linked accounts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, listResponse(identities, nil, int64(len(identities))))
+}
+
+// LinkIdentity starts linking a provider to the current user. It returns the
+// URL instead of redirecting because the request carries a bearer token that
+// a plain browser navigation could not send. The state cookie is set on this
+// response, so the request has to be sent with credentials.
+func (h *OIDCHandler) LinkIdentity(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+
+	userID := user.ID
+	authURL, state, err := h.oidcService.BeginAuth(c.Param("provider"), &userID)
+	if err != nil {
+		if err == services.ErrUnknownProvider {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unknown identity provider"})
+			return
+		}
+		log.Printf("Failed to start %s linking: %v", c.Param("provider"), err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider is unavailable"})
+		return
+	}
+
+	h.setStateCookie(c, state, int(services.OIDCStateTTL.Seconds()))
+	c.JSON(http.StatusOK, gin.H{"authorization_url": authURL})
+}
+
+func (h *OIDCHandler) UnlinkIdentity(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+
+	switch err := h.oidcService.Unlink(user, c.Param("provider")); err {
+	case nil:
+	case services.ErrIdentityNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Linked account not found"})
+		return
+	case services.ErrLastIdentity:
+		c.JSON(http.StatusConflict, gin.H{"error": "Set a password with a password reset before unlinking your only linked account"})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink account"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account unlinked successfully"})
+}
+
+// setStateCookie stores state for the callback, or clears the cookie when
+// maxAge is negative. It is Lax so that it is sent on the provider's redirect
+// back.
+func (h *OIDCHandler) setStateCookie(c *gin.Context, state string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, maxAge, oidcStateCookiePath, "", h.secureCookies, true)
+}
+
+func (h *OIDCHandler) redirectToApp(c *gin.Context, values url.Values) {
+	c.Redirect(http.StatusFound, h.appURL+"/auth/callback#"+values.Encode())
+}
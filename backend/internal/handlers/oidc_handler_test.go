@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"rent-help-backend/internal/config"
+	"rent-help-backend/internal/services"
+	"rent-help-backend/pkg/oidc/oidctest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const testAppURL = "http://app.test"
+
+// testDatabase returns an empty database on the server at TEST_MONGODB_URI
+// and drops it when the test ends. The test is skipped without one.
+func testDatabase(t *testing.T) *mongo.Database {
+	t.Helper()
+	uri := os.Getenv("TEST_MONGODB_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGODB_URI is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	db := client.Database("rent_help_test_" + primitive.NewObjectID().Hex())
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+	return db
+}
+
+// oidcRouter serves the public OIDC routes as main.go registers them, with
+// one provider named mock.
+func oidcRouter(db *mongo.Database, issuer *oidctest.Issuer) *gin.Engine {
+	cfg := &config.Config{
+		JWTSecret:       "test-secret",
+		AccessTokenTTL:  15 * time.Minute,
+		RefreshTokenTTL: time.Hour,
+		PublicURL:       "http://api.test",
+		AppURL:          testAppURL,
+		OIDCProviders: []config.OIDCProvider{{
+			Name:        "mock",
+			DisplayName: "Mock",
+			Issuer:      issuer.URL,
+			ClientID:    "client",
+			Scopes:      []string{"openid", "email"},
+		}},
+	}
+
+	userService := services.NewUserService(db)
+	tokenService := services.NewTokenService(db, cfg)
+	oidcService := services.NewOIDCService(db, userService, tokenService, cfg)
+	handler := NewOIDCHandler(oidcService, userService, tokenService, services.NewTwoFactorService(db, cfg), cfg)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	auth := router.Group("/api/v1/auth")
+	auth.GET("/oidc/providers", handler.GetProviders)
+	auth.GET("/oidc/:provider/login", handler.Login)
+	auth.GET("/oidc/:provider/callback", handler.Callback)
+	return router
+}
+
+func serve(router *gin.Engine, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+// appFragment returns the values the callback passed to the web app.
+func appFragment(t *testing.T, w *httptest.ResponseRecorder) url.Values {
+	t.Helper()
+	if w.Code != http.StatusFound {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusFound, w.Body)
+	}
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(location.String(), testAppURL+"/auth/callback#") {
+		t.Fatalf("redirected to %s, want the app callback", location)
+	}
+	values, err := url.ParseQuery(location.Fragment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return values
+}
+
+func TestOIDCProviders(t *testing.T) {
+	// Listing providers and rejecting unknown ones needs no database.
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI("mongodb://localhost:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := oidcRouter(client.Database("unused"), oidctest.NewIssuer(t))
+
+	w := serve(router, "/api/v1/auth/oidc/providers")
+	var body struct {
+		Data  []services.OIDCProviderInfo `json:"data"`
+		Total int64                       `json:"total"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || body.Total != 1 || body.Data[0].Name != "mock" || body.Data[0].DisplayName != "Mock" {
+		t.Fatalf("providers = %d %s", w.Code, w.Body)
+	}
+
+	for _, target := range []string{"/api/v1/auth/oidc/other/login", "/api/v1/auth/oidc/other/callback?code=c&state=s"} {
+		if w := serve(router, target); w.Code != http.StatusNotFound {
+			t.Errorf("GET %s = %d, want %d", target, w.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestOIDCLoginCallback(t *testing.T) {
+	issuer := oidctest.NewIssuer(t)
+	router := oidcRouter(testDatabase(t), issuer)
+
+	// login returns the query sent to the issuer and the state cookie.
+	login := func() (url.Values, *http.Cookie) {
+		t.Helper()
+		w := serve(router, "/api/v1/auth/oidc/mock/login")
+		if w.Code != http.StatusFound {
+			t.Fatalf("login status = %d: %s", w.Code, w.Body)
+		}
+		var cookie *http.Cookie
+		for _, c := range w.Result().Cookies() {
+			if c.Name == "oidc_state" {
+				cookie = c
+			}
+		}
+		if cookie == nil || !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode || cookie.Path != "/api/v1/auth/oidc" {
+			t.Fatalf("state cookie = %+v", cookie)
+		}
+		authURL, err := url.Parse(w.Header().Get("Location"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(authURL.String(), issuer.URL+"/authorize?") {
+			t.Fatalf("login redirected to %s, want the issuer", authURL)
+		}
+		query := authURL.Query()
+		if query.Get("redirect_uri") != "http://api.test/api/v1/auth/oidc/mock/callback" {
+			t.Fatalf("redirect_uri = %q", query.Get("redirect_uri"))
+		}
+		if cookie.Value != query.Get("state") {
+			t.Fatalf("state cookie %q does not hold the state %q", cookie.Value, query.Get("state"))
+		}
+		return query, cookie
+	}
+	callback := func(code, state string, cookie *http.Cookie) url.Values {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/mock/callback?"+url.Values{
+			"code":  {code},
+			"state": {state},
+		}.Encode(), nil)
+		if cookie != nil {
+			r.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return appFragment(t, w)
+	}
+	authorize := func(query url.Values, code string) {
+		issuer.Code = code
+		issuer.Challenge = query.Get("code_challenge")
+		issuer.Claims = jwt.MapClaims{
+			"iss":            issuer.URL,
+			"aud":            "client",
+			"sub":            "subject-1",
+			"email":          "oidc@example.com",
+			"email_verified": true,
+			"name":           "OIDC User",
+			"nonce":          query.Get("nonce"),
+			"exp":            time.Now().Add(time.Hour).Unix(),
+		}
+	}
+
+	// The callback only completes in the browser that started the sign-in.
+	query, cookie := login()
+	authorize(query, "code-0")
+	if values := callback("code-0", query.Get("state"), nil); values.Get("error") != "invalid_state" {
+		t.Fatalf("callback without the state cookie = %v, want invalid_state", values)
+	}
+	_, other := login()
+	if values := callback("code-0", query.Get("state"), other); values.Get("error") != "invalid_state" {
+		t.Fatalf("callback with another sign-in's cookie = %v, want invalid_state", values)
+	}
+
+	// First sign-in registers the user.
+	authorize(query, "code-1")
+	values := callback("code-1", query.Get("state"), cookie)
+	if values.Get("error") != "" || values.Get("access_token") == "" || values.Get("refresh_token") == "" || values.Get("new_user") != "true" {
+		t.Fatalf("first sign-in = %v", values)
+	}
+
+	// A state is single use.
+	if values := callback("code-1", query.Get("state"), cookie); values.Get("error") != "invalid_state" {
+		t.Fatalf("reused state = %v, want invalid_state", values)
+	}
+
+	// Signing in again finds the linked identity.
+	query, cookie = login()
+	authorize(query, "code-2")
+	values = callback("code-2", query.Get("state"), cookie)
+	if values.Get("access_token") == "" || values.Get("new_user") != "false" {
+		t.Fatalf("second sign-in = %v", values)
+	}
+
+	// Errors from the provider are passed on to the app.
+	if values := appFragment(t, serve(router, "/api/v1/auth/oidc/mock/callback?error=access_denied")); values.Get("error") != "access_denied" {
+		t.Fatalf("provider error = %v", values)
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"reflect"
+
+	"rent-help-backend/internal/models"
+	"rent-help-backend/internal/services"
+	"rent-help-backend/pkg/pagination"
+	"rent-help-backend/pkg/validation"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+)
+
+var newestFirst = pagination.Sort{Field: "created_at", Desc: true}
+
+// parsePage reads the limit, sort and cursor parameters shared by list
+// endpoints. sorts lists the sort names the endpoint accepts.
+func parsePage(c queryParams, validator *validation.Validator, sorts []string, defaultSort pagination.Sort) services.PageRequest {
+	page := services.PageRequest{Sort: defaultSort, Limit: defaultPageSize}
+
+	if c.Query("skip") != "" {
+		validator.AddError("skip", "skip is not supported; pass next_cursor from the previous page as cursor")
+	}
+
+	limit, err := pagination.ParseLimit(c.Query("limit"), defaultPageSize, maxPageSize)
+	if err != nil {
+		validator.AddError("limit", err.Error())
+	} else {
+		page.Limit = limit
+	}
+
+	sort, err := pagination.ParseSort(c.Query("sort"), sorts, defaultSort)
+	if err != nil {
+		validator.AddError("sort", err.Error())
+		return page
+	}
+	page.Sort = sort
+
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, err := pagination.Decode(raw, sort)
+		if err == nil && !primitive.IsValidObjectID(cursor.ID) {
+			err = pagination.ErrInvalidCursor
+		}
+		if err != nil {
+			validator.AddError("cursor", err.Error())
+		} else {
+			page.After = cursor
+		}
+	}
+
+	return page
+}
+
+// listResponse wraps a page of results in the shared envelope. A nil slice
+// is sent as an empty array rather than null.
+func listResponse(data interface{}, nextCursor *string, total int64) models.ListResponse {
+	if v := reflect.ValueOf(data); v.Kind() == reflect.Slice && v.IsNil() {
+		data = reflect.MakeSlice(v.Type(), 0, 0).Interface()
+	}
+	return models.ListResponse{Data: data, NextCursor: nextCursor, Total: total}
+}
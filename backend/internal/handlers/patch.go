@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"rent-help-backend/internal/models"
+	"rent-help-backend/pkg/patch"
+	"rent-help-backend/pkg/validation"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// bindPatch decodes a partial update request into req, a pointer to one of
+// the models.Update*Request types. Fields that are not part of the request
+// type are rejected rather than silently dropped, and so is an empty patch.
+func bindPatch(c *gin.Context, req interface{}) bool {
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	if len(patch.Fields(req)) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No fields to update"})
+		return false
+	}
+	return true
+}
+
+// patchUpdates returns the $set document for a validated patch, or writes a
+// 400 with the validation errors.
+func patchUpdates(c *gin.Context, req interface{}, validator *validation.Validator) (bson.M, bool) {
+	if validator.HasErrors() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": validator.GetErrors()})
+		return nil, false
+	}
+	return bson.M(patch.Fields(req)), true
+}
+
+func validateAddressUpdate(validator *validation.Validator, prefix string, address *models.AddressUpdate) {
+	if address.Street != nil {
+		validator.ValidateMaxLength(prefix+".street", *address.Street, 200, "Street")
+	}
+	if address.City != nil {
+		validator.ValidateMaxLength(prefix+".city", *address.City, 100, "City")
+	}
+	if address.Latitude != nil {
+		validator.ValidateNumericRange(prefix+".latitude", *address.Latitude, -90, 90, "Latitude")
+	}
+	if address.Longitude != nil {
+		validator.ValidateNumericRange(prefix+".longitude", *address.Longitude, -180, 180, "Longitude")
+	}
+}
@@ -1,24 +1,36 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
-	"strconv"
 
 	"rent-help-backend/internal/models"
 	"rent-help-backend/internal/services"
+	"rent-help-backend/pkg/geo"
+	"rent-help-backend/pkg/listing"
+	"rent-help-backend/pkg/validation"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+var propertyTypes = []string{"apartment", "house", "condo", "townhouse", "studio"}
+
 type PropertyHandler struct {
-	propertyService *services.PropertyService
+	propertyService      *services.PropertyService
+	propertyImageService *services.PropertyImageService
+	calendarService      *services.CalendarService
+	favoriteService      *services.FavoriteService
+	analyticsService     *services.AnalyticsService
 }
 
-func NewPropertyHandler(propertyService *services.PropertyService) *PropertyHandler {
+func NewPropertyHandler(propertyService *services.PropertyService, propertyImageService *services.PropertyImageService, calendarService *services.CalendarService, favoriteService *services.FavoriteService, analyticsService *services.AnalyticsService) *PropertyHandler {
 	return &PropertyHandler{
-		propertyService: propertyService,
+		propertyService:      propertyService,
+		propertyImageService: propertyImageService,
+		calendarService:      calendarService,
+		favoriteService:      favoriteService,
+		analyticsService:     analyticsService,
 	}
 }
 
@@ -38,6 +50,19 @@ func (h *PropertyHandler) CreateProperty(c *gin.Context) {
 
 	property.OwnerID = ownerID
 
+	// New listings are saved as drafts or submitted for review right away.
+	validator := validation.NewValidator()
+	if property.Status != "" {
+		validator.ValidateOneOf("status", property.Status, []string{listing.Draft, listing.PendingReview}, "Status")
+	}
+	if property.Location != nil {
+		validateGeoLocation(validator, "location", property.Location.Type, property.Location.Coordinates)
+	}
+	if validator.HasErrors() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": validator.GetErrors()})
+		return
+	}
+
 	if err := h.propertyService.CreateProperty(&property); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create property"})
 		return
@@ -47,31 +72,46 @@ func (h *PropertyHandler) CreateProperty(c *gin.Context) {
 }
 
 func (h *PropertyHandler) GetProperties(c *gin.Context) {
-	// Parse query parameters
-	limitStr := c.DefaultQuery("limit", "10")
-	skipStr := c.DefaultQuery("skip", "0")
-	city := c.Query("city")
-	propertyType := c.Query("type")
-
-	limit, _ := strconv.ParseInt(limitStr, 10, 64)
-	skip, _ := strconv.ParseInt(skipStr, 10, 64)
-
-	// Build filter
-	filter := bson.M{"available": true}
-	if city != "" {
-		filter["location.city"] = bson.M{"$regex": city, "$options": "i"}
-	}
-	if propertyType != "" {
-		filter["type"] = propertyType
+	search, validator := parsePropertySearch(c, c.GetString("user_id"))
+	if validator.HasErrors() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": validator.GetErrors()})
+		return
 	}
 
-	properties, err := h.propertyService.GetProperties(filter, limit, skip)
+	var properties []*models.Property
+	var nextCursor *string
+	var total int64
+	var err error
+	switch {
+	case search.Near != nil:
+		properties, nextCursor, err = h.propertyService.GetPropertiesNear(search.Filter, *search.Near, search.RadiusKm, search.Page)
+		if err == nil {
+			total, err = h.propertyService.CountPropertiesNear(search.Filter, *search.Near, search.RadiusKm)
+		}
+	case search.Query != "":
+		properties, nextCursor, err = h.propertyService.SearchProperties(search.Filter, search.Query, search.Page)
+		if err == nil {
+			total, err = h.propertyService.CountSearchProperties(search.Filter, search.Query)
+		}
+	default:
+		properties, nextCursor, err = h.propertyService.ListProperties(search.Filter, search.Page)
+		if err == nil {
+			total, err = h.propertyService.CountProperties(search.Filter)
+		}
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get properties"})
 		return
 	}
 
-	c.JSON(http.StatusOK, properties)
+	if search.Query != "" {
+		search.highlight(properties)
+	}
+	if !prepareProperties(c, h.favoriteService, properties) {
+		return
+	}
+
+	c.JSON(http.StatusOK, listResponse(properties, nextCursor, total))
 }
 
 func (h *PropertyHandler) GetProperty(c *gin.Context) {
@@ -83,10 +123,23 @@ func (h *PropertyHandler) GetProperty(c *gin.Context) {
 	}
 
 	property, err := h.propertyService.GetPropertyByID(id)
-	if err != nil {
+	if err != nil || !canViewProperty(c, property) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Property not found"})
 		return
 	}
+	if !prepareProperties(c, h.favoriteService, []*models.Property{property}) {
+		return
+	}
+
+	// Counting the view must not fail the request.
+	if visitorID, err := primitive.ObjectIDFromHex(c.GetString("user_id")); err == nil {
+		counted, err := h.analyticsService.RecordView(property, visitorID)
+		if err != nil {
+			log.Printf("Failed to record view of property %s: %v", property.ID.Hex(), err)
+		} else if counted {
+			property.ViewCount++
+		}
+	}
 
 	c.JSON(http.StatusOK, property)
 }
@@ -118,22 +171,28 @@ func (h *PropertyHandler) UpdateProperty(c *gin.Context) {
 		return
 	}
 
-	var updates bson.M
-	if err := c.ShouldBindJSON(&updates); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var req models.UpdatePropertyRequest
+	if !bindPatch(c, &req) {
+		return
+	}
+
+	updates, ok := patchUpdates(c, &req, validatePropertyUpdate(&req))
+	if !ok {
 		return
 	}
 
-	// Remove sensitive fields
-	delete(updates, "_id")
-	delete(updates, "owner_id")
+	// Take the listing down before the new content is written, so that
+	// unreviewed content is never public.
+	if services.NeedsReview(updates) && !resubmitForReview(c, h.propertyService, property) {
+		return
+	}
 
 	if err := h.propertyService.UpdateProperty(id, updates); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update property"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Property updated successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Property updated successfully", "status": property.Status})
 }
 
 func (h *PropertyHandler) DeleteProperty(c *gin.Context) {
@@ -167,6 +226,255 @@ func (h *PropertyHandler) DeleteProperty(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete property"})
 		return
 	}
+	h.propertyImageService.DeleteAllImages(property)
+	if err := h.favoriteService.DeleteForProperty(id); err != nil {
+		log.Printf("Failed to delete favorites of property %s: %v", id.Hex(), err)
+	}
+	if err := h.calendarService.DeleteCalendar(id); err != nil {
+		log.Printf("Failed to delete calendar of property %s: %v", id.Hex(), err)
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Property deleted successfully"})
 }
+
+// ownedProperty loads the property from the :id parameter and checks that
+// the current user owns it.
+func ownedProperty(c *gin.Context, propertyService *services.PropertyService) (*models.Property, bool) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid property ID"})
+		return nil, false
+	}
+
+	property, err := propertyService.GetPropertyByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Property not found"})
+		return nil, false
+	}
+
+	if property.OwnerID.Hex() != c.GetString("user_id") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to update this property"})
+		return nil, false
+	}
+	return property, true
+}
+
+// UpdatePropertyStatus moves a listing through its lifecycle: submitting a
+// draft for review, pausing and resuming a published listing, archiving and
+// restoring. Publishing is up to a reviewer, see AdminHandler.
+func (h *PropertyHandler) UpdatePropertyStatus(c *gin.Context) {
+	var req models.UpdatePropertyStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	validator := validatePropertyStatus(&req)
+	if validator.HasErrors() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": validator.GetErrors()})
+		return
+	}
+
+	property, ok := ownedProperty(c, h.propertyService)
+	if !ok {
+		return
+	}
+
+	changeStatus(c, h.propertyService, property, &req, listing.Owner, property.OwnerID)
+}
+
+func validatePropertyStatus(req *models.UpdatePropertyStatusRequest) *validation.Validator {
+	validator := validation.NewValidator()
+	validator.ValidateOneOf("status", req.Status, listing.States, "Status")
+	validator.ValidateMaxLength("reason", req.Reason, 1000, "Reason")
+	return validator
+}
+
+// changeStatus applies a status change and responds with the property, or
+// with 409 and the statuses allowed instead. It reports whether the status
+// was changed.
+func changeStatus(c *gin.Context, propertyService *services.PropertyService, property *models.Property, req *models.UpdatePropertyStatusRequest, actor listing.Actor, changedBy primitive.ObjectID) bool {
+	from := property.Status
+	err := propertyService.ChangeStatus(property, req.Status, actor, changedBy, req.Reason)
+	switch err {
+	case nil:
+		c.JSON(http.StatusOK, property)
+		return true
+	case services.ErrInvalidStatusTransition:
+		allowed := listing.Next(actor, from)
+		if allowed == nil {
+			allowed = []string{}
+		}
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Cannot change listing status from " + from + " to " + req.Status,
+			"allowed": allowed,
+		})
+	case services.ErrPropertyStatusChanged:
+		c.JSON(http.StatusConflict, gin.H{"error": "The listing was changed by another request, please retry"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update listing status"})
+	}
+	return false
+}
+
+// resubmitForReview sends a public listing back to review before its owner
+// changes reviewed content. On failure it responds with 409 or 500.
+func resubmitForReview(c *gin.Context, propertyService *services.PropertyService, property *models.Property) bool {
+	switch err := propertyService.ResubmitForReview(property); err {
+	case nil:
+		return true
+	case services.ErrPropertyStatusChanged:
+		c.JSON(http.StatusConflict, gin.H{"error": "The listing was changed by another request, please retry"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update listing status"})
+	}
+	return false
+}
+
+// prepareProperties readies listings for a response to the current user: it
+// hides the status history of other users' listings from everyone but admins
+// and marks the listings the user saved. On failure it responds with 500.
+func prepareProperties(c *gin.Context, favoriteService *services.FavoriteService, properties []*models.Property) bool {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return false
+	}
+
+	ids := make([]primitive.ObjectID, len(properties))
+	for i, property := range properties {
+		ids[i] = property.ID
+		if property.OwnerID != userID && c.GetString("role") != "admin" {
+			property.StatusHistory = nil
+		}
+	}
+
+	favorited, err := favoriteService.FavoritedIDs(userID, ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get favorites"})
+		return false
+	}
+	for _, property := range properties {
+		saved := favorited[property.ID]
+		property.Favorited = &saved
+	}
+	return true
+}
+
+// canViewProperty reports whether the current user may see a listing. Only
+// published listings are public; owners and admins see every state.
+func canViewProperty(c *gin.Context, property *models.Property) bool {
+	return listing.Public(property.Status) ||
+		property.OwnerID.Hex() == c.GetString("user_id") ||
+		c.GetString("role") == "admin"
+}
+
+func validatePropertyUpdate(req *models.UpdatePropertyRequest) *validation.Validator {
+	validator := validation.NewValidator()
+
+	if req.Title != nil {
+		validator.ValidateRequired("title", *req.Title, "Title")
+		validator.ValidateMinLength("title", *req.Title, 5, "Title")
+		validator.ValidateMaxLength("title", *req.Title, 200, "Title")
+	}
+	if req.Description != nil {
+		validator.ValidateRequired("description", *req.Description, "Description")
+		validator.ValidateMinLength("description", *req.Description, 20, "Description")
+		validator.ValidateMaxLength("description", *req.Description, 2000, "Description")
+	}
+	if req.Type != nil {
+		validator.ValidateRequired("type", *req.Type, "Property type")
+		validator.ValidateOneOf("type", *req.Type, propertyTypes, "Property type")
+	}
+	if req.Price != nil {
+		if *req.Price <= 0 {
+			validator.AddError("price", "Price must be greater than 0")
+		}
+		if *req.Price > 1000000 {
+			validator.AddError("price", "Price cannot exceed 1,000,000")
+		}
+	}
+	if req.Currency != nil {
+		validator.ValidateCurrencyCode("currency", *req.Currency)
+	}
+	if req.Address != nil {
+		validateAddressUpdate(validator, "address", req.Address)
+	}
+	if loc := req.Location; loc != nil {
+		locationType := ""
+		if loc.Type != nil {
+			locationType = *loc.Type
+		}
+		validateGeoLocation(validator, "location", locationType, loc.Coordinates)
+	}
+
+	if req.Bedrooms != nil && *req.Bedrooms < 0 {
+		validator.AddError("bedrooms", "Bedrooms cannot be negative")
+	}
+	if req.Bathrooms != nil && *req.Bathrooms < 0 {
+		validator.AddError("bathrooms", "Bathrooms cannot be negative")
+	}
+	if req.Area != nil && *req.Area < 0 {
+		validator.AddError("area", "Area cannot be negative")
+	}
+	if req.SquareFeet != nil && *req.SquareFeet < 0 {
+		validator.AddError("square_feet", "Square feet cannot be negative")
+	}
+
+	if req.VirtualTour != nil {
+		validator.ValidateURL("virtual_tour", *req.VirtualTour)
+	}
+	if req.FloorPlan != nil {
+		validator.ValidateURL("floor_plan", *req.FloorPlan)
+	}
+	for _, image := range req.Images {
+		validator.ValidateURL("images", image)
+	}
+	for _, video := range req.Videos {
+		validator.ValidateURL("videos", video)
+	}
+
+	if rules := req.Rules; rules != nil {
+		if rules.MaxOccupants != nil && *rules.MaxOccupants < 0 {
+			validator.AddError("rules.max_occupants", "Max occupants cannot be negative")
+		}
+		if rules.MinAge != nil {
+			validator.ValidateNumericRange("rules.min_age", float64(*rules.MinAge), 0, 120, "Minimum age")
+		}
+		if rules.GenderPreference != nil {
+			validator.ValidateOneOf("rules.gender_preference", *rules.GenderPreference, []string{"male", "female", "any"}, "Gender preference")
+		}
+		if qh := rules.QuietHours; qh != nil {
+			if qh.Start != nil {
+				validator.ValidateTimeOfDay("rules.quiet_hours.start", *qh.Start, "Quiet hours start")
+			}
+			if qh.End != nil {
+				validator.ValidateTimeOfDay("rules.quiet_hours.end", *qh.End, "Quiet hours end")
+			}
+		}
+	}
+
+	if parking := req.Parking; parking != nil {
+		if parking.Type != nil {
+			validator.ValidateOneOf("parking.type", *parking.Type, []string{"garage", "covered", "open", "street"}, "Parking type")
+		}
+		if parking.Spaces != nil && *parking.Spaces < 0 {
+			validator.AddError("parking.spaces", "Parking spaces cannot be negative")
+		}
+		if parking.Cost != nil && *parking.Cost < 0 {
+			validator.AddError("parking.cost", "Parking cost cannot be negative")
+		}
+	}
+
+	return validator
+}
+
+// validateGeoLocation checks that a location is a GeoJSON point, which is the
+// only geometry the 2dsphere index on properties accepts from listings.
+func validateGeoLocation(validator *validation.Validator, field, locationType string, coordinates []float64) {
+	if locationType != "Point" {
+		validator.AddError(field+".type", "Location type must be Point")
+	}
+	if _, err := geo.FromCoordinates(coordinates); err != nil {
+		validator.AddError(field+".coordinates", "Coordinates must be [longitude, latitude]: "+err.Error())
+	}
+}
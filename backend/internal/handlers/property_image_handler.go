@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+
+	"rent-help-backend/internal/models"
+	"rent-help-backend/internal/services"
+	"rent-help-backend/pkg/imaging"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Image types accepted for upload, keyed by the sniffed content type. The
+// Content-Type sent by the client is ignored.
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+type PropertyImageHandler struct {
+	propertyService      *services.PropertyService
+	propertyImageService *services.PropertyImageService
+	maxUploadSize        int64
+	maxImages            int
+}
+
+func NewPropertyImageHandler(propertyService *services.PropertyService, propertyImageService *services.PropertyImageService, maxUploadSize int64, maxImages int) *PropertyImageHandler {
+	return &PropertyImageHandler{
+		propertyService:      propertyService,
+		propertyImageService: propertyImageService,
+		maxUploadSize:        maxUploadSize,
+		maxImages:            maxImages,
+	}
+}
+
+func (h *PropertyImageHandler) GetImages(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid property ID"})
+		return
+	}
+
+	property, err := h.propertyService.GetPropertyByID(id)
+	if err != nil || !canViewProperty(c, property) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Property not found"})
+		return
+	}
+
+	respondWithImages(c, http.StatusOK, property.PropertyImages)
+}
+
+// UploadImages accepts one or more files in the multipart field "images",
+// with optional "caption" values in the same order.
+func (h *PropertyImageHandler) UploadImages(c *gin.Context) {
+	property, ok := ownedProperty(c, h.propertyService)
+	if !ok {
+		return
+	}
+
+	// Bound the whole request body, not just each file, so a request with
+	// many parts cannot fill the disk with multipart temp files.
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxUploadSize*int64(h.maxImages)+1<<20)
+	form, err := c.MultipartForm()
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Upload is too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Expected a multipart/form-data body"})
+		return
+	}
+
+	files := form.File["images"]
+	if len(files) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No images uploaded; send files in the \"images\" field"})
+		return
+	}
+	if len(property.PropertyImages)+len(files) > h.maxImages {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A property can have at most " + strconv.Itoa(h.maxImages) + " images"})
+		return
+	}
+
+	captions := form.Value["caption"]
+	uploads := make([]services.ImageUpload, 0, len(files))
+	for i, file := range files {
+		upload, status, message := h.readImage(file)
+		if status != 0 {
+			c.JSON(status, gin.H{"error": file.Filename + ": " + message})
+			return
+		}
+		if i < len(captions) {
+			upload.Caption = captions[i]
+		}
+		uploads = append(uploads, *upload)
+	}
+
+	if !resubmitForReview(c, h.propertyService, property) {
+		return
+	}
+
+	images, err := h.propertyImageService.AddImages(property, uploads)
+	if err != nil {
+		respondWithImageError(c, err, "Failed to upload images")
+		return
+	}
+
+	respondWithImages(c, http.StatusCreated, images)
+}
+
+// ReorderImages sets the display order of the images. The request must list
+// every image ID of the property.
+func (h *PropertyImageHandler) ReorderImages(c *gin.Context) {
+	var req models.ReorderImagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(req.ImageIDs))
+	for _, raw := range req.ImageIDs {
+		id, err := primitive.ObjectIDFromHex(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image ID: " + raw})
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	property, ok := ownedProperty(c, h.propertyService)
+	if !ok {
+		return
+	}
+
+	images, err := h.propertyImageService.ReorderImages(property, ids)
+	if err != nil {
+		respondWithImageError(c, err, "Failed to reorder images")
+		return
+	}
+
+	respondWithImages(c, http.StatusOK, images)
+}
+
+func (h *PropertyImageHandler) SetPrimaryImage(c *gin.Context) {
+	imageID, err := primitive.ObjectIDFromHex(c.Param("imageId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image ID"})
+		return
+	}
+
+	property, ok := ownedProperty(c, h.propertyService)
+	if !ok {
+		return
+	}
+
+	images, err := h.propertyImageService.SetPrimaryImage(property, imageID)
+	if err != nil {
+		respondWithImageError(c, err, "Failed to update image")
+		return
+	}
+
+	respondWithImages(c, http.StatusOK, images)
+}
+
+func (h *PropertyImageHandler) DeleteImage(c *gin.Context) {
+	imageID, err := primitive.ObjectIDFromHex(c.Param("imageId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image ID"})
+		return
+	}
+
+	property, ok := ownedProperty(c, h.propertyService)
+	if !ok {
+		return
+	}
+
+	images, err := h.propertyImageService.DeleteImage(property, imageID)
+	if err != nil {
+		respondWithImageError(c, err, "Failed to delete image")
+		return
+	}
+
+	respondWithImages(c, http.StatusOK, images)
+}
+
+// readImage reads an uploaded file, checks its size and sniffed type and runs
+// it through the image pipeline. On failure it returns the HTTP status and
+// message to respond with.
+func (h *PropertyImageHandler) readImage(file *multipart.FileHeader) (*services.ImageUpload, int, string) {
+	maxSize := strconv.FormatInt(h.maxUploadSize>>20, 10) + " MB"
+	if file.Size > h.maxUploadSize {
+		return nil, http.StatusRequestEntityTooLarge, "Image exceeds the maximum size of " + maxSize
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		return nil, http.StatusBadRequest, "Failed to read image"
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, h.maxUploadSize+1))
+	if err != nil {
+		return nil, http.StatusBadRequest, "Failed to read image"
+	}
+	if int64(len(data)) > h.maxUploadSize {
+		return nil, http.StatusRequestEntityTooLarge, "Image exceeds the maximum size of " + maxSize
+	}
+
+	contentType := http.DetectContentType(data)
+	extension, ok := imageExtensions[contentType]
+	if !ok {
+		return nil, http.StatusUnsupportedMediaType, "Only JPEG, PNG and WebP images are supported"
+	}
+
+	// Re-encode or strip the file before it is stored anywhere, so that
+	// location data in the EXIF tags never becomes public.
+	processed, err := imaging.Process(data, imaging.DefaultSizes)
+	switch err {
+	case nil:
+	case imaging.ErrImageTooLarge:
+		return nil, http.StatusBadRequest, "Image dimensions are too large"
+	case imaging.ErrInvalidImage, imaging.ErrUnsupportedFormat:
+		return nil, http.StatusBadRequest, "Image could not be decoded"
+	default:
+		return nil, http.StatusInternalServerError, "Failed to process image"
+	}
+
+	return &services.ImageUpload{
+		Data:        processed.Data,
+		ContentType: processed.ContentType,
+		Extension:   extension,
+		Width:       processed.Width,
+		Height:      processed.Height,
+		Variants:    processed.Variants,
+	}, 0, ""
+}
+
+func respondWithImages(c *gin.Context, status int, images []models.PropertyImage) {
+	c.JSON(status, listResponse(images, nil, int64(len(images))))
+}
+
+func respondWithImageError(c *gin.Context, err error, message string) {
+	switch err {
+	case services.ErrImageNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+	case services.ErrInvalidImageOrder:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "image_ids must list every image of the property exactly once"})
+	case services.ErrTooManyImages:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many images"})
+	case services.ErrImagesChanged:
+		c.JSON(http.StatusConflict, gin.H{"error": "The property was changed by another request, please retry"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,408 @@
+package handlers
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"rent-help-backend/internal/models"
+	"rent-help-backend/internal/services"
+	"rent-help-backend/pkg/geo"
+	"rent-help-backend/pkg/listing"
+	"rent-help-backend/pkg/pagination"
+	"rent-help-backend/pkg/textsearch"
+	"rent-help-backend/pkg/validation"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultSearchRadiusKm = 10
+	maxSearchRadiusKm     = 200
+	maxSearchQueryLength  = 200
+	snippetLength         = 160
+)
+
+// Boolean fields of models.PropertyFeatures and models.PropertySafety that can
+// be required with features= and safety=.
+var (
+	propertyFeatureFilters = []string{
+		"furnished", "pets_allowed", "smoking_allowed", "balcony", "garden", "terrace", "basement", "attic",
+		"fireplace", "pool", "gym", "elevator", "accessible_entry", "storage_unit", "laundry_room",
+	}
+	propertySafetyFilters = []string{
+		"smoke_detector", "carbon_monoxide", "fire_extinguisher", "security_system",
+		"security_guard", "cctv", "gated_community", "well_lit",
+	}
+	parkingTypes = []string{"garage", "covered", "open", "street"}
+)
+
+// queryParams is the part of *gin.Context the query parsers read, so that a
+// stored query can be parsed the same way as a request through urlQuery.
+type queryParams interface {
+	Query(key string) string
+	DefaultQuery(key, defaultValue string) string
+	QueryArray(key string) []string
+}
+
+// urlQuery reads parameters from parsed URL values.
+type urlQuery url.Values
+
+func (q urlQuery) Query(key string) string {
+	return url.Values(q).Get(key)
+}
+
+func (q urlQuery) DefaultQuery(key, defaultValue string) string {
+	if values, ok := q[key]; ok && len(values) > 0 {
+		return values[0]
+	}
+	return defaultValue
+}
+
+func (q urlQuery) QueryArray(key string) []string {
+	return q[key]
+}
+
+// propertySearch is a parsed GET /properties query.
+type propertySearch struct {
+	Filter   bson.M
+	Near     *geo.Point
+	RadiusKm float64
+	Query    string
+	Tokens   []string
+	Page     services.PageRequest
+}
+
+// parsePropertySearch builds the Mongo filter for a property listing query.
+// Invalid parameters are reported on the returned validator rather than
+// silently ignored. userID is the caller, whose listings mine=true returns.
+func parsePropertySearch(c queryParams, userID string) (*propertySearch, *validation.Validator) {
+	validator := validation.NewValidator()
+	search := &propertySearch{
+		Filter: bson.M{"status": listing.Published},
+	}
+
+	// mine=true lists the caller's own listings in every state instead of
+	// the published listings of everyone.
+	if mine := queryBool(c, validator, "mine"); mine != nil && *mine {
+		ownerID, _ := primitive.ObjectIDFromHex(userID)
+		search.Filter = bson.M{"owner_id": ownerID}
+		if status := c.Query("status"); status != "" {
+			validator.ValidateOneOf("status", status, listing.States, "Status")
+			search.Filter["status"] = status
+		}
+	} else if c.Query("status") != "" {
+		validator.AddError("status", "status can only be used with mine=true")
+	}
+
+	if city := c.Query("city"); city != "" {
+		search.Filter["address.city"] = bson.M{"$regex": regexp.QuoteMeta(city), "$options": "i"}
+	}
+	// type accepts several types, which match listings of any of them.
+	if types := queryList(c, "type"); len(types) == 1 {
+		validator.ValidateOneOf("type", types[0], propertyTypes, "Property type")
+		search.Filter["type"] = types[0]
+	} else if len(types) > 1 {
+		for _, propertyType := range types {
+			validator.ValidateOneOf("type", propertyType, propertyTypes, "Property type")
+		}
+		search.Filter["type"] = bson.M{"$in": types}
+	}
+
+	parseAttributeFilters(c, search, validator)
+	parseGeoSearch(c, search, validator)
+	parseTextSearch(c, search, validator)
+
+	// Proximity searches default to nearest first and text searches to most
+	// relevant first; everything else lists the newest listings first.
+	defaultSort := newestFirst
+	switch {
+	case search.Near != nil:
+		defaultSort = pagination.Sort{Field: "distance"}
+	case search.Query != "":
+		defaultSort = pagination.Sort{Field: "relevance", Desc: true}
+	}
+	search.Page = parsePage(c, validator, services.PropertySorts, defaultSort)
+	if search.Page.Sort.Field == "distance" && search.Near == nil {
+		validator.AddError("sort", "Sorting by distance requires near")
+	}
+	if search.Page.Sort.Field == "relevance" && search.Query == "" {
+		validator.AddError("sort", "Sorting by relevance requires q")
+	}
+
+	return search, validator
+}
+
+// parseTextSearch handles q=. MongoDB cannot combine $text with $geoNear, so
+// q cannot be used together with near.
+func parseTextSearch(c queryParams, search *propertySearch, validator *validation.Validator) {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		return
+	}
+	if len([]rune(q)) > maxSearchQueryLength {
+		validator.AddError("q", "q cannot be longer than "+strconv.Itoa(maxSearchQueryLength)+" characters")
+		return
+	}
+	if search.Near != nil {
+		validator.AddError("q", "q cannot be combined with near; use bbox or polygon instead")
+		return
+	}
+
+	search.Tokens = textsearch.Tokenize(q)
+	if len(search.Tokens) == 0 {
+		validator.AddError("q", "q must contain at least one word")
+		return
+	}
+	search.Query = textsearch.Query(q)
+}
+
+// highlight fills in Highlights for text search results.
+func (s *propertySearch) highlight(properties []*models.Property) {
+	for _, property := range properties {
+		highlights := make(map[string]string)
+		if title := textsearch.Highlight(property.Title, s.Tokens, 0); title != "" {
+			highlights["title"] = title
+		}
+		if description := textsearch.Highlight(property.Description, s.Tokens, snippetLength); description != "" {
+			highlights["description"] = description
+		}
+		if len(highlights) > 0 {
+			property.Highlights = highlights
+		}
+	}
+}
+
+// parseAttributeFilters handles the price, room, feature and rule filters.
+// Conditions that need their own $or are collected under $and so they do not
+// overwrite each other.
+func parseAttributeFilters(c queryParams, search *propertySearch, validator *validation.Validator) {
+	var and bson.A
+
+	minPrice := queryFloat(c, validator, "min_price")
+	maxPrice := queryFloat(c, validator, "max_price")
+	if minPrice != nil || maxPrice != nil {
+		price := bson.M{}
+		if minPrice != nil {
+			price["$gte"] = *minPrice
+		}
+		if maxPrice != nil {
+			price["$lte"] = *maxPrice
+		}
+		if minPrice != nil && maxPrice != nil && *minPrice > *maxPrice {
+			validator.AddError("min_price", "min_price cannot be greater than max_price")
+		}
+		search.Filter["price"] = price
+	}
+
+	if bedrooms := queryInt(c, validator, "bedrooms"); bedrooms != nil {
+		search.Filter["bedrooms"] = bson.M{"$gte": *bedrooms}
+	}
+	if bathrooms := queryInt(c, validator, "bathrooms"); bathrooms != nil {
+		search.Filter["bathrooms"] = bson.M{"$gte": *bathrooms}
+	}
+
+	if furnished := queryBool(c, validator, "furnished"); furnished != nil {
+		search.Filter["features.furnished"] = *furnished
+	}
+	// Pets and smoking exist both at the top level and under features, and
+	// older listings only set one of them.
+	for _, rule := range []string{"pets_allowed", "smoking_allowed"} {
+		if allowed := queryBool(c, validator, rule); allowed != nil {
+			if *allowed {
+				and = append(and, bson.M{"$or": bson.A{
+					bson.M{rule: true},
+					bson.M{"features." + rule: true},
+				}})
+			} else {
+				and = append(and, bson.M{rule: bson.M{"$ne": true}, "features." + rule: bson.M{"$ne": true}})
+			}
+		}
+	}
+
+	for _, field := range []string{"amenities", "tags"} {
+		values := queryList(c, field)
+		if len(values) == 0 {
+			continue
+		}
+		operator := "$all"
+		switch mode := c.DefaultQuery(field+"_match", "all"); mode {
+		case "all":
+		case "any":
+			operator = "$in"
+		default:
+			validator.AddError(field+"_match", field+"_match must be one of: any, all")
+		}
+		search.Filter[field] = bson.M{operator: values}
+	}
+
+	if availableFrom := c.Query("available_from"); availableFrom != "" {
+		date, err := time.Parse("2006-01-02", availableFrom)
+		if err != nil {
+			validator.AddError("available_from", "available_from must be a date in YYYY-MM-DD format")
+		} else {
+			// Listings that can be moved into on or before the given date.
+			search.Filter["available_from"] = bson.M{"$lt": date.AddDate(0, 0, 1)}
+		}
+	}
+
+	if parking := queryBool(c, validator, "parking"); parking != nil {
+		if *parking {
+			search.Filter["parking.available"] = true
+		} else {
+			search.Filter["parking.available"] = bson.M{"$ne": true}
+		}
+	}
+	if parkingType := c.Query("parking_type"); parkingType != "" {
+		validator.ValidateOneOf("parking_type", parkingType, parkingTypes, "Parking type")
+		search.Filter["parking.available"] = true
+		search.Filter["parking.type"] = parkingType
+	}
+
+	requireFlags(c, validator, search.Filter, "features", propertyFeatureFilters)
+	requireFlags(c, validator, search.Filter, "safety", propertySafetyFilters)
+
+	if len(and) > 0 {
+		search.Filter["$and"] = and
+	}
+}
+
+// requireFlags handles list parameters such as features=balcony,gym, which
+// match listings where every named boolean is true.
+func requireFlags(c queryParams, validator *validation.Validator, filter bson.M, param string, allowed []string) {
+	for _, name := range queryList(c, param) {
+		if !contains(allowed, name) {
+			validator.AddError(param, "Unknown "+param+" filter: "+name)
+			continue
+		}
+		filter[param+"."+name] = true
+	}
+}
+
+func queryFloat(c queryParams, validator *validation.Validator, name string) *float64 {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || value < 0 {
+		validator.AddError(name, name+" must be a non-negative number")
+		return nil
+	}
+	return &value
+}
+
+func queryInt(c queryParams, validator *validation.Validator, name string) *int {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 0 {
+		validator.AddError(name, name+" must be a non-negative integer")
+		return nil
+	}
+	return &value
+}
+
+func queryBool(c queryParams, validator *validation.Validator, name string) *bool {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		validator.AddError(name, name+" must be true or false")
+		return nil
+	}
+	return &value
+}
+
+// queryList accepts both comma separated values and repeated parameters, so
+// amenities=wifi,gym and amenities=wifi&amenities=gym are equivalent.
+func queryList(c queryParams, name string) []string {
+	var values []string
+	for _, raw := range c.QueryArray(name) {
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// parseGeoSearch handles the mutually exclusive near=, bbox= and polygon=
+// parameters. near= is answered with $geoNear so results come back sorted by
+// distance; the other two become a $geoWithin filter.
+func parseGeoSearch(c queryParams, search *propertySearch, validator *validation.Validator) {
+	near, bbox, polygon := c.Query("near"), c.Query("bbox"), c.Query("polygon")
+
+	given := 0
+	for _, value := range []string{near, bbox, polygon} {
+		if value != "" {
+			given++
+		}
+	}
+	if given > 1 {
+		validator.AddError("near", "Only one of near, bbox and polygon can be used")
+		return
+	}
+
+	if radius := c.Query("radius_km"); radius != "" && near == "" {
+		validator.AddError("radius_km", "radius_km requires near")
+	}
+
+	switch {
+	case near != "":
+		point, err := geo.ParsePoint(near)
+		if err != nil {
+			validator.AddError("near", "Invalid near: "+err.Error())
+			return
+		}
+		search.Near = &point
+		search.RadiusKm = defaultSearchRadiusKm
+
+		if radius := c.Query("radius_km"); radius != "" {
+			radiusKm, err := strconv.ParseFloat(radius, 64)
+			if err != nil || radiusKm <= 0 || radiusKm > maxSearchRadiusKm {
+				validator.AddError("radius_km", "radius_km must be a number between 0 and "+strconv.Itoa(maxSearchRadiusKm))
+				return
+			}
+			search.RadiusKm = radiusKm
+		}
+	case bbox != "":
+		box, err := geo.ParseBBox(bbox)
+		if err != nil {
+			validator.AddError("bbox", "Invalid bbox: "+err.Error())
+			return
+		}
+		search.Filter["location"] = geoWithin(box.Ring())
+	case polygon != "":
+		points, err := geo.ParsePolygon(polygon)
+		if err != nil {
+			validator.AddError("polygon", "Invalid polygon: "+err.Error())
+			return
+		}
+		search.Filter["location"] = geoWithin(geo.Ring(points))
+	}
+}
+
+func geoWithin(ring [][]float64) bson.M {
+	return bson.M{"$geoWithin": bson.M{"$geometry": bson.M{
+		"type":        "Polygon",
+		"coordinates": [][][]float64{ring},
+	}}}
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"rent-help-backend/pkg/geo"
+	"rent-help-backend/pkg/listing"
+	"rent-help-backend/pkg/pagination"
+	"rent-help-backend/pkg/textsearch"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	testBBox    = "116.3,39.8,116.5,40.0"
+	testPolygon = "39.8,116.3;40.0,116.3;40.0,116.5"
+	testNear    = "39.9,116.4"
+)
+
+func parseSearch(t *testing.T, query, userID string) (*propertySearch, []string) {
+	t.Helper()
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	search, validator := parsePropertySearch(urlQuery(values), userID)
+	var fields []string
+	for _, e := range validator.GetErrors() {
+		fields = append(fields, e.Field)
+	}
+	return search, fields
+}
+
+func TestParsePropertySearchFilter(t *testing.T) {
+	published := func(filter bson.M) bson.M {
+		filter["status"] = listing.Published
+		return filter
+	}
+	petsAllowed := bson.M{"$or": bson.A{bson.M{"pets_allowed": true}, bson.M{"features.pets_allowed": true}}}
+	smokingAllowed := bson.M{"$or": bson.A{bson.M{"smoking_allowed": true}, bson.M{"features.smoking_allowed": true}}}
+	noPets := bson.M{"pets_allowed": bson.M{"$ne": true}, "features.pets_allowed": bson.M{"$ne": true}}
+	noSmoking := bson.M{"smoking_allowed": bson.M{"$ne": true}, "features.smoking_allowed": bson.M{"$ne": true}}
+	box, _ := geo.ParseBBox(testBBox)
+	polygon, _ := geo.ParsePolygon(testPolygon)
+	ownerID := primitive.NewObjectID()
+
+	tests := []struct {
+		name  string
+		query string
+		want  bson.M
+	}{
+		{"no parameters", "", published(bson.M{})},
+		{"pets allowed", "pets_allowed=true", published(bson.M{"$and": bson.A{petsAllowed}})},
+		{"no pets", "pets_allowed=false", published(bson.M{"$and": bson.A{noPets}})},
+		{"pets and smoking", "smoking_allowed=true&pets_allowed=true", published(bson.M{"$and": bson.A{petsAllowed, smokingAllowed}})},
+		{"no pets, no smoking", "pets_allowed=false&smoking_allowed=false", published(bson.M{"$and": bson.A{noPets, noSmoking}})},
+		{"amenities match all by default", "amenities=wifi,gym", published(bson.M{"amenities": bson.M{"$all": []string{"wifi", "gym"}}})},
+		{"amenities match any", "amenities=wifi&amenities=gym&amenities_match=any", published(bson.M{"amenities": bson.M{"$in": []string{"wifi", "gym"}}})},
+		{"tags match all", "tags=quiet&tags_match=all", published(bson.M{"tags": bson.M{"$all": []string{"quiet"}}})},
+		{"match mode without values", "tags_match=any", published(bson.M{})},
+		{"parking", "parking=true", published(bson.M{"parking.available": true})},
+		{"no parking", "parking=false", published(bson.M{"parking.available": bson.M{"$ne": true}})},
+		{"parking type implies parking", "parking_type=garage", published(bson.M{"parking.available": true, "parking.type": "garage"})},
+		{"one type", "type=house", published(bson.M{"type": "house"})},
+		{"several types", "type=house,condo", published(bson.M{"type": bson.M{"$in": []string{"house", "condo"}}})},
+		{"price and rooms", "min_price=1000&max_price=3000&bedrooms=2&bathrooms=1", published(bson.M{
+			"price":     bson.M{"$gte": 1000.0, "$lte": 3000.0},
+			"bedrooms":  bson.M{"$gte": 2},
+			"bathrooms": bson.M{"$gte": 1},
+		})},
+		{"feature and safety flags", "features=balcony,gym&safety=cctv&furnished=true", published(bson.M{
+			"features.balcony":   true,
+			"features.gym":       true,
+			"features.furnished": true,
+			"safety.cctv":        true,
+		})},
+		{"bbox", "bbox=" + testBBox, published(bson.M{"location": geoWithin(box.Ring())})},
+		{"polygon", "polygon=" + url.QueryEscape(testPolygon), published(bson.M{"location": geoWithin(geo.Ring(polygon))})},
+		{"near is not a filter", "near=" + testNear, published(bson.M{})},
+		{"own listings", "mine=true", bson.M{"owner_id": ownerID}},
+		{"own listings by status", "mine=true&status=draft", bson.M{"owner_id": ownerID, "status": listing.Draft}},
+	}
+	for _, tt := range tests {
+		search, errs := parseSearch(t, tt.query, ownerID.Hex())
+		if len(errs) > 0 {
+			t.Errorf("%s: unexpected errors on fields %v", tt.name, errs)
+			continue
+		}
+		if !reflect.DeepEqual(search.Filter, tt.want) {
+			t.Errorf("%s: Filter = %v, want %v", tt.name, search.Filter, tt.want)
+		}
+	}
+}
+
+func TestParsePropertySearchErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"pets_allowed=maybe", []string{"pets_allowed"}},
+		{"amenities=wifi&amenities_match=some", []string{"amenities_match"}},
+		{"tags=quiet&tags_match=none", []string{"tags_match"}},
+		{"parking_type=carport", []string{"parking_type"}},
+		{"type=castle", []string{"type"}},
+		{"type=house,castle", []string{"type"}},
+		{"features=moat", []string{"features"}},
+		{"safety=moat", []string{"safety"}},
+		{"min_price=5000&max_price=1000", []string{"min_price"}},
+		{"min_price=-1", []string{"min_price"}},
+		{"bedrooms=two", []string{"bedrooms"}},
+		{"available_from=tomorrow", []string{"available_from"}},
+		{"status=draft", []string{"status"}},
+		{"mine=true&status=rented", []string{"status"}},
+		{"near=" + testNear + "&bbox=" + testBBox, []string{"near"}},
+		{"bbox=" + testBBox + "&polygon=" + url.QueryEscape(testPolygon), []string{"near"}},
+		{"radius_km=5", []string{"radius_km"}},
+		{"near=" + testNear + "&radius_km=500", []string{"radius_km"}},
+		{"near=100,0", []string{"near"}},
+		{"bbox=1,2,3", []string{"bbox"}},
+		{"polygon=1,2%3B3,4", []string{"polygon"}},
+		{"near=" + testNear + "&q=flat", []string{"q"}},
+		{"q=%2B%2B%2B", []string{"q"}},
+		{"sort=distance", []string{"sort"}},
+		{"sort=relevance", []string{"sort"}},
+		{"sort=bogus", []string{"sort"}},
+		{"skip=10", []string{"skip"}},
+	}
+	for _, tt := range tests {
+		_, errs := parseSearch(t, tt.query, "")
+		if !reflect.DeepEqual(errs, tt.want) {
+			t.Errorf("%q: errors on fields %v, want %v", tt.query, errs, tt.want)
+		}
+	}
+}
+
+func TestParsePropertySearchModes(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		near     bool
+		radiusKm float64
+		text     string
+		sort     pagination.Sort
+	}{
+		{"newest first by default", "", false, 0, "", newestFirst},
+		{"near sorts by distance", "near=" + testNear, true, defaultSearchRadiusKm, "", pagination.Sort{Field: "distance"}},
+		{"near with radius", "near=" + testNear + "&radius_km=5", true, 5, "", pagination.Sort{Field: "distance"}},
+		{"near sorted by price", "near=" + testNear + "&sort=price", true, defaultSearchRadiusKm, "", pagination.Sort{Field: "price"}},
+		{"q sorts by relevance", "q=两居室 metro", false, 0, textsearch.Query("两居室 metro"), pagination.Sort{Field: "relevance", Desc: true}},
+		{"q sorted by date", "q=flat&sort=-created_at", false, 0, "flat", newestFirst},
+		{"q with bbox", "q=flat&bbox=" + testBBox, false, 0, "flat", pagination.Sort{Field: "relevance", Desc: true}},
+	}
+	for _, tt := range tests {
+		search, errs := parseSearch(t, tt.query, "")
+		if len(errs) > 0 {
+			t.Errorf("%s: unexpected errors on fields %v", tt.name, errs)
+			continue
+		}
+		if (search.Near != nil) != tt.near || search.RadiusKm != tt.radiusKm {
+			t.Errorf("%s: Near = %v, RadiusKm = %v", tt.name, search.Near, search.RadiusKm)
+		}
+		if search.Query != tt.text {
+			t.Errorf("%s: Query = %q, want %q", tt.name, search.Query, tt.text)
+		}
+		if search.Page.Sort != tt.sort {
+			t.Errorf("%s: Sort = %+v, want %+v", tt.name, search.Page.Sort, tt.sort)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+
+	"rent-help-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type PublicProfileHandler struct {
+	publicProfileService *services.PublicProfileService
+}
+
+func NewPublicProfileHandler(publicProfileService *services.PublicProfileService) *PublicProfileHandler {
+	return &PublicProfileHandler{
+		publicProfileService: publicProfileService,
+	}
+}
+
+func (h *PublicProfileHandler) GetPublicProfile(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	profile, err := h.publicProfileService.GetPublicProfile(id)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user profile"})
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"rent-help-backend/internal/models"
+	"rent-help-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPublicProfileReviews(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+
+	host := models.User{ID: primitive.NewObjectID(), FullName: "Host", Role: "landlord", IsActive: true}
+	guest := models.User{ID: primitive.NewObjectID(), FullName: "Guest", Role: "tenant", IsActive: true}
+	if _, err := db.Collection("users").InsertMany(ctx, []interface{}{host, guest}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	review := func(comment string, public bool, at time.Time) *models.Review {
+		return &models.Review{
+			ID:         primitive.NewObjectID(),
+			ReviewerID: guest.ID,
+			RevieweeID: host.ID,
+			Type:       "tenant_to_landlord",
+			Comment:    comment,
+			IsPublic:   public,
+			CreatedAt:  at,
+		}
+	}
+	booking := func(status string, reviews models.BookingReviews) interface{} {
+		return models.Booking{
+			ID:         primitive.NewObjectID(),
+			PropertyID: primitive.NewObjectID(),
+			TenantID:   guest.ID,
+			LandlordID: host.ID,
+			Status:     status,
+			Reviews:    reviews,
+		}
+	}
+	_, err := db.Collection("bookings").InsertMany(ctx, []interface{}{
+		// The landlord has not reviewed the guest yet, so that side is null.
+		booking("completed", models.BookingReviews{TenantReview: review("older", true, now.Add(-time.Hour))}),
+		booking("completed", models.BookingReviews{TenantReview: review("newer", true, now)}),
+		booking("completed", models.BookingReviews{TenantReview: review("private", false, now)}),
+		booking("pending", models.BookingReviews{TenantReview: review("not stayed", true, now)}),
+		booking("completed", models.BookingReviews{}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/v1/users/:id/public", NewPublicProfileHandler(services.NewPublicProfileService(db)).GetPublicProfile)
+
+	w := serve(router, "/api/v1/users/"+host.ID.Hex()+"/public")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var profile struct {
+		RecentReviews []struct {
+			Comment  string `json:"comment"`
+			Reviewer struct {
+				FullName string `json:"full_name"`
+			} `json:"reviewer"`
+		} `json:"recent_reviews"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &profile); err != nil {
+		t.Fatal(err)
+	}
+	var comments []string
+	for _, r := range profile.RecentReviews {
+		comments = append(comments, r.Comment)
+		if r.Reviewer.FullName != guest.FullName {
+			t.Errorf("reviewer = %q, want %q", r.Reviewer.FullName, guest.FullName)
+		}
+	}
+	if len(comments) != 2 || comments[0] != "newer" || comments[1] != "older" {
+		t.Fatalf("recent reviews = %v, want [newer older]", comments)
+	}
+
+	// The guest has no reviews, only null sides and reviews they wrote.
+	if w := serve(router, "/api/v1/users/"+guest.ID.Hex()+"/public"); w.Code != http.StatusOK {
+		t.Fatalf("guest profile status = %d: %s", w.Code, w.Body)
+	}
+}
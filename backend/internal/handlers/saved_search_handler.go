@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"rent-help-backend/internal/models"
+	"rent-help-backend/internal/services"
+	"rent-help-backend/pkg/validation"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// pagingParams are the GET /properties parameters that are dropped from a
+// saved search.
+var pagingParams = []string{"limit", "cursor", "sort", "skip"}
+
+type SavedSearchHandler struct {
+	savedSearchService *services.SavedSearchService
+}
+
+func NewSavedSearchHandler(savedSearchService *services.SavedSearchService) *SavedSearchHandler {
+	return &SavedSearchHandler{
+		savedSearchService: savedSearchService,
+	}
+}
+
+// GetSavedSearches lists the current user's saved searches, newest first.
+func (h *SavedSearchHandler) GetSavedSearches(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	searches, err := h.savedSearchService.ListSavedSearches(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get saved searches"})
+		return
+	}
+
+	c.JSON(http.StatusOK, listResponse(searches, nil, int64(len(searches))))
+}
+
+// CreateSavedSearch saves a GET /properties query under a name. Alerts are
+// on unless the request turns them off.
+func (h *SavedSearchHandler) CreateSavedSearch(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+
+	var req models.CreateSavedSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query, validator := savedSearchQuery(req.Query, user.Preferences)
+	if validator.HasErrors() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": validator.GetErrors()})
+		return
+	}
+
+	search := &models.SavedSearch{
+		UserID: user.ID,
+		Name:   strings.TrimSpace(req.Name),
+		Query:  query,
+		Alerts: req.Alerts == nil || *req.Alerts,
+	}
+	switch err := h.savedSearchService.CreateSavedSearch(search); err {
+	case nil:
+		c.JSON(http.StatusCreated, search)
+	case services.ErrTooManySavedSearches:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save search"})
+	}
+}
+
+// UpdateSavedSearch renames a saved search or turns its alerts on or off.
+// The query itself cannot be changed; save a new search instead.
+func (h *SavedSearchHandler) UpdateSavedSearch(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid saved search ID"})
+		return
+	}
+
+	var req models.UpdateSavedSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Name cannot be empty"})
+			return
+		}
+		req.Name = &name
+	}
+	if req.Name == nil && req.Alerts == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to update"})
+		return
+	}
+
+	search, err := h.savedSearchService.UpdateSavedSearch(userID, id, &req)
+	switch err {
+	case nil:
+		c.JSON(http.StatusOK, search)
+	case services.ErrSavedSearchNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Saved search not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update saved search"})
+	}
+}
+
+func (h *SavedSearchHandler) DeleteSavedSearch(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid saved search ID"})
+		return
+	}
+
+	switch err := h.savedSearchService.DeleteSavedSearch(userID, id); err {
+	case nil:
+		c.JSON(http.StatusOK, gin.H{"message": "Saved search deleted successfully"})
+	case services.ErrSavedSearchNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Saved search not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete saved search"})
+	}
+}
+
+// ParseSavedSearch reads the query of a saved search with the GET /properties
+// parser. It is the services.SearchParser of the alert matcher.
+func ParseSavedSearch(query string) (*services.SearchCriteria, error) {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	search, validator := parsePropertySearch(urlQuery(values), "")
+	if validator.HasErrors() {
+		return nil, validator.GetErrors()
+	}
+	return &services.SearchCriteria{
+		Filter:   search.Filter,
+		Near:     search.Near,
+		RadiusKm: search.RadiusKm,
+		Query:    search.Query,
+	}, nil
+}
+
+// savedSearchQuery validates the query of a new saved search and returns it
+// without the paging parameters. raw may also be a whole URL. An empty query
+// is built from the user's preferred property types and price range, and a
+// proximity search without radius_km gets the preferred search radius.
+func savedSearchQuery(raw string, prefs models.UserPreferences) (string, *validation.Validator) {
+	validator := validation.NewValidator()
+
+	if i := strings.IndexByte(raw, '?'); i >= 0 {
+		raw = raw[i+1:]
+	}
+	query, err := url.ParseQuery(strings.TrimSpace(raw))
+	if err != nil {
+		validator.AddError("query", "query must be a URL query string")
+		return "", validator
+	}
+	for _, name := range pagingParams {
+		query.Del(name)
+	}
+	if query.Has("mine") || query.Has("status") {
+		validator.AddError("query", "Searches of your own listings (mine, status) cannot be saved")
+		return "", validator
+	}
+
+	if len(query) == 0 {
+		query = preferencesQuery(prefs)
+		if len(query) == 0 {
+			validator.AddError("query", "query is required when your preferences have no property types or price range")
+			return "", validator
+		}
+	}
+	if query.Get("near") != "" && query.Get("radius_km") == "" && prefs.SearchRadius > 0 {
+		radiusKm := prefs.SearchRadius
+		if radiusKm > maxSearchRadiusKm {
+			radiusKm = maxSearchRadiusKm
+		}
+		query.Set("radius_km", strconv.Itoa(radiusKm))
+	}
+
+	if _, parsed := parsePropertySearch(urlQuery(query), ""); parsed.HasErrors() {
+		return "", parsed
+	}
+	return query.Encode(), validator
+}
+
+// preferencesQuery turns the search preferences of a user into a
+// GET /properties query.
+func preferencesQuery(prefs models.UserPreferences) url.Values {
+	query := url.Values{}
+	if len(prefs.PropertyTypes) > 0 {
+		query.Set("type", strings.Join(prefs.PropertyTypes, ","))
+	}
+	if prefs.PriceRange.Min > 0 {
+		query.Set("min_price", strconv.FormatFloat(prefs.PriceRange.Min, 'f', -1, 64))
+	}
+	if prefs.PriceRange.Max > 0 {
+		query.Set("max_price", strconv.FormatFloat(prefs.PriceRange.Max, 'f', -1, 64))
+	}
+	return query
+}
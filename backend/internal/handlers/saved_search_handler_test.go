@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	"rent-help-backend/internal/models"
+	"rent-help-backend/pkg/listing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestPreferencesQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		prefs models.UserPreferences
+		want  string
+	}{
+		{"no search preferences", models.UserPreferences{Currency: "CNY", SearchRadius: 5}, ""},
+		{"types", models.UserPreferences{PropertyTypes: []string{"apartment", "studio"}}, "type=apartment%2Cstudio"},
+		{"price range", models.UserPreferences{PriceRange: models.PriceRange{Min: 1000, Max: 3500.5}}, "max_price=3500.5&min_price=1000"},
+		{"maximum price only", models.UserPreferences{PriceRange: models.PriceRange{Max: 3000}}, "max_price=3000"},
+	}
+	for _, tt := range tests {
+		if got := preferencesQuery(tt.prefs).Encode(); got != tt.want {
+			t.Errorf("%s: preferencesQuery = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSavedSearchQuery(t *testing.T) {
+	prefs := models.UserPreferences{
+		PropertyTypes: []string{"apartment"},
+		PriceRange:    models.PriceRange{Max: 3000},
+		SearchRadius:  5,
+	}
+	farRadius := prefs
+	farRadius.SearchRadius = 500
+	noSearchPrefs := models.UserPreferences{SearchRadius: 5}
+
+	tests := []struct {
+		name  string
+		raw   string
+		prefs models.UserPreferences
+		want  string
+		errs  []string
+	}{
+		{"paging parameters are dropped", "city=Beijing&limit=5&cursor=abc&sort=price&skip=10", prefs, "city=Beijing", nil},
+		{"whole URL", "https://app.example/properties?city=Beijing&type=house", prefs, "city=Beijing&type=house", nil},
+		{"surrounding spaces", "  pets_allowed=true  ", prefs, "pets_allowed=true", nil},
+		{"empty query uses preferences", "", prefs, "max_price=3000&type=apartment", nil},
+		{"paging only uses preferences", "limit=20", prefs, "max_price=3000&type=apartment", nil},
+		{"near gets the preferred radius", "near=39.9,116.4", prefs, "near=39.9%2C116.4&radius_km=5", nil},
+		{"preferred radius is capped", "near=39.9,116.4", farRadius, "near=39.9%2C116.4&radius_km=200", nil},
+		{"explicit radius is kept", "near=39.9,116.4&radius_km=3", prefs, "near=39.9%2C116.4&radius_km=3", nil},
+		{"no preferred radius", "near=39.9,116.4", models.UserPreferences{}, "near=39.9%2C116.4", nil},
+		{"empty query without preferences", "", noSearchPrefs, "", []string{"query"}},
+		{"own listings", "mine=true", prefs, "", []string{"query"}},
+		{"status", "status=draft", prefs, "", []string{"query"}},
+		{"not a query string", "city=%zz", prefs, "", []string{"query"}},
+		{"invalid parameter", "pets_allowed=maybe&parking_type=carport", prefs, "", []string{"pets_allowed", "parking_type"}},
+		{"sort is dropped before validation", "sort=distance&city=Beijing", prefs, "city=Beijing", nil},
+		{"geo parameters are validated", "near=39.9,116.4&bbox=116.3,39.8,116.5,40.0", prefs, "", []string{"near"}},
+		{"near and q", "near=39.9,116.4&q=garden", prefs, "", []string{"q"}},
+	}
+	for _, tt := range tests {
+		got, validator := savedSearchQuery(tt.raw, tt.prefs)
+		var errs []string
+		for _, e := range validator.GetErrors() {
+			errs = append(errs, e.Field)
+		}
+		if !reflect.DeepEqual(errs, tt.errs) {
+			t.Errorf("%s: errors on fields %v, want %v", tt.name, errs, tt.errs)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: savedSearchQuery = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseSavedSearch(t *testing.T) {
+	criteria, err := ParseSavedSearch("near=39.9%2C116.4&radius_km=5&type=house%2Ccondo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := bson.M{"status": listing.Published, "type": bson.M{"$in": []string{"house", "condo"}}}
+	if !reflect.DeepEqual(criteria.Filter, want) {
+		t.Errorf("Filter = %v, want %v", criteria.Filter, want)
+	}
+	if criteria.Near == nil || criteria.Near.Lat != 39.9 || criteria.Near.Lng != 116.4 || criteria.RadiusKm != 5 || criteria.Query != "" {
+		t.Errorf("criteria = %+v", criteria)
+	}
+
+	criteria, err = ParseSavedSearch("q=garden+flat&bbox=116.3,39.8,116.5,40.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if criteria.Query != "garden flat" || criteria.Near != nil || criteria.Filter["location"] == nil {
+		t.Errorf("criteria = %+v", criteria)
+	}
+
+	for _, query := range []string{"near=39.9,116.4&q=flat", "mine=true&status=rented", "%zz"} {
+		if _, err := ParseSavedSearch(query); err == nil {
+			t.Errorf("ParseSavedSearch(%q) accepted an invalid query", query)
+		}
+	}
+}
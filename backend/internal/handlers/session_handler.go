@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"net/http"
+
+	"rent-help-backend/internal/models"
+	"rent-help-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SessionHandler struct {
+	tokenService *services.TokenService
+}
+
+func NewSessionHandler(tokenService *services.TokenService) *SessionHandler {
+	return &SessionHandler{
+		tokenService: tokenService,
+	}
+}
+
+// GetSessions lists the devices the user is signed in on. The session the
+// request was made with is flagged as current.
+func (h *SessionHandler) GetSessions(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := primitive.ObjectIDFromHex(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	sessions, err := h.tokenService.GetSessions(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get sessions"})
+		return
+	}
+
+	current := c.GetString("session_id")
+	for _, session := range sessions {
+		session.Current = session.ID.Hex() == current
+	}
+
+	c.JSON(http.StatusOK, listResponse(sessions, nil, int64(len(sessions))))
+}
+
+// RevokeSession signs the user out on one device. Access tokens of that
+// session stop working immediately.
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	sessionID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	userIDStr, _ := c.Get("user_id")
+	userID, err := primitive.ObjectIDFromHex(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.tokenService.RevokeSession(userID, sessionID); err != nil {
+		if err == services.ErrSessionNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
+}
+
+// maxUserAgentLength bounds what is stored from the client-controlled header.
+const maxUserAgentLength = 512
+
+// sessionClient describes the client making a login request.
+func sessionClient(c *gin.Context, loginMethod string) models.SessionClient {
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	return models.SessionClient{
+		UserAgent:   userAgent,
+		IP:          c.ClientIP(),
+		LoginMethod: loginMethod,
+	}
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"rent-help-backend/internal/models"
+	"rent-help-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func (h *UserHandler) SetupTwoFactor(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	setup, err := h.twoFactorService.BeginSetup(user)
+	if err != nil {
+		if err == services.ErrTwoFactorAlreadyEnabled {
+			c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start two-factor setup"})
+		return
+	}
+
+	c.JSON(http.StatusOK, setup)
+}
+
+func (h *UserHandler) ConfirmTwoFactor(c *gin.Context) {
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	recoveryCodes, err := h.twoFactorService.ConfirmSetup(user, req.Code)
+	if err != nil {
+		switch err {
+		case services.ErrTwoFactorAlreadyEnabled:
+			c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		case services.ErrTwoFactorNotPending:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor setup has not been started"})
+		case services.ErrInvalidTwoFactorCode:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid two-factor code"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+func (h *UserHandler) DisableTwoFactor(c *gin.Context) {
+	var req models.TwoFactorDisableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userService.ValidatePassword(user.Password, req.Password); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
+
+	if err := h.twoFactorService.Disable(user, req.Code); err != nil {
+		switch err {
+		case services.ErrTwoFactorNotEnabled:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		case services.ErrInvalidTwoFactorCode:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid two-factor code"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// VerifyTwoFactorLogin completes a login started by Login for a user with
+// 2FA enabled. Wrong codes count towards the same throttle as passwords.
+func (h *UserHandler) VerifyTwoFactorLogin(c *gin.Context) {
+	var req models.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, err := h.twoFactorService.ParseChallenge(req.ChallengeToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired two-factor challenge"})
+		return
+	}
+
+	user, err := h.userService.GetUserByID(userID)
+	if err != nil || !user.IsActive || !user.TwoFactor.Enabled {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired two-factor challenge"})
+		return
+	}
+
+	if !h.allowLoginAttempt(c, user.Email) {
+		return
+	}
+
+	if err := h.twoFactorService.VerifyCode(user, req.Code); err != nil {
+		if err != services.ErrInvalidTwoFactorCode {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify two-factor code"})
+			return
+		}
+		if err := h.loginThrottleService.RecordFailure(user.Email, c.ClientIP()); err != nil {
+			log.Printf("Failed to record login failure for %s: %v", user.Email, err)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code"})
+		return
+	}
+
+	h.respondWithTokens(c, user)
+}
+
+// currentUser loads the authenticated user, writing an error response and
+// returning false when that is not possible.
+func (h *UserHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := primitive.ObjectIDFromHex(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return nil, false
+	}
+
+	user, err := h.userService.GetUserByID(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+	return user, true
+}
@@ -1,28 +1,40 @@
 package handlers
 
 import (
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"rent-help-backend/internal/config"
 	"rent-help-backend/internal/models"
 	"rent-help-backend/internal/services"
+	"rent-help-backend/pkg/validation"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type UserHandler struct {
-	userService *services.UserService
-	config      *config.Config
+	userService          *services.UserService
+	tokenService         *services.TokenService
+	verificationService  *services.VerificationService
+	passwordResetService *services.PasswordResetService
+	loginThrottleService *services.LoginThrottleService
+	twoFactorService     *services.TwoFactorService
+	config               *config.Config
 }
 
-func NewUserHandler(userService *services.UserService, cfg *config.Config) *UserHandler {
+func NewUserHandler(userService *services.UserService, tokenService *services.TokenService, verificationService *services.VerificationService, passwordResetService *services.PasswordResetService, loginThrottleService *services.LoginThrottleService, twoFactorService *services.TwoFactorService, cfg *config.Config) *UserHandler {
 	return &UserHandler{
-		userService: userService,
-		config:      cfg,
+		userService:          userService,
+		tokenService:         tokenService,
+		verificationService:  verificationService,
+		passwordResetService: passwordResetService,
+		loginThrottleService: loginThrottleService,
+		twoFactorService:     twoFactorService,
+		config:               cfg,
 	}
 }
 
@@ -57,6 +69,12 @@ func (h *UserHandler) Register(c *gin.Context) {
 		return
 	}
 
+	// The account is usable without a delivered email; the user can ask for
+	// a new link later.
+	if err := h.verificationService.SendVerificationEmail(user); err != nil {
+		log.Printf("Failed to send verification email to %s: %v", user.Email, err)
+	}
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "User created successfully",
 		"user": gin.H{
@@ -75,34 +93,86 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
+	if !h.allowLoginAttempt(c, req.Email) {
+		return
+	}
+
 	user, err := h.userService.GetUserByEmail(req.Email)
+	if err == nil {
+		err = h.userService.ValidatePassword(user.Password, req.Password)
+	}
 	if err != nil {
+		if err := h.loginThrottleService.RecordFailure(req.Email, c.ClientIP()); err != nil {
+			log.Printf("Failed to record login failure for %s: %v", req.Email, err)
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
-	if err := h.userService.ValidatePassword(user.Password, req.Password); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+	if !user.IsActive {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is deactivated"})
 		return
 	}
 
-	// Generate JWT token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID.Hex(),
-		"email":   user.Email,
-		"role":    user.Role,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
-	})
+	// With 2FA on, the password only earns a challenge that has to be
+	// completed at POST /auth/2fa/verify.
+	if user.TwoFactor.Enabled {
+		challengeToken, err := h.twoFactorService.CreateChallenge(user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create two-factor challenge"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"two_factor_required": true,
+			"challenge_token":     challengeToken,
+		})
+		return
+	}
+
+	h.respondWithTokens(c, user)
+}
+
+// allowLoginAttempt answers with 429 and Retry-After while the email or the
+// client IP is throttled, and reports whether the login may proceed.
+func (h *UserHandler) allowLoginAttempt(c *gin.Context, email string) bool {
+	retryAfter, err := h.loginThrottleService.Check(email, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check login attempts"})
+		return false
+	}
+	if retryAfter > 0 {
+		seconds := int(math.Ceil(retryAfter.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":       "Too many failed login attempts, please try again later",
+			"retry_after": seconds,
+		})
+		return false
+	}
+	return true
+}
+
+// respondWithTokens completes a login by issuing tokens for the user. The
+// failed attempt counter is only cleared here, after every factor passed.
+func (h *UserHandler) respondWithTokens(c *gin.Context, user *models.User) {
+	if err := h.loginThrottleService.RecordSuccess(user.Email); err != nil {
+		log.Printf("Failed to reset login attempts for %s: %v", user.Email, err)
+	}
 
-	tokenString, err := token.SignedString([]byte(h.config.JWTSecret))
+	tokens, err := h.tokenService.IssueTokens(user, sessionClient(c, "password"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
+	if err := h.userService.RecordLogin(user.ID); err != nil {
+		log.Printf("Failed to record login for %s: %v", user.Email, err)
+	}
+
 	response := gin.H{
-		"access_token": tokenString,
-		"expires_in":   24 * 3600, // 24 hours in seconds
+		"access_token":  tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
 		"user": gin.H{
 			"id":          user.ID.Hex(),
 			"email":       user.Email,
@@ -119,8 +189,153 @@ func (h *UserHandler) Login(c *gin.Context) {
 }
 
 func (h *UserHandler) RefreshToken(c *gin.Context) {
-	// Implement refresh token logic
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "Not implemented yet"})
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	current, err := h.tokenService.ConsumeRefreshToken(req.RefreshToken)
+	if err != nil {
+		switch err {
+		case services.ErrRefreshTokenReused:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token reuse detected, please log in again"})
+		case services.ErrInvalidRefreshToken:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		}
+		return
+	}
+
+	user, err := h.userService.GetUserByID(current.UserID)
+	if err != nil || !user.IsActive {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+
+	tokens, err := h.tokenService.RotateTokens(user, current.FamilyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+func (h *UserHandler) Logout(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.tokenService.RevokeAccessToken(c.GetString("jti"), userID, c.GetTime("token_expires_at")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	// Also end the refresh token family the access token was issued with.
+	if sessionID, err := primitive.ObjectIDFromHex(c.GetString("session_id")); err == nil {
+		if err := h.tokenService.RevokeFamily(sessionID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+func (h *UserHandler) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Verification token required"})
+		return
+	}
+
+	user, err := h.verificationService.VerifyEmail(token)
+	if err != nil {
+		if err == services.ErrInvalidVerificationToken {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Email verified successfully",
+		"email":   user.Email,
+	})
+}
+
+func (h *UserHandler) ResendVerification(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := primitive.ObjectIDFromHex(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	user, err := h.userService.GetUserByID(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.IsVerified {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already verified"})
+		return
+	}
+
+	if err := h.verificationService.SendVerificationEmail(user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
+
+func (h *UserHandler) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.passwordResetService.RequestReset(req.Email); err != nil {
+		log.Printf("Failed to process password reset for %s: %v", req.Email, err)
+	}
+
+	// Always answer the same way so the endpoint cannot be used to find
+	// registered addresses.
+	c.JSON(http.StatusOK, gin.H{"message": "If the email is registered, a password reset link has been sent"})
+}
+
+func (h *UserHandler) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	validator := validation.NewValidator()
+	validator.ValidatePassword("password", req.Password)
+	if validator.HasErrors() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": validator.GetErrors()})
+		return
+	}
+
+	if err := h.passwordResetService.ResetPassword(req.Token, req.Password); err != nil {
+		if err == services.ErrInvalidResetToken {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully, please log in again"})
 }
 
 func (h *UserHandler) GetProfile(c *gin.Context) {
@@ -148,16 +363,15 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 		return
 	}
 
-	var updates bson.M
-	if err := c.ShouldBindJSON(&updates); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var req models.UpdateProfileRequest
+	if !bindPatch(c, &req) {
 		return
 	}
 
-	// Remove sensitive fields
-	delete(updates, "password")
-	delete(updates, "email")
-	delete(updates, "_id")
+	updates, ok := patchUpdates(c, &req, validateProfileUpdate(&req))
+	if !ok {
+		return
+	}
 
 	if err := h.userService.UpdateUser(userID, updates); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
@@ -166,3 +380,84 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Profile updated successfully"})
 }
+
+func validateProfileUpdate(req *models.UpdateProfileRequest) *validation.Validator {
+	validator := validation.NewValidator()
+
+	if req.FullName != nil {
+		validator.ValidateRequired("full_name", *req.FullName, "Full name")
+		validator.ValidateMinLength("full_name", *req.FullName, 2, "Full name")
+		validator.ValidateMaxLength("full_name", *req.FullName, 100, "Full name")
+	}
+	if req.FirstName != nil {
+		validator.ValidateMaxLength("first_name", *req.FirstName, 50, "First name")
+	}
+	if req.LastName != nil {
+		validator.ValidateMaxLength("last_name", *req.LastName, 50, "Last name")
+	}
+	if req.Phone != nil {
+		validator.ValidatePhoneNumber("phone", *req.Phone)
+	}
+	if req.Avatar != nil {
+		validator.ValidateURL("avatar", *req.Avatar)
+	}
+	if req.DateOfBirth != nil && req.DateOfBirth.After(time.Now()) {
+		validator.AddError("date_of_birth", "Date of birth must be in the past")
+	}
+	if req.Gender != nil {
+		validator.ValidateOneOf("gender", *req.Gender, []string{"male", "female", "other"}, "Gender")
+	}
+	if req.Occupation != nil {
+		validator.ValidateMaxLength("occupation", *req.Occupation, 100, "Occupation")
+	}
+	if req.Bio != nil {
+		validator.ValidateMaxLength("bio", *req.Bio, 1000, "Bio")
+	}
+
+	if prefs := req.Preferences; prefs != nil {
+		if prefs.Currency != nil {
+			validator.ValidateCurrencyCode("preferences.currency", *prefs.Currency)
+		}
+		if prefs.Timezone != nil {
+			validator.ValidateTimezone("preferences.timezone", *prefs.Timezone)
+		}
+		if prefs.SearchRadius != nil {
+			validator.ValidateNumericRange("preferences.search_radius", float64(*prefs.SearchRadius), 0, 500, "Search radius")
+		}
+		for _, propertyType := range prefs.PropertyTypes {
+			validator.ValidateOneOf("preferences.property_types", propertyType, propertyTypes, "Property type")
+		}
+		if pr := prefs.PriceRange; pr != nil {
+			if pr.Min != nil && *pr.Min < 0 {
+				validator.AddError("preferences.price_range.min", "Minimum price cannot be negative")
+			}
+			if pr.Min != nil && pr.Max != nil && *pr.Max < *pr.Min {
+				validator.AddError("preferences.price_range.max", "Maximum price must not be less than minimum price")
+			}
+			if pr.Currency != nil {
+				validator.ValidateCurrencyCode("preferences.price_range.currency", *pr.Currency)
+			}
+		}
+	}
+
+	if req.Address != nil {
+		validateAddressUpdate(validator, "address", req.Address)
+	}
+
+	if links := req.SocialLinks; links != nil {
+		if links.Facebook != nil {
+			validator.ValidateURL("social_links.facebook", *links.Facebook)
+		}
+		if links.Twitter != nil {
+			validator.ValidateURL("social_links.twitter", *links.Twitter)
+		}
+		if links.LinkedIn != nil {
+			validator.ValidateURL("social_links.linkedin", *links.LinkedIn)
+		}
+		if links.Instagram != nil {
+			validator.ValidateURL("social_links.instagram", *links.Instagram)
+		}
+	}
+
+	return validator
+}
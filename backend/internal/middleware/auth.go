@@ -5,13 +5,32 @@ import (
 	"strings"
 
 	"rent-help-backend/internal/config"
+	"rent-help-backend/internal/models"
+	"rent-help-backend/internal/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
+const (
+	AuthMethodJWT    = "jwt"
+	AuthMethodAPIKey = "api_key"
+
+	apiKeyHeader = "X-API-Key"
+	apiKeyPrefix = "rh_"
+)
+
+// AuthMiddleware authenticates the request with either a Bearer JWT access
+// token or an API key. API keys are sent in the X-API-Key header or as the
+// Bearer token. Both set the same user_id, email and role context values.
+func AuthMiddleware(cfg *config.Config, tokenService *services.TokenService, userService *services.UserService, apiKeyService *services.APIKeyService) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if apiKey := c.GetHeader(apiKeyHeader); apiKey != "" {
+			authenticateAPIKey(c, apiKey, userService, apiKeyService)
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
@@ -26,9 +45,14 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
+		if strings.HasPrefix(tokenString, apiKeyPrefix) {
+			authenticateAPIKey(c, tokenString, userService, apiKeyService)
+			return
+		}
+
 		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 			return []byte(cfg.JWTSecret), nil
-		})
+		}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
 		if err != nil || !token.Valid {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
@@ -36,10 +60,158 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok {
-			c.Set("user_id", claims["user_id"])
-			c.Set("email", claims["email"])
-			c.Set("role", claims["role"])
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Abort()
+			return
+		}
+
+		// Only access tokens carry user_id and jti; reject other tokens signed
+		// with the same secret, such as email verification links.
+		userIDStr, _ := claims["user_id"].(string)
+		jti, _ := claims["jti"].(string)
+		userID, err := primitive.ObjectIDFromHex(userIDStr)
+		if err != nil || jti == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Abort()
+			return
+		}
+
+		revoked, err := tokenService.IsAccessTokenRevoked(jti)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate token"})
+			c.Abort()
+			return
+		}
+		if revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+			c.Abort()
+			return
+		}
+
+		sessionID, err := primitive.ObjectIDFromHex(stringClaim(claims, "sid"))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Abort()
+			return
+		}
+		active, err := tokenService.TouchSession(sessionID, c.ClientIP())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate token"})
+			c.Abort()
+			return
+		}
+		if !active {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+			c.Abort()
+			return
+		}
+
+		user, ok := loadActiveUser(c, userID, userService)
+		if !ok {
+			return
+		}
+
+		// Tokens issued before the last password change belong to sessions
+		// that were invalidated by it.
+		issuedAt, _ := claims.GetIssuedAt()
+		if user.PasswordChangedAt != nil && (issuedAt == nil || issuedAt.Unix() < user.PasswordChangedAt.Unix()) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+			c.Abort()
+			return
+		}
+
+		setUser(c, user, AuthMethodJWT)
+		c.Set("jti", jti)
+		c.Set("session_id", sessionID.Hex())
+		if expiresAt, _ := claims.GetExpirationTime(); expiresAt != nil {
+			c.Set("token_expires_at", expiresAt.Time)
+		}
+
+		c.Next()
+	}
+}
+
+func stringClaim(claims jwt.MapClaims, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+func authenticateAPIKey(c *gin.Context, rawKey string, userService *services.UserService, apiKeyService *services.APIKeyService) {
+	key, err := apiKeyService.Authenticate(rawKey)
+	if err != nil {
+		if err == services.ErrInvalidAPIKey {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate API key"})
+		}
+		c.Abort()
+		return
+	}
+
+	user, ok := loadActiveUser(c, key.UserID, userService)
+	if !ok {
+		return
+	}
+
+	setUser(c, user, AuthMethodAPIKey)
+	c.Set("api_key_id", key.ID.Hex())
+	c.Set("scopes", key.Scopes)
+
+	c.Next()
+}
+
+func loadActiveUser(c *gin.Context, userID primitive.ObjectID, userService *services.UserService) (*models.User, bool) {
+	user, err := userService.GetUserByID(userID)
+	if err != nil || !user.IsActive {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Account is inactive"})
+		c.Abort()
+		return nil, false
+	}
+	return user, true
+}
+
+// setUser stores the authenticated user. Email and role come from the stored
+// user so that changes made by an admin apply without waiting for the token
+// to expire.
+func setUser(c *gin.Context, user *models.User, method string) {
+	c.Set("user", user)
+	c.Set("user_id", user.ID.Hex())
+	c.Set("email", user.Email)
+	c.Set("role", user.Role)
+	c.Set("auth_method", method)
+}
+
+// RequireScope lets API key requests through only when the key was granted
+// scope. Requests authenticated with a JWT are not restricted by scopes.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("auth_method") != AuthMethodAPIKey {
+			c.Next()
+			return
+		}
+
+		for _, granted := range c.GetStringSlice("scopes") {
+			if granted == scope {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "API key is missing the " + scope + " scope"})
+		c.Abort()
+	}
+}
+
+// DenyAPIKeys restricts a route to users that logged in interactively, for
+// endpoints such as account and key management that no scope covers.
+func DenyAPIKeys() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("auth_method") == AuthMethodAPIKey {
+			c.JSON(http.StatusForbidden, gin.H{"error": "API keys cannot be used for this endpoint"})
+			c.Abort()
+			return
 		}
 
 		c.Next()
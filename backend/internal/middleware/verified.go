@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+
+	"rent-help-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireVerifiedEmail rejects users that have not confirmed their email
+// address yet. It reads the user loaded by AuthMiddleware, so verifying takes
+// effect immediately rather than when the token is refreshed.
+func RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := c.MustGet("user").(*models.User)
+		if !ok || !user.IsVerified {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Email address not verified"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
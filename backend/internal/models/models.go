@@ -7,30 +7,45 @@ import (
 )
 
 type User struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Email       string             `bson:"email" json:"email" binding:"required,email"`
-	Password    string             `bson:"password" json:"-"`
-	FullName    string             `bson:"full_name" json:"full_name" binding:"required"`
-	FirstName   string             `bson:"first_name" json:"first_name"`
-	LastName    string             `bson:"last_name" json:"last_name"`
-	Phone       string             `bson:"phone" json:"phone"`
-	Avatar      string             `bson:"avatar" json:"avatar"`
-	Role        string             `bson:"role" json:"role"` // "tenant", "landlord", "admin"
-	IsVerified  bool               `bson:"is_verified" json:"is_verified"`
-	Verified    bool               `bson:"verified" json:"verified"`
-	DateOfBirth *time.Time         `bson:"date_of_birth" json:"date_of_birth,omitempty"`
-	Gender      string             `bson:"gender" json:"gender,omitempty"` // "male", "female", "other"
-	Occupation  string             `bson:"occupation" json:"occupation,omitempty"`
-	Bio         string             `bson:"bio" json:"bio,omitempty"`
-	Languages   []string           `bson:"languages" json:"languages,omitempty"`
-	Preferences UserPreferences    `bson:"preferences" json:"preferences"`
-	Address     Address            `bson:"address" json:"address,omitempty"`
-	SocialLinks SocialLinks        `bson:"social_links" json:"social_links,omitempty"`
-	Rating      UserRating         `bson:"rating" json:"rating"`
-	IsActive    bool               `bson:"is_active" json:"is_active"`
-	LastLoginAt *time.Time         `bson:"last_login_at" json:"last_login_at,omitempty"`
-	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	ID                primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Email             string             `bson:"email" json:"email" binding:"required,email"`
+	Password          string             `bson:"password" json:"-"`
+	FullName          string             `bson:"full_name" json:"full_name" binding:"required"`
+	FirstName         string             `bson:"first_name" json:"first_name"`
+	LastName          string             `bson:"last_name" json:"last_name"`
+	Phone             string             `bson:"phone" json:"phone"`
+	Avatar            string             `bson:"avatar" json:"avatar"`
+	Role              string             `bson:"role" json:"role"` // "tenant", "landlord", "admin"
+	IsVerified        bool               `bson:"is_verified" json:"is_verified"`
+	Verified          bool               `bson:"verified" json:"verified"`
+	DateOfBirth       *time.Time         `bson:"date_of_birth" json:"date_of_birth,omitempty"`
+	Gender            string             `bson:"gender" json:"gender,omitempty"` // "male", "female", "other"
+	Occupation        string             `bson:"occupation" json:"occupation,omitempty"`
+	Bio               string             `bson:"bio" json:"bio,omitempty"`
+	Languages         []string           `bson:"languages" json:"languages,omitempty"`
+	Preferences       UserPreferences    `bson:"preferences" json:"preferences"`
+	Address           Address            `bson:"address" json:"address,omitempty"`
+	SocialLinks       SocialLinks        `bson:"social_links" json:"social_links,omitempty"`
+	Rating            UserRating         `bson:"rating" json:"rating"`
+	IsActive          bool               `bson:"is_active" json:"is_active"`
+	LastLoginAt       *time.Time         `bson:"last_login_at" json:"last_login_at,omitempty"`
+	DeactivatedAt     *time.Time         `bson:"deactivated_at,omitempty" json:"deactivated_at,omitempty"` // set while an admin has deactivated the account
+	PasswordChangedAt *time.Time         `bson:"password_changed_at,omitempty" json:"-"`
+	PasswordUnset     bool               `bson:"password_unset,omitempty" json:"-"` // the stored password is random and was never known to the user
+	TwoFactor         TwoFactorSettings  `bson:"two_factor" json:"two_factor"`
+	DeletedAt         *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	CreatedAt         time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt         time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// TwoFactorSettings holds a user's TOTP enrolment. Secret is written when
+// setup starts and Enabled flips once the first code has been confirmed.
+type TwoFactorSettings struct {
+	Enabled       bool       `bson:"enabled" json:"enabled"`
+	Secret        string     `bson:"secret,omitempty" json:"-"`
+	RecoveryCodes []string   `bson:"recovery_codes,omitempty" json:"-"` // SHA-256 hashes
+	LastUsedStep  uint64     `bson:"last_used_step,omitempty" json:"-"`
+	EnabledAt     *time.Time `bson:"enabled_at,omitempty" json:"enabled_at,omitempty"`
 }
 
 type UserPreferences struct {
@@ -94,7 +109,7 @@ type Property struct {
 	Price             float64            `bson:"price" json:"price" binding:"required"`
 	Currency          string             `bson:"currency" json:"currency"`
 	Address           Address            `bson:"address" json:"address"`
-	Location          GeoLocation        `bson:"location" json:"location"`
+	Location          *GeoLocation       `bson:"location,omitempty" json:"location,omitempty"`
 	Bedrooms          int                `bson:"bedrooms" json:"bedrooms"`
 	Bathrooms         int                `bson:"bathrooms" json:"bathrooms"`
 	Area              int                `bson:"area" json:"area"`
@@ -106,7 +121,7 @@ type Property struct {
 	Videos            []string           `bson:"videos" json:"videos,omitempty"`
 	VirtualTour       string             `bson:"virtual_tour" json:"virtual_tour,omitempty"`
 	FloorPlan         string             `bson:"floor_plan" json:"floor_plan,omitempty"`
-	Available         bool               `bson:"available" json:"available"`
+	Available         bool               `bson:"available" json:"available"` // true while the listing is published
 	AvailableFrom     time.Time          `bson:"available_from" json:"available_from"`
 	LeaseTerms        []string           `bson:"lease_terms" json:"lease_terms"`
 	PetsAllowed       bool               `bson:"pets_allowed" json:"pets_allowed"`
@@ -120,12 +135,44 @@ type Property struct {
 	ViewCount         int                `bson:"view_count" json:"view_count"`
 	FavoriteCount     int                `bson:"favorite_count" json:"favorite_count"`
 	Rating            PropertyRating     `bson:"rating" json:"rating"`
-	Status            string             `bson:"status" json:"status"` // "draft", "published", "rented", "maintenance"
+	Status            string             `bson:"status" json:"status"` // "draft", "pending_review", "published", "paused", "archived"
+	StatusHistory     []StatusChange     `bson:"status_history" json:"status_history,omitempty"`
+	PublishedAt       *time.Time         `bson:"published_at,omitempty" json:"published_at,omitempty"`
+	FirstPublishedAt  *time.Time         `bson:"first_published_at,omitempty" json:"first_published_at,omitempty"`
 	Featured          bool               `bson:"featured" json:"featured"`
 	Priority          int                `bson:"priority" json:"priority"`
 	Tags              []string           `bson:"tags" json:"tags,omitempty"`
 	CreatedAt         time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt         time.Time          `bson:"updated_at" json:"updated_at"`
+
+	// SearchTerms holds CJK n-grams of the listing text for the text index.
+	SearchTerms []string `bson:"search_terms" json:"-"`
+
+	// Distance is the distance in kilometres from the near= point. It is only
+	// set on results of a proximity search and is never stored.
+	Distance *float64 `bson:"distance,omitempty" json:"distance_km,omitempty"`
+	// Score and Highlights are only set on results of a q= text search.
+	Score      *float64          `bson:"score,omitempty" json:"score,omitempty"`
+	Highlights map[string]string `bson:"-" json:"highlights,omitempty"`
+	// Favorited is set on responses to say whether the current user saved
+	// the listing.
+	Favorited *bool `bson:"-" json:"favorited,omitempty"`
+}
+
+// StatusChange records a change of a listing's status. The first entry of a
+// listing has an empty From.
+type StatusChange struct {
+	From      string             `bson:"from" json:"from"`
+	To        string             `bson:"to" json:"to"`
+	ChangedBy primitive.ObjectID `bson:"changed_by" json:"changed_by"`
+	Reason    string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	ChangedAt time.Time          `bson:"changed_at" json:"changed_at"`
+}
+
+// UpdatePropertyStatusRequest moves a listing to another state.
+type UpdatePropertyStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+	Reason string `json:"reason"`
 }
 
 type PropertyFeatures struct {
@@ -146,12 +193,38 @@ type PropertyFeatures struct {
 	LaundryRoom     bool `bson:"laundry_room" json:"laundry_room"`
 }
 
+// PropertyImage is a listing photo. Uploaded images carry the storage Key
+// they were saved under and resized Variants; ThumbnailURL is the small JPEG
+// variant for list views. Images added by URL only have a URL.
 type PropertyImage struct {
-	URL        string    `bson:"url" json:"url"`
-	Caption    string    `bson:"caption" json:"caption,omitempty"`
-	IsPrimary  bool      `bson:"is_primary" json:"is_primary"`
-	Order      int       `bson:"order" json:"order"`
-	UploadedAt time.Time `bson:"uploaded_at" json:"uploaded_at"`
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	URL          string             `bson:"url" json:"url"`
+	Key          string             `bson:"key,omitempty" json:"-"`
+	ContentType  string             `bson:"content_type,omitempty" json:"content_type,omitempty"`
+	Size         int64              `bson:"size,omitempty" json:"size,omitempty"`
+	Width        int                `bson:"width,omitempty" json:"width,omitempty"`
+	Height       int                `bson:"height,omitempty" json:"height,omitempty"`
+	ThumbnailURL string             `bson:"thumbnail_url,omitempty" json:"thumbnail_url,omitempty"`
+	Variants     []ImageVariant     `bson:"variants,omitempty" json:"variants,omitempty"`
+	Caption      string             `bson:"caption" json:"caption,omitempty"`
+	IsPrimary    bool               `bson:"is_primary" json:"is_primary"`
+	Order        int                `bson:"order" json:"order"`
+	UploadedAt   time.Time          `bson:"uploaded_at" json:"uploaded_at"`
+}
+
+// ImageVariant is a resized copy of an uploaded image
+type ImageVariant struct {
+	Name   string `bson:"name" json:"name"`     // small, medium, large
+	Format string `bson:"format" json:"format"` // jpeg, webp
+	Width  int    `bson:"width" json:"width"`
+	Height int    `bson:"height" json:"height"`
+	Size   int64  `bson:"size" json:"size"`
+	URL    string `bson:"url" json:"url"`
+	Key    string `bson:"key,omitempty" json:"-"`
+}
+
+type ReorderImagesRequest struct {
+	ImageIDs []string `json:"image_ids" binding:"required,min=1"`
 }
 
 type LeaseTerms struct {
@@ -265,7 +338,7 @@ type RegisterRequest struct {
 	Password string `json:"password" binding:"required,min=6"`
 	Name     string `json:"name" binding:"required"`
 	Phone    string `json:"phone"`
-	Role     string `json:"role" binding:"required"`
+	Role     string `json:"role" binding:"required,oneof=tenant landlord"`
 }
 
 type TokenResponse struct {
@@ -274,6 +347,289 @@ type TokenResponse struct {
 	ExpiresIn    int64  `json:"expires_in"`
 }
 
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// RefreshToken is the server-side record of an issued refresh token. Only the
+// SHA-256 hash of the token is stored. Every rotation issues a new token in the
+// same family; presenting an already used token revokes the whole family.
+type RefreshToken struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	FamilyID  primitive.ObjectID `bson:"family_id" json:"family_id"`
+	TokenHash string             `bson:"token_hash" json:"-"`
+	ExpiresAt time.Time          `bson:"expires_at" json:"expires_at"`
+	UsedAt    *time.Time         `bson:"used_at" json:"used_at,omitempty"`
+	RevokedAt *time.Time         `bson:"revoked_at" json:"revoked_at,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// Session is one login on one device. Its ID is the refresh token family ID
+// carried as the sid claim of access tokens.
+type Session struct {
+	ID          primitive.ObjectID `bson:"_id" json:"id"`
+	UserID      primitive.ObjectID `bson:"user_id" json:"-"`
+	Device      string             `bson:"device" json:"device"` // e.g. "Chrome on macOS"
+	DeviceType  string             `bson:"device_type" json:"device_type"`
+	UserAgent   string             `bson:"user_agent" json:"user_agent"`
+	IP          string             `bson:"ip" json:"ip"`
+	LoginMethod string             `bson:"login_method" json:"login_method"` // "password", "oidc:<provider>"
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	LastSeenAt  time.Time          `bson:"last_seen_at" json:"last_seen_at"`
+	ExpiresAt   time.Time          `bson:"expires_at" json:"expires_at"`
+	RevokedAt   *time.Time         `bson:"revoked_at,omitempty" json:"-"`
+	Current     bool               `bson:"-" json:"current"`
+}
+
+// SessionClient describes the client a session is started from.
+type SessionClient struct {
+	UserAgent   string
+	IP          string
+	LoginMethod string
+}
+
+// RevokedToken is a denylist entry for an access token that was revoked
+// before it expired. Entries are removed by a TTL index once the token
+// would have expired anyway.
+type RevokedToken struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	JTI       string             `bson:"jti" json:"jti"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	ExpiresAt time.Time          `bson:"expires_at" json:"expires_at"`
+	RevokedAt time.Time          `bson:"revoked_at" json:"revoked_at"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type TwoFactorDisableRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"` // TOTP code or recovery code
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// Admin user management requests
+type UpdateUserStatusRequest struct {
+	IsActive *bool `json:"is_active" binding:"required"`
+}
+
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=tenant landlord admin"`
+}
+
+// DeleteAccountRequest confirms an account deletion with either the current
+// password or a token from POST /users/me/delete-confirmation.
+type DeleteAccountRequest struct {
+	Password          string `json:"password"`
+	ConfirmationToken string `json:"confirmation_token"`
+	Code              string `json:"code"` // required when two-factor authentication is enabled
+}
+
+// Partial update requests. Only the fields listed here can be changed through
+// the PATCH endpoints; nil fields are left untouched. See pkg/patch.
+type UpdateProfileRequest struct {
+	FullName    *string                `bson:"full_name" json:"full_name"`
+	FirstName   *string                `bson:"first_name" json:"first_name"`
+	LastName    *string                `bson:"last_name" json:"last_name"`
+	Phone       *string                `bson:"phone" json:"phone"`
+	Avatar      *string                `bson:"avatar" json:"avatar"`
+	DateOfBirth *time.Time             `bson:"date_of_birth" json:"date_of_birth"`
+	Gender      *string                `bson:"gender" json:"gender"`
+	Occupation  *string                `bson:"occupation" json:"occupation"`
+	Bio         *string                `bson:"bio" json:"bio"`
+	Languages   []string               `bson:"languages" json:"languages"`
+	Preferences *UserPreferencesUpdate `bson:"preferences" json:"preferences"`
+	Address     *AddressUpdate         `bson:"address" json:"address"`
+	SocialLinks *SocialLinksUpdate     `bson:"social_links" json:"social_links"`
+}
+
+type UserPreferencesUpdate struct {
+	Currency      *string           `bson:"currency" json:"currency"`
+	Language      *string           `bson:"language" json:"language"`
+	Timezone      *string           `bson:"timezone" json:"timezone"`
+	NotifyByEmail *bool             `bson:"notify_by_email" json:"notify_by_email"`
+	NotifyBySMS   *bool             `bson:"notify_by_sms" json:"notify_by_sms"`
+	NotifyByPush  *bool             `bson:"notify_by_push" json:"notify_by_push"`
+	SearchRadius  *int              `bson:"search_radius" json:"search_radius"`
+	PropertyTypes []string          `bson:"property_types" json:"property_types"`
+	PriceRange    *PriceRangeUpdate `bson:"price_range" json:"price_range"`
+}
+
+type PriceRangeUpdate struct {
+	Min      *float64 `bson:"min" json:"min"`
+	Max      *float64 `bson:"max" json:"max"`
+	Currency *string  `bson:"currency" json:"currency"`
+}
+
+type AddressUpdate struct {
+	Street     *string  `bson:"street" json:"street"`
+	City       *string  `bson:"city" json:"city"`
+	State      *string  `bson:"state" json:"state"`
+	Country    *string  `bson:"country" json:"country"`
+	ZipCode    *string  `bson:"zip_code" json:"zip_code"`
+	PostalCode *string  `bson:"postal_code" json:"postal_code"`
+	Latitude   *float64 `bson:"latitude" json:"latitude"`
+	Longitude  *float64 `bson:"longitude" json:"longitude"`
+}
+
+type SocialLinksUpdate struct {
+	Facebook  *string `bson:"facebook" json:"facebook"`
+	Twitter   *string `bson:"twitter" json:"twitter"`
+	LinkedIn  *string `bson:"linkedin" json:"linkedin"`
+	Instagram *string `bson:"instagram" json:"instagram"`
+}
+
+type UpdatePropertyRequest struct {
+	Title             *string                 `bson:"title" json:"title"`
+	Description       *string                 `bson:"description" json:"description"`
+	Type              *string                 `bson:"type" json:"type"`
+	Price             *float64                `bson:"price" json:"price"`
+	Currency          *string                 `bson:"currency" json:"currency"`
+	Address           *AddressUpdate          `bson:"address" json:"address"`
+	Location          *GeoLocationUpdate      `bson:"location" json:"location"`
+	Bedrooms          *int                    `bson:"bedrooms" json:"bedrooms"`
+	Bathrooms         *int                    `bson:"bathrooms" json:"bathrooms"`
+	Area              *int                    `bson:"area" json:"area"`
+	SquareFeet        *int                    `bson:"square_feet" json:"square_feet"`
+	Features          *PropertyFeaturesUpdate `bson:"features" json:"features"`
+	Amenities         []string                `bson:"amenities" json:"amenities"`
+	Images            []string                `bson:"images" json:"images"`
+	Videos            []string                `bson:"videos" json:"videos"`
+	VirtualTour       *string                 `bson:"virtual_tour" json:"virtual_tour"`
+	FloorPlan         *string                 `bson:"floor_plan" json:"floor_plan"`
+	AvailableFrom     *time.Time              `bson:"available_from" json:"available_from"`
+	LeaseTerms        []string                `bson:"lease_terms" json:"lease_terms"`
+	PetsAllowed       *bool                   `bson:"pets_allowed" json:"pets_allowed"`
+	SmokingAllowed    *bool                   `bson:"smoking_allowed" json:"smoking_allowed"`
+	UtilitiesIncluded []string                `bson:"utilities_included" json:"utilities_included"`
+	Rules             *PropertyRulesUpdate    `bson:"rules" json:"rules"`
+	Safety            *PropertySafetyUpdate   `bson:"safety" json:"safety"`
+	Parking           *PropertyParkingUpdate  `bson:"parking" json:"parking"`
+	Tags              []string                `bson:"tags" json:"tags"`
+}
+
+type GeoLocationUpdate struct {
+	Type        *string   `bson:"type" json:"type"`
+	Coordinates []float64 `bson:"coordinates" json:"coordinates"`
+}
+
+type PropertyFeaturesUpdate struct {
+	Furnished       *bool `bson:"furnished" json:"furnished"`
+	PetsAllowed     *bool `bson:"pets_allowed" json:"pets_allowed"`
+	SmokingAllowed  *bool `bson:"smoking_allowed" json:"smoking_allowed"`
+	Balcony         *bool `bson:"balcony" json:"balcony"`
+	Garden          *bool `bson:"garden" json:"garden"`
+	Terrace         *bool `bson:"terrace" json:"terrace"`
+	Basement        *bool `bson:"basement" json:"basement"`
+	Attic           *bool `bson:"attic" json:"attic"`
+	Fireplace       *bool `bson:"fireplace" json:"fireplace"`
+	Pool            *bool `bson:"pool" json:"pool"`
+	Gym             *bool `bson:"gym" json:"gym"`
+	Elevator        *bool `bson:"elevator" json:"elevator"`
+	AccessibleEntry *bool `bson:"accessible_entry" json:"accessible_entry"`
+	StorageUnit     *bool `bson:"storage_unit" json:"storage_unit"`
+	LaundryRoom     *bool `bson:"laundry_room" json:"laundry_room"`
+}
+
+type PropertyRulesUpdate struct {
+	MaxOccupants         *int              `bson:"max_occupants" json:"max_occupants"`
+	MinAge               *int              `bson:"min_age" json:"min_age"`
+	GenderPreference     *string           `bson:"gender_preference" json:"gender_preference"`
+	ProfessionPreference *string           `bson:"profession_preference" json:"profession_preference"`
+	QuietHours           *QuietHoursUpdate `bson:"quiet_hours" json:"quiet_hours"`
+	VisitorPolicy        *string           `bson:"visitor_policy" json:"visitor_policy"`
+	PartyPolicy          *string           `bson:"party_policy" json:"party_policy"`
+	CleaningSchedule     *string           `bson:"cleaning_schedule" json:"cleaning_schedule"`
+}
+
+type QuietHoursUpdate struct {
+	Start *string `bson:"start" json:"start"`
+	End   *string `bson:"end" json:"end"`
+}
+
+type PropertySafetyUpdate struct {
+	SmokeDetector    *bool `bson:"smoke_detector" json:"smoke_detector"`
+	CarbonMonoxide   *bool `bson:"carbon_monoxide" json:"carbon_monoxide"`
+	FireExtinguisher *bool `bson:"fire_extinguisher" json:"fire_extinguisher"`
+	SecuritySystem   *bool `bson:"security_system" json:"security_system"`
+	SecurityGuard    *bool `bson:"security_guard" json:"security_guard"`
+	CCTV             *bool `bson:"cctv" json:"cctv"`
+	GatedCommunity   *bool `bson:"gated_community" json:"gated_community"`
+	WellLit          *bool `bson:"well_lit" json:"well_lit"`
+}
+
+type PropertyParkingUpdate struct {
+	Available   *bool    `bson:"available" json:"available"`
+	Type        *string  `bson:"type" json:"type"`
+	Spaces      *int     `bson:"spaces" json:"spaces"`
+	Cost        *float64 `bson:"cost" json:"cost"`
+	Description *string  `bson:"description" json:"description"`
+}
+
+// CreateBookingRequest holds the fields a tenant supplies when requesting a
+// booking. Parties, status, amounts, payment and reviews are set by the server.
+type CreateBookingRequest struct {
+	PropertyID      primitive.ObjectID `json:"property_id" binding:"required"`
+	StartDate       time.Time          `json:"start_date" binding:"required"`
+	EndDate         time.Time          `json:"end_date" binding:"required"`
+	CheckInTime     string             `json:"check_in_time"`
+	CheckOutTime    string             `json:"check_out_time"`
+	Message         string             `json:"message"`
+	SpecialRequests []string           `json:"special_requests"`
+	GuestInfo       GuestInfo          `json:"guest_info"`
+}
+
+// UpdateBookingRequest holds the fields a tenant may change on their own
+// booking. Status, payment and amounts are managed by the server.
+type UpdateBookingRequest struct {
+	StartDate       *time.Time       `bson:"start_date" json:"start_date"`
+	EndDate         *time.Time       `bson:"end_date" json:"end_date"`
+	CheckInTime     *string          `bson:"check_in_time" json:"check_in_time"`
+	CheckOutTime    *string          `bson:"check_out_time" json:"check_out_time"`
+	Message         *string          `bson:"message" json:"message"`
+	SpecialRequests []string         `bson:"special_requests" json:"special_requests"`
+	GuestInfo       *GuestInfoUpdate `bson:"guest_info" json:"guest_info"`
+}
+
+type GuestInfoUpdate struct {
+	Adults   *int    `bson:"adults" json:"adults"`
+	Children *int    `bson:"children" json:"children"`
+	Infants  *int    `bson:"infants" json:"infants"`
+	Purpose  *string `bson:"purpose" json:"purpose"`
+}
+
+// UpdateBookingStatusRequest confirms or cancels a booking.
+type UpdateBookingStatusRequest struct {
+	Status string `json:"status" binding:"required"` // "confirmed", "cancelled"
+	Reason string `json:"reason"`
+}
+
+// PasswordResetToken is a single-use password reset token. Only the SHA-256
+// hash of the token is stored.
+type PasswordResetToken struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	TokenHash string             `bson:"token_hash" json:"-"`
+	ExpiresAt time.Time          `bson:"expires_at" json:"expires_at"`
+	UsedAt    *time.Time         `bson:"used_at" json:"used_at,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
 type Booking struct {
 	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	PropertyID       primitive.ObjectID `bson:"property_id" json:"property_id" binding:"required"`
@@ -396,6 +752,88 @@ type Damage struct {
 	Severity    string   `bson:"severity" json:"severity"` // "minor", "major", "severe"
 }
 
+// Availability calendar models
+
+// CalendarRange marks the nights from StartDate up to, but not including,
+// EndDate. Booked ranges are created when a booking is confirmed and carry
+// its BookingID; landlords manage the available and blocked ones. A price
+// override replaces the listing price for the nights it covers.
+type CalendarRange struct {
+	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	PropertyID   primitive.ObjectID  `bson:"property_id" json:"property_id"`
+	StartDate    time.Time           `bson:"start_date" json:"start_date"`
+	EndDate      time.Time           `bson:"end_date" json:"end_date"`
+	Status       string              `bson:"status" json:"status"` // "available", "blocked", "booked"
+	NightlyPrice *float64            `bson:"nightly_price,omitempty" json:"nightly_price,omitempty"`
+	MonthlyPrice *float64            `bson:"monthly_price,omitempty" json:"monthly_price,omitempty"`
+	Note         string              `bson:"note,omitempty" json:"note,omitempty"`
+	BookingID    *primitive.ObjectID `bson:"booking_id,omitempty" json:"booking_id,omitempty"`
+	CreatedAt    time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// PropertyCalendar is the calendar of a listing between From and To. Nights
+// not covered by a range are priced at the listing price.
+type PropertyCalendar struct {
+	PropertyID primitive.ObjectID `json:"property_id"`
+	Price      float64            `json:"price"`
+	Currency   string             `json:"currency"`
+	From       time.Time          `json:"from"`
+	To         time.Time          `json:"to"`
+	Ranges     []CalendarRange    `json:"ranges"`
+}
+
+// UpdateCalendarRequest replaces all available and blocked ranges of a
+// listing. Booked ranges are kept.
+type UpdateCalendarRequest struct {
+	Ranges []CalendarRangeInput `json:"ranges"`
+}
+
+type CalendarRangeInput struct {
+	StartDate    time.Time `json:"start_date" binding:"required"`
+	EndDate      time.Time `json:"end_date" binding:"required"`
+	Status       string    `json:"status" binding:"required"` // "available", "blocked"
+	NightlyPrice *float64  `json:"nightly_price"`
+	MonthlyPrice *float64  `json:"monthly_price"`
+	Note         string    `json:"note"`
+}
+
+// Listing analytics models
+
+// PropertyView records that a visitor viewed a listing on a day (UTC). There
+// is at most one per visitor, listing and day.
+type PropertyView struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PropertyID primitive.ObjectID `bson:"property_id" json:"property_id"`
+	VisitorID  primitive.ObjectID `bson:"visitor_id" json:"visitor_id"`
+	Day        time.Time          `bson:"day" json:"day"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// PropertyStats are the activity counts of a listing between From and To,
+// overall and per day.
+type PropertyStats struct {
+	PropertyID primitive.ObjectID `json:"property_id"`
+	From       time.Time          `json:"from"`
+	To         time.Time          `json:"to"`
+	Totals     StatsCounts        `json:"totals"`
+	Days       []DailyStats       `json:"days"`
+}
+
+type DailyStats struct {
+	Date string `json:"date"` // YYYY-MM-DD
+	StatsCounts
+}
+
+// StatsCounts counts unique daily views, new favorites and booking requests.
+// ConversionRate is booking requests per view.
+type StatsCounts struct {
+	Views           int64   `bson:"views" json:"views"`
+	Favorites       int64   `bson:"favorites" json:"favorites"`
+	BookingRequests int64   `bson:"booking_requests" json:"booking_requests"`
+	ConversionRate  float64 `bson:"-" json:"conversion_rate"`
+}
+
 // Message/Chat models
 type Message struct {
 	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
@@ -414,7 +852,7 @@ type Message struct {
 type Notification struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
-	Type      string             `bson:"type" json:"type"` // "booking", "message", "review", "payment", "system"
+	Type      string             `bson:"type" json:"type"` // "booking", "message", "review", "payment", "system", "search_alert"
 	Title     string             `bson:"title" json:"title"`
 	Content   string             `bson:"content" json:"content"`
 	Data      interface{}        `bson:"data" json:"data,omitempty"`
@@ -450,3 +888,170 @@ type Favorite struct {
 	PropertyID primitive.ObjectID `bson:"property_id" json:"property_id"`
 	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
 }
+
+type AddFavoriteRequest struct {
+	PropertyID string `json:"property_id" binding:"required"`
+}
+
+// Saved search models
+
+// SavedSearch is a GET /properties query saved under a name. Query holds the
+// search parameters without the paging ones. With Alerts on, listings
+// published after LastCheckedAt that match the query are sent to the user.
+type SavedSearch struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID        primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Name          string             `bson:"name" json:"name"`
+	Query         string             `bson:"query" json:"query"`
+	Alerts        bool               `bson:"alerts" json:"alerts"`
+	LastCheckedAt time.Time          `bson:"last_checked_at" json:"last_checked_at"`
+	LastMatchedAt *time.Time         `bson:"last_matched_at" json:"last_matched_at,omitempty"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// CreateSavedSearchRequest saves a search. Without a query the search is
+// built from the user's preferred property types and price range.
+type CreateSavedSearchRequest struct {
+	Name   string `json:"name" binding:"required,max=100"`
+	Query  string `json:"query" binding:"max=2000"`
+	Alerts *bool  `json:"alerts"`
+}
+
+type UpdateSavedSearchRequest struct {
+	Name   *string `json:"name" binding:"omitempty,min=1,max=100"`
+	Alerts *bool   `json:"alerts"`
+}
+
+// API key models
+type APIKey struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Name       string             `bson:"name" json:"name"`
+	Prefix     string             `bson:"prefix" json:"prefix"` // first characters of the key, for display
+	KeyHash    string             `bson:"key_hash" json:"-"`
+	Scopes     []string           `bson:"scopes" json:"scopes"` // e.g. "properties:read", "bookings:write"
+	LastUsedAt *time.Time         `bson:"last_used_at" json:"last_used_at,omitempty"`
+	ExpiresAt  *time.Time         `bson:"expires_at" json:"expires_at,omitempty"`
+	RevokedAt  *time.Time         `bson:"revoked_at" json:"revoked_at,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1,max=3650"`
+}
+
+// Login throttling models
+type LoginAttempt struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Key       string             `bson:"key" json:"key"` // "email:<address>" or "ip:<address>"
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	ExpiresAt time.Time          `bson:"expires_at" json:"expires_at"`
+}
+
+type LoginLockout struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Key         string             `bson:"key" json:"key"`
+	LockedUntil time.Time          `bson:"locked_until" json:"locked_until"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// Audit models
+type AuditLog struct {
+	ID         primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	Action     string                 `bson:"action" json:"action"` // e.g. "auth.lockout", "admin.user.deactivate"
+	ActorID    *primitive.ObjectID    `bson:"actor_id,omitempty" json:"actor_id,omitempty"`
+	TargetType string                 `bson:"target_type,omitempty" json:"target_type,omitempty"` // "user", "property", "booking"
+	TargetID   string                 `bson:"target_id,omitempty" json:"target_id,omitempty"`
+	IP         string                 `bson:"ip,omitempty" json:"ip,omitempty"`
+	Metadata   map[string]interface{} `bson:"metadata,omitempty" json:"metadata,omitempty"`
+	CreatedAt  time.Time              `bson:"created_at" json:"created_at"`
+}
+
+// Account data export
+type AccountExport struct {
+	ExportedAt time.Time   `json:"exported_at"`
+	Profile    *User       `json:"profile"`
+	Properties []*Property `json:"properties"`
+	Bookings   []*Booking  `json:"bookings"`
+	Messages   []*Message  `json:"messages"`
+	Payments   []*Payment  `json:"payments"`
+}
+
+// Social login models
+
+// UserIdentity links a user to an account at an OpenID Connect provider.
+type UserIdentity struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Provider   string             `bson:"provider" json:"provider"`
+	Subject    string             `bson:"subject" json:"-"` // the provider's stable user id
+	Email      string             `bson:"email" json:"email"`
+	LastUsedAt *time.Time         `bson:"last_used_at" json:"last_used_at,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// OIDCAuthState is kept between redirecting the user to a provider and the
+// provider redirecting back. UserID is set when an existing user is linking
+// a provider rather than signing in.
+type OIDCAuthState struct {
+	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	StateHash    string              `bson:"state_hash" json:"-"`
+	Provider     string              `bson:"provider" json:"provider"`
+	CodeVerifier string              `bson:"code_verifier" json:"-"`
+	Nonce        string              `bson:"nonce" json:"-"`
+	UserID       *primitive.ObjectID `bson:"user_id,omitempty" json:"user_id,omitempty"`
+	ExpiresAt    time.Time           `bson:"expires_at" json:"expires_at"`
+	CreatedAt    time.Time           `bson:"created_at" json:"created_at"`
+}
+
+// Public profile models
+
+// PublicProfile is what other users can see about a user. It deliberately
+// leaves out contact details and the date of birth.
+type PublicProfile struct {
+	ID             primitive.ObjectID `json:"id"`
+	FullName       string             `json:"full_name"`
+	Avatar         string             `json:"avatar"`
+	Role           string             `json:"role"`
+	IsVerified     bool               `json:"is_verified"`
+	Bio            string             `json:"bio,omitempty"`
+	Occupation     string             `json:"occupation,omitempty"`
+	Languages      []string           `json:"languages,omitempty"`
+	SocialLinks    SocialLinks        `json:"social_links"`
+	City           string             `json:"city,omitempty"`
+	Country        string             `json:"country,omitempty"`
+	Rating         UserRating         `json:"rating"`
+	RecentReviews  []PublicReview     `json:"recent_reviews"`
+	ActiveListings int64              `json:"active_listings"`
+	ResponseRate   *float64           `json:"response_rate"` // share of conversations answered, nil without any
+	MemberSince    time.Time          `json:"member_since"`
+}
+
+type PublicReview struct {
+	ID        primitive.ObjectID `bson:"_id" json:"id"`
+	Type      string             `bson:"type" json:"type"`
+	Rating    ReviewRating       `bson:"rating" json:"rating"`
+	Comment   string             `bson:"comment" json:"comment"`
+	Response  *ReviewResponse    `bson:"response" json:"response,omitempty"`
+	Reviewer  PublicReviewer     `bson:"reviewer" json:"reviewer"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+type PublicReviewer struct {
+	ID       primitive.ObjectID `bson:"_id" json:"id"`
+	FullName string             `bson:"full_name" json:"full_name"`
+	Avatar   string             `bson:"avatar" json:"avatar"`
+}
+
+// List response models
+
+// ListResponse is the envelope returned by every list endpoint. NextCursor is
+// null on the last page; pass it back as ?cursor= to fetch the next one.
+type ListResponse struct {
+	Data       interface{} `json:"data"`
+	NextCursor *string     `json:"next_cursor"`
+	Total      int64       `json:"total"`
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"rent-help-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var ErrAccountHasActiveBookings = errors.New("account has bookings that are still in progress")
+
+// Booking statuses that still need both parties to be reachable.
+var activeBookingStatuses = []string{"pending", "confirmed", "checked_in"}
+
+// AccountService exports everything stored about a user and erases their
+// personal data on request.
+type AccountService struct {
+	messages            *mongo.Collection
+	payments            *mongo.Collection
+	userService         *UserService
+	propertyService     *PropertyService
+	bookingService      *BookingService
+	favoriteService     *FavoriteService
+	savedSearchService  *SavedSearchService
+	notificationService *NotificationService
+	tokenService        *TokenService
+	apiKeyService       *APIKeyService
+	oidcService         *OIDCService
+	auditService        *AuditService
+}
+
+func NewAccountService(db *mongo.Database, userService *UserService, propertyService *PropertyService, bookingService *BookingService, favoriteService *FavoriteService, savedSearchService *SavedSearchService, notificationService *NotificationService, tokenService *TokenService, apiKeyService *APIKeyService, oidcService *OIDCService, auditService *AuditService) *AccountService {
+	return &AccountService{
+		messages:            db.Collection("messages"),
+		payments:            db.Collection("payments"),
+		userService:         userService,
+		propertyService:     propertyService,
+		bookingService:      bookingService,
+		favoriteService:     favoriteService,
+		savedSearchService:  savedSearchService,
+		notificationService: notificationService,
+		tokenService:        tokenService,
+		apiKeyService:       apiKeyService,
+		oidcService:         oidcService,
+		auditService:        auditService,
+	}
+}
+
+// Export collects the user's profile together with the properties, bookings,
+// messages and payments they are a party to.
+func (s *AccountService) Export(userID primitive.ObjectID) (*models.AccountExport, error) {
+	user, err := s.userService.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	properties, err := s.propertyService.GetProperties(bson.M{"owner_id": userID}, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	bookings, err := s.bookingService.GetBookings(bson.M{"$or": bson.A{
+		bson.M{"tenant_id": userID},
+		bson.M{"landlord_id": userID},
+	}}, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []*models.Message
+	if err := findAll(s.messages, bson.M{"$or": bson.A{
+		bson.M{"sender_id": userID},
+		bson.M{"receiver_id": userID},
+	}}, &messages); err != nil {
+		return nil, err
+	}
+
+	var payments []*models.Payment
+	if err := findAll(s.payments, bson.M{"$or": bson.A{
+		bson.M{"payer_id": userID},
+		bson.M{"receiver_id": userID},
+	}}, &payments); err != nil {
+		return nil, err
+	}
+
+	s.audit("account.export", userID)
+
+	return &models.AccountExport{
+		ExportedAt: time.Now(),
+		Profile:    user,
+		Properties: properties,
+		Bookings:   bookings,
+		Messages:   messages,
+		Payments:   payments,
+	}, nil
+}
+
+// Delete anonymises the user's personal data and signs them out everywhere.
+// The user document itself is kept so that bookings, payments and messages
+// still resolve for the other party; only identifying fields are cleared.
+// Deletion is refused while a booking is still in progress.
+func (s *AccountService) Delete(user *models.User) error {
+	active, err := s.bookingService.CountBookings(bson.M{
+		"status": bson.M{"$in": activeBookingStatuses},
+		"$or": bson.A{
+			bson.M{"tenant_id": user.ID},
+			bson.M{"landlord_id": user.ID},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if active > 0 {
+		return ErrAccountHasActiveBookings
+	}
+
+	// A random password that is never revealed locks the account for good.
+	if err := s.userService.LockPassword(user.ID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := s.userService.UpdateUser(user.ID, bson.M{
+		"email":         fmt.Sprintf("deleted-%s@deleted.invalid", user.ID.Hex()),
+		"full_name":     "Deleted user",
+		"first_name":    "",
+		"last_name":     "",
+		"phone":         "",
+		"avatar":        "",
+		"date_of_birth": nil,
+		"gender":        "",
+		"occupation":    "",
+		"bio":           "",
+		"languages":     []string{},
+		"preferences":   models.UserPreferences{},
+		"address":       models.Address{},
+		"social_links":  models.SocialLinks{},
+		"two_factor":    models.TwoFactorSettings{},
+		"is_active":     false,
+		"deleted_at":    now,
+	}); err != nil {
+		return err
+	}
+
+	// Listings of a deleted landlord can no longer be found or booked.
+	if err := s.propertyService.ArchivePropertiesByOwner(user.ID, user.ID, "Account deleted"); err != nil {
+		return err
+	}
+	if err := s.favoriteService.DeleteForUser(user.ID); err != nil {
+		return err
+	}
+	if err := s.savedSearchService.DeleteForUser(user.ID); err != nil {
+		return err
+	}
+	if err := s.notificationService.DeleteForUser(user.ID); err != nil {
+		return err
+	}
+
+	if err := s.tokenService.RevokeAllForUser(user.ID); err != nil {
+		return err
+	}
+	if err := s.apiKeyService.RevokeAllForUser(user.ID); err != nil {
+		return err
+	}
+	if err := s.oidcService.DeleteIdentities(user.ID); err != nil {
+		return err
+	}
+
+	s.audit("account.delete", user.ID)
+	return nil
+}
+
+func (s *AccountService) audit(action string, userID primitive.ObjectID) {
+	entry := &models.AuditLog{
+		Action:     action,
+		ActorID:    &userID,
+		TargetType: "user",
+		TargetID:   userID.Hex(),
+	}
+	if err := s.auditService.Record(entry); err != nil {
+		log.Printf("Failed to audit %s for user %s: %v", action, userID.Hex(), err)
+	}
+}
+
+// findAll decodes every document matching filter into results, which must be
+// a pointer to a slice.
+func findAll(collection *mongo.Collection, filter bson.M, results interface{}) error {
+	cursor, err := collection.Find(context.Background(), filter)
+	if err != nil {
+		return err
+	}
+	return cursor.All(context.Background(), results)
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"rent-help-backend/internal/models"
+	"rent-help-backend/pkg/calendar"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// viewRetention is how long view records are kept for the statistics.
+const viewRetention = 400 * 24 * time.Hour
+
+// AnalyticsService tracks listing views and reports listing statistics.
+type AnalyticsService struct {
+	views      *mongo.Collection
+	favorites  *mongo.Collection
+	bookings   *mongo.Collection
+	properties *mongo.Collection
+}
+
+func NewAnalyticsService(db *mongo.Database) *AnalyticsService {
+	return &AnalyticsService{
+		views:      db.Collection("property_views"),
+		favorites:  db.Collection("favorites"),
+		bookings:   db.Collection("bookings"),
+		properties: db.Collection("properties"),
+	}
+}
+
+func (s *AnalyticsService) EnsureIndexes() error {
+	_, err := s.views.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "property_id", Value: 1}, {Key: "day", Value: 1}, {Key: "visitor_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(viewRetention.Seconds())),
+		},
+	})
+	return err
+}
+
+// RecordView counts a view of a listing and increments its ViewCount, unless
+// the visitor owns the listing or has viewed it already today (UTC). It
+// reports whether the view was counted.
+func (s *AnalyticsService) RecordView(property *models.Property, visitorID primitive.ObjectID) (bool, error) {
+	if property.OwnerID == visitorID {
+		return false, nil
+	}
+
+	now := time.Now()
+	view := models.PropertyView{
+		PropertyID: property.ID,
+		VisitorID:  visitorID,
+		Day:        calendar.Date(now.UTC()),
+		CreatedAt:  now,
+	}
+	if _, err := s.views.InsertOne(context.Background(), view); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	_, err := s.properties.UpdateOne(
+		context.Background(),
+		bson.M{"_id": property.ID},
+		bson.M{"$inc": bson.M{"view_count": 1}},
+	)
+	return err == nil, err
+}
+
+// PropertyStats counts the views, new favorites and booking requests of a
+// listing for each day (UTC) of the range. Days without activity are
+// included with zero counts.
+func (s *AnalyticsService) PropertyStats(propertyID primitive.ObjectID, days calendar.Range) (*models.PropertyStats, error) {
+	// One pipeline over the views that pulls in the favorites and bookings
+	// with $unionWith, so that all three are bucketed the same way.
+	activity := func(field, counter string) mongo.Pipeline {
+		counts := bson.M{"views": 0, "favorites": 0, "booking_requests": 0}
+		counts[counter] = 1
+		project := bson.M{"_id": 0, "day": utcDay("$" + field)}
+		for name, value := range counts {
+			project[name] = bson.M{"$literal": value}
+		}
+		return mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{
+				"property_id": propertyID,
+				field:         bson.M{"$gte": days.Start, "$lt": days.End},
+			}}},
+			{{Key: "$project", Value: project}},
+		}
+	}
+
+	pipeline := activity("day", "views")
+	pipeline = append(pipeline,
+		bson.D{{Key: "$unionWith", Value: bson.M{"coll": s.favorites.Name(), "pipeline": activity("created_at", "favorites")}}},
+		bson.D{{Key: "$unionWith", Value: bson.M{"coll": s.bookings.Name(), "pipeline": activity("created_at", "booking_requests")}}},
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id":              "$day",
+			"views":            bson.M{"$sum": "$views"},
+			"favorites":        bson.M{"$sum": "$favorites"},
+			"booking_requests": bson.M{"$sum": "$booking_requests"},
+		}}},
+	)
+
+	var rows []struct {
+		Day                time.Time `bson:"_id"`
+		models.StatsCounts `bson:",inline"`
+	}
+	cursor, err := s.views.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(context.Background(), &rows); err != nil {
+		return nil, err
+	}
+
+	byDay := make(map[string]models.StatsCounts, len(rows))
+	for _, row := range rows {
+		byDay[row.Day.UTC().Format(calendar.DateLayout)] = row.StatsCounts
+	}
+
+	stats := &models.PropertyStats{
+		PropertyID: propertyID,
+		From:       days.Start,
+		To:         days.End,
+		Days:       make([]models.DailyStats, 0, days.Nights()),
+	}
+	for _, day := range days.Days() {
+		date := day.Format(calendar.DateLayout)
+		counts := byDay[date]
+		counts.ConversionRate = conversionRate(counts.BookingRequests, counts.Views)
+		stats.Days = append(stats.Days, models.DailyStats{Date: date, StatsCounts: counts})
+
+		stats.Totals.Views += counts.Views
+		stats.Totals.Favorites += counts.Favorites
+		stats.Totals.BookingRequests += counts.BookingRequests
+	}
+	stats.Totals.ConversionRate = conversionRate(stats.Totals.BookingRequests, stats.Totals.Views)
+	return stats, nil
+}
+
+// utcDay truncates a date expression to midnight UTC.
+func utcDay(date string) bson.M {
+	return bson.M{"$dateFromParts": bson.M{
+		"year":  bson.M{"$year": date},
+		"month": bson.M{"$month": date},
+		"day":   bson.M{"$dayOfMonth": date},
+	}}
+}
+
+// conversionRate returns booking requests per view, rounded to four decimal
+// places, or 0 without views.
+func conversionRate(requests, views int64) float64 {
+	if views == 0 {
+		return 0
+	}
+	return math.Round(float64(requests)/float64(views)*10000) / 10000
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"rent-help-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	ScopePropertiesRead  = "properties:read"
+	ScopePropertiesWrite = "properties:write"
+	ScopeBookingsRead    = "bookings:read"
+	ScopeBookingsWrite   = "bookings:write"
+
+	apiKeyPrefix        = "rh_"
+	apiKeyDisplayLength = 11
+)
+
+// APIKeyScopes lists the scopes that can be granted to an API key.
+var APIKeyScopes = []string{ScopePropertiesRead, ScopePropertiesWrite, ScopeBookingsRead, ScopeBookingsWrite}
+
+var (
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrInvalidAPIKey  = errors.New("invalid api key")
+)
+
+type APIKeyService struct {
+	collection *mongo.Collection
+}
+
+func NewAPIKeyService(db *mongo.Database) *APIKeyService {
+	return &APIKeyService{
+		collection: db.Collection("api_keys"),
+	}
+}
+
+func (s *APIKeyService) EnsureIndexes() error {
+	_, err := s.collection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "key_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	return err
+}
+
+// CreateKey stores a new key for the user and returns the record together
+// with the raw key. Only a hash is kept, so the raw key cannot be shown again.
+func (s *APIKeyService) CreateKey(userID primitive.ObjectID, name string, scopes []string, expiresAt *time.Time) (*models.APIKey, string, error) {
+	secret, err := generateRandomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	rawKey := apiKeyPrefix + secret
+
+	key := &models.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    rawKey[:apiKeyDisplayLength],
+		KeyHash:   hashToken(rawKey),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+
+	result, err := s.collection.InsertOne(context.Background(), key)
+	if err != nil {
+		return nil, "", err
+	}
+
+	key.ID = result.InsertedID.(primitive.ObjectID)
+	return key, rawKey, nil
+}
+
+func (s *APIKeyService) GetKeysByUser(userID primitive.ObjectID) ([]*models.APIKey, error) {
+	keys := []*models.APIKey{}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := s.collection.Find(context.Background(), bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(context.Background()) {
+		var key models.APIKey
+		if err := cursor.Decode(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, &key)
+	}
+
+	return keys, nil
+}
+
+// RevokeKey revokes one of the user's keys.
+func (s *APIKeyService) RevokeKey(userID, keyID primitive.ObjectID) error {
+	result, err := s.collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": keyID, "user_id": userID, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// RevokeAllForUser revokes every key the user holds.
+func (s *APIKeyService) RevokeAllForUser(userID primitive.ObjectID) error {
+	_, err := s.collection.UpdateMany(
+		context.Background(),
+		bson.M{"user_id": userID, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	return err
+}
+
+// Authenticate resolves a raw key to its record if it is neither revoked nor
+// expired, and records when it was last used.
+func (s *APIKeyService) Authenticate(rawKey string) (*models.APIKey, error) {
+	if !strings.HasPrefix(rawKey, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	var key models.APIKey
+	err := s.collection.FindOneAndUpdate(
+		context.Background(),
+		bson.M{
+			"key_hash":   hashToken(rawKey),
+			"revoked_at": nil,
+			"$or": bson.A{
+				bson.M{"expires_at": nil},
+				bson.M{"expires_at": bson.M{"$gt": now}},
+			},
+		},
+		bson.M{"$set": bson.M{"last_used_at": now}},
+	).Decode(&key)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// IsValidScope reports whether scope can be granted to an API key.
+func IsValidScope(scope string) bool {
+	for _, s := range APIKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
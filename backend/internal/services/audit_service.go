@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"rent-help-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type AuditService struct {
+	collection *mongo.Collection
+}
+
+func NewAuditService(db *mongo.Database) *AuditService {
+	return &AuditService{
+		collection: db.Collection("audit_logs"),
+	}
+}
+
+func (s *AuditService) EnsureIndexes() error {
+	_, err := s.collection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "action", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "actor_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "target_type", Value: 1}, {Key: "target_id", Value: 1}}},
+	})
+	return err
+}
+
+// Record appends an entry to the audit trail.
+func (s *AuditService) Record(entry *models.AuditLog) error {
+	entry.CreatedAt = time.Now()
+
+	_, err := s.collection.InsertOne(context.Background(), entry)
+	return err
+}
+
+// TargetIDs returns the distinct targets of the entries with the given
+// action.
+func (s *AuditService) TargetIDs(action string) ([]string, error) {
+	values, err := s.collection.Distinct(context.Background(), "target_id", bson.M{"action": action})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(values))
+	for _, value := range values {
+		if id, ok := value.(string); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
@@ -2,9 +2,12 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"rent-help-backend/internal/models"
+	"rent-help-backend/pkg/pagination"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -12,13 +15,26 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// BookingSorts are the sort names accepted by ListBookings.
+var BookingSorts = []string{"created_at", "start_date", "total_amount"}
+
+var bookingSortFields = map[string]sortField{
+	"created_at":   {path: "created_at", isTime: true},
+	"start_date":   {path: "start_date", isTime: true},
+	"total_amount": {path: "total_amount"},
+}
+
+var ErrBookingStatusChanged = errors.New("booking status was changed concurrently")
+
 type BookingService struct {
-	collection *mongo.Collection
+	collection      *mongo.Collection
+	calendarService *CalendarService
 }
 
-func NewBookingService(db *mongo.Database) *BookingService {
+func NewBookingService(db *mongo.Database, calendarService *CalendarService) *BookingService {
 	return &BookingService{
-		collection: db.Collection("bookings"),
+		collection:      db.Collection("bookings"),
+		calendarService: calendarService,
 	}
 }
 
@@ -57,6 +73,45 @@ func (s *BookingService) GetBookings(filter bson.M, limit, skip int64) ([]*model
 	return bookings, nil
 }
 
+// ListBookings returns one page of the bookings matching filter.
+func (s *BookingService) ListBookings(filter bson.M, page PageRequest) ([]*models.Booking, *string, error) {
+	field, ok := bookingSortFields[page.Sort.Field]
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported booking sort %q", page.Sort.Field)
+	}
+	paging, err := page.stages(field)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bookings := []*models.Booking{}
+	pipeline := append(mongo.Pipeline{{{Key: "$match", Value: filter}}}, paging...)
+	cursor, err := s.collection.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cursor.All(context.Background(), &bookings); err != nil {
+		return nil, nil, err
+	}
+
+	if int64(len(bookings)) <= page.Limit {
+		return bookings, nil, nil
+	}
+	bookings = bookings[:page.Limit]
+	last := bookings[len(bookings)-1]
+	return bookings, page.nextCursor(bookingSortValue(last, page.Sort.Field), last.ID), nil
+}
+
+func bookingSortValue(booking *models.Booking, sort string) float64 {
+	switch sort {
+	case "start_date":
+		return pagination.TimeValue(booking.StartDate)
+	case "total_amount":
+		return booking.TotalAmount
+	}
+	return pagination.TimeValue(booking.CreatedAt)
+}
+
 func (s *BookingService) GetBookingByID(id primitive.ObjectID) (*models.Booking, error) {
 	var booking models.Booking
 	err := s.collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&booking)
@@ -76,9 +131,67 @@ func (s *BookingService) UpdateBooking(id primitive.ObjectID, updates bson.M) er
 	return err
 }
 
+// ConfirmBooking confirms a pending booking and marks its nights as booked
+// on the property calendar. It returns ErrDatesUnavailable if the nights are
+// blocked or taken by another booking.
+func (s *BookingService) ConfirmBooking(booking *models.Booking) error {
+	if err := s.calendarService.BookDates(booking); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	result, err := s.collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": booking.ID, "status": "pending"},
+		bson.M{"$set": bson.M{"status": "confirmed", "updated_at": now}},
+	)
+	if err == nil && result.MatchedCount == 0 {
+		err = ErrBookingStatusChanged
+	}
+	if err != nil {
+		s.calendarService.ReleaseBooking(booking.ID)
+		return err
+	}
+
+	booking.Status = "confirmed"
+	booking.UpdatedAt = now
+	return nil
+}
+
+// CancelBooking cancels a pending or confirmed booking and frees its nights
+// on the property calendar.
+func (s *BookingService) CancelBooking(booking *models.Booking, cancelledBy primitive.ObjectID, reason string) error {
+	now := time.Now()
+	info := &models.CancellationInfo{
+		CancelledBy: cancelledBy,
+		CancelledAt: now,
+		Reason:      reason,
+	}
+	result, err := s.collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": booking.ID, "status": booking.Status},
+		bson.M{"$set": bson.M{"status": "cancelled", "cancellation_info": info, "updated_at": now}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrBookingStatusChanged
+	}
+
+	booking.Status = "cancelled"
+	booking.CancellationInfo = info
+	booking.UpdatedAt = now
+	return s.calendarService.ReleaseBooking(booking.ID)
+}
+
+// DeleteBooking deletes a booking and frees its nights on the property
+// calendar.
 func (s *BookingService) DeleteBooking(id primitive.ObjectID) error {
-	_, err := s.collection.DeleteOne(context.Background(), bson.M{"_id": id})
-	return err
+	if _, err := s.collection.DeleteOne(context.Background(), bson.M{"_id": id}); err != nil {
+		return err
+	}
+	return s.calendarService.ReleaseBooking(id)
 }
 
 func (s *BookingService) GetBookingsByTenant(tenantID primitive.ObjectID) ([]*models.Booking, error) {
@@ -88,3 +201,7 @@ func (s *BookingService) GetBookingsByTenant(tenantID primitive.ObjectID) ([]*mo
 func (s *BookingService) GetBookingsByProperty(propertyID primitive.ObjectID) ([]*models.Booking, error) {
 	return s.GetBookings(bson.M{"property_id": propertyID}, 100, 0)
 }
+
+func (s *BookingService) CountBookings(filter bson.M) (int64, error) {
+	return s.collection.CountDocuments(context.Background(), filter)
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"rent-help-backend/internal/models"
+	"rent-help-backend/pkg/calendar"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrDatesUnavailable = errors.New("dates are not available")
+
+// Calendar range statuses
+const (
+	CalendarAvailable = "available"
+	CalendarBlocked   = "blocked"
+	CalendarBooked    = "booked"
+)
+
+// unavailableStatuses are the range statuses that cannot be booked.
+var unavailableStatuses = []string{CalendarBlocked, CalendarBooked}
+
+// CalendarService keeps the availability calendar of each listing in the
+// property_calendar collection, one document per date range.
+type CalendarService struct {
+	collection *mongo.Collection
+}
+
+func NewCalendarService(db *mongo.Database) *CalendarService {
+	return &CalendarService{
+		collection: db.Collection("property_calendar"),
+	}
+}
+
+func (s *CalendarService) EnsureIndexes() error {
+	_, err := s.collection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "property_id", Value: 1}, {Key: "start_date", Value: 1}}},
+		{
+			Keys: bson.D{{Key: "booking_id", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+				"booking_id": bson.M{"$exists": true},
+			}),
+		},
+	})
+	return err
+}
+
+// GetRanges returns the ranges of a property that cover any night between
+// from and to, ordered by start date.
+func (s *CalendarService) GetRanges(propertyID primitive.ObjectID, from, to time.Time) ([]models.CalendarRange, error) {
+	ranges := []models.CalendarRange{}
+	opts := options.Find().SetSort(bson.D{{Key: "start_date", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := s.collection.Find(context.Background(), overlapFilter(propertyID, calendar.Range{Start: from, End: to}), opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(context.Background(), &ranges); err != nil {
+		return nil, err
+	}
+	return ranges, nil
+}
+
+// ReplaceRanges replaces the available and blocked ranges of a property.
+// Booked ranges belong to bookings and are left alone; they take precedence
+// over any range they overlap.
+func (s *CalendarService) ReplaceRanges(propertyID primitive.ObjectID, ranges []models.CalendarRange) error {
+	ctx := context.Background()
+	if _, err := s.collection.DeleteMany(ctx, bson.M{
+		"property_id": propertyID,
+		"status":      bson.M{"$ne": CalendarBooked},
+	}); err != nil {
+		return err
+	}
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	docs := make([]interface{}, len(ranges))
+	for i := range ranges {
+		ranges[i].ID = primitive.NewObjectID()
+		ranges[i].PropertyID = propertyID
+		ranges[i].BookingID = nil
+		ranges[i].CreatedAt = now
+		ranges[i].UpdatedAt = now
+		docs[i] = ranges[i]
+	}
+	_, err := s.collection.InsertMany(ctx, docs)
+	return err
+}
+
+// CheckAvailable returns ErrDatesUnavailable if any night of the stay is
+// blocked or booked.
+func (s *CalendarService) CheckAvailable(propertyID primitive.ObjectID, stay calendar.Range) error {
+	filter := overlapFilter(propertyID, stay)
+	filter["status"] = bson.M{"$in": unavailableStatuses}
+	count, err := s.collection.CountDocuments(context.Background(), filter, options.Count().SetLimit(1))
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDatesUnavailable
+	}
+	return nil
+}
+
+// StayPrice returns the rent for the nights of stay at property, using the
+// price overrides of its available ranges.
+func (s *CalendarService) StayPrice(property *models.Property, stay calendar.Range) (float64, error) {
+	ranges, err := s.GetRanges(property.ID, stay.Start, stay.End)
+	if err != nil {
+		return 0, err
+	}
+
+	var rates []calendar.Rate
+	for _, r := range ranges {
+		if r.Status != CalendarAvailable || (r.NightlyPrice == nil && r.MonthlyPrice == nil) {
+			continue
+		}
+		rates = append(rates, calendar.Rate{
+			Range:   calendar.NewRange(r.StartDate, r.EndDate),
+			Nightly: r.NightlyPrice,
+			Monthly: r.MonthlyPrice,
+		})
+	}
+	return calendar.StayPrice(stay, property.Price, rates), nil
+}
+
+// BookDates marks the nights of a booking as booked. It returns
+// ErrDatesUnavailable if any of them is blocked or booked already.
+//
+// MongoDB cannot enforce that ranges do not overlap, so the range is inserted
+// first and the calendar checked afterwards. Of two bookings confirmed at the
+// same time at least one sees the other and backs out.
+func (s *CalendarService) BookDates(booking *models.Booking) error {
+	ctx := context.Background()
+	stay := calendar.NewRange(booking.StartDate, booking.EndDate)
+	if !stay.Valid() {
+		return ErrDatesUnavailable
+	}
+	if err := s.CheckAvailable(booking.PropertyID, stay); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	bookingID := booking.ID
+	booked := models.CalendarRange{
+		ID:         primitive.NewObjectID(),
+		PropertyID: booking.PropertyID,
+		StartDate:  stay.Start,
+		EndDate:    stay.End,
+		Status:     CalendarBooked,
+		BookingID:  &bookingID,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if _, err := s.collection.InsertOne(ctx, booked); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			// The booking is on the calendar already.
+			return nil
+		}
+		return err
+	}
+
+	filter := overlapFilter(booking.PropertyID, stay)
+	filter["status"] = bson.M{"$in": unavailableStatuses}
+	filter["_id"] = bson.M{"$ne": booked.ID}
+	count, err := s.collection.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	if err == nil && count == 0 {
+		return nil
+	}
+	if _, delErr := s.collection.DeleteOne(ctx, bson.M{"_id": booked.ID}); delErr != nil && err == nil {
+		err = delErr
+	}
+	if err != nil {
+		return err
+	}
+	return ErrDatesUnavailable
+}
+
+// ReleaseBooking frees the nights held by a booking.
+func (s *CalendarService) ReleaseBooking(bookingID primitive.ObjectID) error {
+	_, err := s.collection.DeleteOne(context.Background(), bson.M{"booking_id": bookingID})
+	return err
+}
+
+// DeleteCalendar removes all ranges of a property.
+func (s *CalendarService) DeleteCalendar(propertyID primitive.ObjectID) error {
+	_, err := s.collection.DeleteMany(context.Background(), bson.M{"property_id": propertyID})
+	return err
+}
+
+func overlapFilter(propertyID primitive.ObjectID, r calendar.Range) bson.M {
+	return bson.M{
+		"property_id": propertyID,
+		"start_date":  bson.M{"$lt": r.End},
+		"end_date":    bson.M{"$gt": r.Start},
+	}
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"rent-help-backend/internal/models"
+	"rent-help-backend/pkg/pagination"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrFavoriteNotFound = errors.New("favorite not found")
+
+var favoriteSortField = sortField{path: "created_at", isTime: true}
+
+// FavoriteService manages users' saved listings.
+//
+// Property.FavoriteCount is adjusted only after a favorite has actually been
+// inserted or deleted. The unique (user_id, property_id) index makes both
+// happen at most once per pair, so concurrent or repeated requests cannot
+// drift the count.
+type FavoriteService struct {
+	collection *mongo.Collection
+	properties *mongo.Collection
+}
+
+func NewFavoriteService(db *mongo.Database) *FavoriteService {
+	return &FavoriteService{
+		collection: db.Collection("favorites"),
+		properties: db.Collection("properties"),
+	}
+}
+
+func (s *FavoriteService) EnsureIndexes() error {
+	_, err := s.collection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "property_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "property_id", Value: 1}}},
+	})
+	return err
+}
+
+// AddFavorite saves a listing for a user. Adding a listing twice is not an
+// error; created reports whether a new favorite was stored.
+func (s *FavoriteService) AddFavorite(userID, propertyID primitive.ObjectID) (favorite *models.Favorite, created bool, err error) {
+	ctx := context.Background()
+	favorite = &models.Favorite{
+		ID:         primitive.NewObjectID(),
+		UserID:     userID,
+		PropertyID: propertyID,
+		CreatedAt:  time.Now(),
+	}
+	if _, err := s.collection.InsertOne(ctx, favorite); err != nil {
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, false, err
+		}
+		existing := &models.Favorite{}
+		err := s.collection.FindOne(ctx, bson.M{"user_id": userID, "property_id": propertyID}).Decode(existing)
+		return existing, false, err
+	}
+
+	if err := s.adjustCount(propertyID, 1); err != nil {
+		// Undo the insert so that the count and the favorites stay in step.
+		s.collection.DeleteOne(ctx, bson.M{"_id": favorite.ID})
+		return nil, false, err
+	}
+	return favorite, true, nil
+}
+
+// RemoveFavorite removes a saved listing. It returns ErrFavoriteNotFound if
+// the user had not saved it.
+func (s *FavoriteService) RemoveFavorite(userID, propertyID primitive.ObjectID) error {
+	result, err := s.collection.DeleteOne(context.Background(), bson.M{"user_id": userID, "property_id": propertyID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrFavoriteNotFound
+	}
+	return s.adjustCount(propertyID, -1)
+}
+
+// ListFavorites returns one page of the listings a user saved, most recently
+// saved first. Favorites of listings that no longer exist are skipped, so a
+// page can be shorter than the limit.
+func (s *FavoriteService) ListFavorites(userID primitive.ObjectID, page PageRequest) ([]*models.Property, *string, error) {
+	paging, err := page.stages(favoriteSortField)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pipeline := append(mongo.Pipeline{{{Key: "$match", Value: bson.M{"user_id": userID}}}}, paging...)
+	pipeline = append(pipeline, bson.D{{Key: "$lookup", Value: bson.M{
+		"from":         s.properties.Name(),
+		"localField":   "property_id",
+		"foreignField": "_id",
+		"as":           "property",
+	}}})
+
+	var rows []struct {
+		ID        primitive.ObjectID `bson:"_id"`
+		CreatedAt time.Time          `bson:"created_at"`
+		Property  []*models.Property `bson:"property"`
+	}
+	cursor, err := s.collection.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cursor.All(context.Background(), &rows); err != nil {
+		return nil, nil, err
+	}
+
+	var next *string
+	if int64(len(rows)) > page.Limit {
+		rows = rows[:page.Limit]
+		last := rows[len(rows)-1]
+		next = page.nextCursor(pagination.TimeValue(last.CreatedAt), last.ID)
+	}
+
+	properties := make([]*models.Property, 0, len(rows))
+	for _, row := range rows {
+		if len(row.Property) > 0 {
+			properties = append(properties, row.Property[0])
+		}
+	}
+	return properties, next, nil
+}
+
+func (s *FavoriteService) CountFavorites(userID primitive.ObjectID) (int64, error) {
+	return s.collection.CountDocuments(context.Background(), bson.M{"user_id": userID})
+}
+
+// FavoritedIDs returns which of the given listings the user has saved.
+func (s *FavoriteService) FavoritedIDs(userID primitive.ObjectID, propertyIDs []primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	favorited := make(map[primitive.ObjectID]bool)
+	if len(propertyIDs) == 0 {
+		return favorited, nil
+	}
+
+	var favorites []models.Favorite
+	opts := options.Find().SetProjection(bson.M{"property_id": 1})
+	cursor, err := s.collection.Find(context.Background(), bson.M{
+		"user_id":     userID,
+		"property_id": bson.M{"$in": propertyIDs},
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(context.Background(), &favorites); err != nil {
+		return nil, err
+	}
+	for _, favorite := range favorites {
+		favorited[favorite.PropertyID] = true
+	}
+	return favorited, nil
+}
+
+// DeleteForProperty removes every favorite of a deleted listing.
+func (s *FavoriteService) DeleteForProperty(propertyID primitive.ObjectID) error {
+	_, err := s.collection.DeleteMany(context.Background(), bson.M{"property_id": propertyID})
+	return err
+}
+
+// DeleteForUser removes all favorites of a user, one at a time so that each
+// listing's count is decremented only for favorites actually removed.
+func (s *FavoriteService) DeleteForUser(userID primitive.ObjectID) error {
+	var favorites []models.Favorite
+	if err := findAll(s.collection, bson.M{"user_id": userID}, &favorites); err != nil {
+		return err
+	}
+	for _, favorite := range favorites {
+		if err := s.RemoveFavorite(userID, favorite.PropertyID); err != nil && err != ErrFavoriteNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *FavoriteService) adjustCount(propertyID primitive.ObjectID, delta int) error {
+	_, err := s.properties.UpdateOne(
+		context.Background(),
+		bson.M{"_id": propertyID},
+		bson.M{"$inc": bson.M{"favorite_count": delta}},
+	)
+	return err
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"rent-help-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	loginAttemptWindow   = 15 * time.Minute
+	loginLockoutDuration = 15 * time.Minute
+	// Failures per email before each further attempt has to wait.
+	loginDelayThreshold = 3
+	loginDelayBase      = time.Second
+	maxEmailFailures    = 5
+	maxIPFailures       = 20
+)
+
+// LoginThrottleService counts failed logins per email and per client IP in a
+// sliding window. It is backed by MongoDB so limits hold across replicas.
+type LoginThrottleService struct {
+	attempts     *mongo.Collection
+	lockouts     *mongo.Collection
+	auditService *AuditService
+}
+
+func NewLoginThrottleService(db *mongo.Database, auditService *AuditService) *LoginThrottleService {
+	return &LoginThrottleService{
+		attempts:     db.Collection("login_attempts"),
+		lockouts:     db.Collection("login_lockouts"),
+		auditService: auditService,
+	}
+}
+
+func (s *LoginThrottleService) EnsureIndexes() error {
+	_, err := s.attempts.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "key", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.lockouts.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "key", Value: 1}}},
+		{Keys: bson.D{{Key: "locked_until", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	return err
+}
+
+// Check returns how long the caller has to wait before the next login attempt
+// for this email and IP is accepted. Zero means the attempt may proceed.
+func (s *LoginThrottleService) Check(email, ip string) (time.Duration, error) {
+	now := time.Now()
+	emailKey, ipKey := emailAttemptKey(email), ipAttemptKey(ip)
+
+	var lockout models.LoginLockout
+	err := s.lockouts.FindOne(
+		context.Background(),
+		bson.M{"key": bson.M{"$in": []string{emailKey, ipKey}}, "locked_until": bson.M{"$gt": now}},
+		options.FindOne().SetSort(bson.D{{Key: "locked_until", Value: -1}}),
+	).Decode(&lockout)
+	if err == nil {
+		return lockout.LockedUntil.Sub(now), nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return 0, err
+	}
+
+	// Progressive delay: every failure past the threshold doubles the time
+	// that has to pass since the previous failure.
+	failures, err := s.countFailures(emailKey, now)
+	if err != nil || failures < loginDelayThreshold {
+		return 0, err
+	}
+
+	var last models.LoginAttempt
+	err = s.attempts.FindOne(
+		context.Background(),
+		bson.M{"key": emailKey},
+		options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	).Decode(&last)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if wait := last.CreatedAt.Add(loginDelay(failures)).Sub(now); wait > 0 {
+		return wait, nil
+	}
+	return 0, nil
+}
+
+// RecordFailure stores a failed attempt and locks the email or IP out once
+// it has too many failures inside the window.
+func (s *LoginThrottleService) RecordFailure(email, ip string) error {
+	now := time.Now()
+	keys := map[string]int64{
+		emailAttemptKey(email): maxEmailFailures,
+		ipAttemptKey(ip):       maxIPFailures,
+	}
+
+	for key, limit := range keys {
+		if _, err := s.attempts.InsertOne(context.Background(), models.LoginAttempt{
+			Key:       key,
+			CreatedAt: now,
+			ExpiresAt: now.Add(loginAttemptWindow),
+		}); err != nil {
+			return err
+		}
+
+		failures, err := s.countFailures(key, now)
+		if err != nil {
+			return err
+		}
+		if failures < limit {
+			continue
+		}
+
+		if err := s.lockout(key, failures, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordSuccess clears the failure history of the email. IP failures are
+// kept so that logging into one account does not reset an IP's budget.
+func (s *LoginThrottleService) RecordSuccess(email string) error {
+	_, err := s.attempts.DeleteMany(context.Background(), bson.M{"key": emailAttemptKey(email)})
+	return err
+}
+
+func (s *LoginThrottleService) lockout(key string, failures int64, now time.Time) error {
+	lockedUntil := now.Add(loginLockoutDuration)
+	if _, err := s.lockouts.InsertOne(context.Background(), models.LoginLockout{
+		Key:         key,
+		LockedUntil: lockedUntil,
+		CreatedAt:   now,
+	}); err != nil {
+		return err
+	}
+
+	// Start counting afresh once the lockout ends.
+	if _, err := s.attempts.DeleteMany(context.Background(), bson.M{"key": key}); err != nil {
+		return err
+	}
+
+	kind, value, _ := strings.Cut(key, ":")
+	entry := &models.AuditLog{
+		Action:     "auth.lockout",
+		TargetType: kind,
+		TargetID:   value,
+		Metadata: map[string]interface{}{
+			"failures":     failures,
+			"locked_until": lockedUntil,
+		},
+	}
+	if kind == "ip" {
+		entry.IP = value
+	}
+	if err := s.auditService.Record(entry); err != nil {
+		log.Printf("Failed to audit login lockout for %s: %v", key, err)
+	}
+	return nil
+}
+
+func (s *LoginThrottleService) countFailures(key string, now time.Time) (int64, error) {
+	return s.attempts.CountDocuments(context.Background(), bson.M{
+		"key":        key,
+		"created_at": bson.M{"$gt": now.Add(-loginAttemptWindow)},
+	})
+}
+
+// loginDelay returns the wait required after the given number of failures.
+func loginDelay(failures int64) time.Duration {
+	if failures < loginDelayThreshold {
+		return 0
+	}
+	return loginDelayBase << uint(failures-loginDelayThreshold)
+}
+
+func emailAttemptKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipAttemptKey(ip string) string {
+	return "ip:" + ip
+}
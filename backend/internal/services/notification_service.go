@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"rent-help-backend/internal/models"
+	"rent-help-backend/pkg/mailer"
+	"rent-help-backend/pkg/pagination"
+	"rent-help-backend/pkg/sms"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var notificationSortField = sortField{path: "created_at", isTime: true}
+
+// NotificationService delivers notifications on the channels a user turned on
+// in their preferences. Push notifications are stored in the notifications
+// collection, which the apps read through GET /users/me/notifications.
+type NotificationService struct {
+	collection *mongo.Collection
+	mailer     mailer.Mailer
+	sms        sms.Sender
+}
+
+func NewNotificationService(db *mongo.Database, m mailer.Mailer, sender sms.Sender) *NotificationService {
+	return &NotificationService{
+		collection: db.Collection("notifications"),
+		mailer:     m,
+		sms:        sender,
+	}
+}
+
+func (s *NotificationService) EnsureIndexes() error {
+	_, err := s.collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+	})
+	return err
+}
+
+// Notify sends the notification by email, SMS and push as the user's
+// preferences ask. A failing channel does not stop the others; their errors
+// are returned together.
+func (s *NotificationService) Notify(user *models.User, notification *models.Notification) error {
+	var errs []error
+	prefs := user.Preferences
+
+	if prefs.NotifyByEmail && user.Email != "" && user.IsVerified {
+		body := fmt.Sprintf("Hi %s,\n\n%s\n", user.FullName, notification.Content)
+		if notification.ActionURL != "" {
+			body += "\n" + notification.ActionURL + "\n"
+		}
+		errs = append(errs, s.mailer.Send(mailer.Message{
+			To:      user.Email,
+			Subject: notification.Title,
+			Body:    body,
+		}))
+	}
+
+	if prefs.NotifyBySMS && user.Phone != "" {
+		body := notification.Title
+		if notification.ActionURL != "" {
+			body += " " + notification.ActionURL
+		}
+		errs = append(errs, s.sms.Send(sms.Message{To: user.Phone, Body: body}))
+	}
+
+	if prefs.NotifyByPush {
+		notification.ID = primitive.NewObjectID()
+		notification.UserID = user.ID
+		notification.CreatedAt = time.Now()
+		_, err := s.collection.InsertOne(context.Background(), notification)
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// ListNotifications returns one page of a user's notifications, newest first.
+func (s *NotificationService) ListNotifications(userID primitive.ObjectID, page PageRequest) ([]*models.Notification, *string, error) {
+	paging, err := page.stages(notificationSortField)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	notifications := []*models.Notification{}
+	pipeline := append(mongo.Pipeline{{{Key: "$match", Value: bson.M{"user_id": userID}}}}, paging...)
+	cursor, err := s.collection.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cursor.All(context.Background(), &notifications); err != nil {
+		return nil, nil, err
+	}
+
+	if int64(len(notifications)) <= page.Limit {
+		return notifications, nil, nil
+	}
+	notifications = notifications[:page.Limit]
+	last := notifications[len(notifications)-1]
+	return notifications, page.nextCursor(pagination.TimeValue(last.CreatedAt), last.ID), nil
+}
+
+func (s *NotificationService) CountNotifications(userID primitive.ObjectID) (int64, error) {
+	return s.collection.CountDocuments(context.Background(), bson.M{"user_id": userID})
+}
+
+// DeleteForUser removes all notifications of a user.
+func (s *NotificationService) DeleteForUser(userID primitive.ObjectID) error {
+	_, err := s.collection.DeleteMany(context.Background(), bson.M{"user_id": userID})
+	return err
+}
@@ -0,0 +1,348 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"rent-help-backend/internal/config"
+	"rent-help-backend/internal/models"
+	"rent-help-backend/pkg/oidc"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// OIDCStateTTL is how long a sign-in started with BeginAuth can be completed.
+const OIDCStateTTL = 10 * time.Minute
+
+var (
+	ErrUnknownProvider       = errors.New("unknown identity provider")
+	ErrInvalidOIDCState      = errors.New("invalid or expired sign-in state")
+	ErrOIDCEmailNotVerified  = errors.New("identity provider did not return a verified email")
+	ErrIdentityAlreadyLinked = errors.New("identity is linked to another user")
+	ErrProviderAlreadyLinked = errors.New("a different account at this provider is already linked")
+	ErrIdentityNotFound      = errors.New("identity not found")
+	ErrLastIdentity          = errors.New("cannot unlink the only way to sign in before a password is set")
+)
+
+// OIDCProviderInfo is the public description of a configured provider.
+type OIDCProviderInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+// OIDCResult is the outcome of a completed provider redirect.
+type OIDCResult struct {
+	User    *models.User
+	Linking bool // the flow was started by a signed-in user linking a provider
+	Created bool // a new user was registered
+}
+
+// OIDCService signs users in with OpenID Connect providers using the
+// authorization code flow with PKCE. Identities are matched by the
+// provider's subject, then by verified email, and new users are registered
+// otherwise.
+type OIDCService struct {
+	states       *mongo.Collection
+	identities   *mongo.Collection
+	userService  *UserService
+	tokenService *TokenService
+	providers    map[string]*oidc.Provider
+	providerInfo []OIDCProviderInfo
+}
+
+func NewOIDCService(db *mongo.Database, userService *UserService, tokenService *TokenService, cfg *config.Config) *OIDCService {
+	s := &OIDCService{
+		states:       db.Collection("oidc_states"),
+		identities:   db.Collection("user_identities"),
+		userService:  userService,
+		tokenService: tokenService,
+		providers:    make(map[string]*oidc.Provider),
+	}
+	for _, provider := range cfg.OIDCProviders {
+		s.providers[provider.Name] = oidc.NewProvider(oidc.Config{
+			Issuer:       provider.Issuer,
+			ClientID:     provider.ClientID,
+			ClientSecret: provider.ClientSecret,
+			RedirectURL:  cfg.PublicURL + "/api/v1/auth/oidc/" + provider.Name + "/callback",
+			Scopes:       provider.Scopes,
+		}, nil)
+		s.providerInfo = append(s.providerInfo, OIDCProviderInfo{Name: provider.Name, DisplayName: provider.DisplayName})
+	}
+	return s
+}
+
+func (s *OIDCService) EnsureIndexes() error {
+	if _, err := s.states.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "state_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	}); err != nil {
+		return err
+	}
+
+	_, err := s.identities.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "provider", Value: 1}, {Key: "subject", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "provider", Value: 1}}, Options: options.Index().SetUnique(true)},
+	})
+	return err
+}
+
+// Providers lists the configured providers.
+func (s *OIDCService) Providers() []OIDCProviderInfo {
+	if s.providerInfo == nil {
+		return []OIDCProviderInfo{}
+	}
+	return s.providerInfo
+}
+
+// HasProvider reports whether providerName is configured.
+func (s *OIDCService) HasProvider(providerName string) bool {
+	_, ok := s.providers[providerName]
+	return ok
+}
+
+// BeginAuth returns the provider URL to redirect the user to and the state
+// the provider will send back. The caller binds the state to the browser so
+// that a callback started elsewhere is rejected. linkUserID is set when a
+// signed-in user is linking the provider to their account.
+func (s *OIDCService) BeginAuth(providerName string, linkUserID *primitive.ObjectID) (authURL, state string, err error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+
+	state, err = oidc.GenerateState()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := oidc.GenerateState()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := oidc.GenerateVerifier()
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err = provider.AuthCodeURL(context.Background(), state, nonce, oidc.Challenge(verifier))
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	record := models.OIDCAuthState{
+		StateHash:    hashToken(state),
+		Provider:     providerName,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		UserID:       linkUserID,
+		ExpiresAt:    now.Add(OIDCStateTTL),
+		CreatedAt:    now,
+	}
+	if _, err := s.states.InsertOne(context.Background(), record); err != nil {
+		return "", "", err
+	}
+	return authURL, state, nil
+}
+
+// CompleteAuth handles the provider's redirect back with code and state.
+func (s *OIDCService) CompleteAuth(providerName, code, state string) (*OIDCResult, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	// States are single use.
+	var record models.OIDCAuthState
+	err := s.states.FindOneAndDelete(context.Background(), bson.M{
+		"state_hash": hashToken(state),
+		"provider":   providerName,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}).Decode(&record)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrInvalidOIDCState
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	token, err := provider.Exchange(ctx, code, record.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := provider.VerifyIDToken(ctx, token.IDToken, record.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &OIDCResult{Linking: record.UserID != nil}
+
+	var identity models.UserIdentity
+	err = s.identities.FindOne(ctx, bson.M{"provider": providerName, "subject": claims.Subject}).Decode(&identity)
+	switch {
+	case err == nil:
+		if record.UserID != nil && identity.UserID != *record.UserID {
+			return nil, ErrIdentityAlreadyLinked
+		}
+		now := time.Now()
+		if _, err := s.identities.UpdateOne(ctx, bson.M{"_id": identity.ID}, bson.M{"$set": bson.M{"last_used_at": now}}); err != nil {
+			return nil, err
+		}
+		result.User, err = s.userService.GetUserByID(identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+
+	case err != mongo.ErrNoDocuments:
+		return nil, err
+	}
+
+	if record.UserID != nil {
+		result.User, err = s.userService.GetUserByID(*record.UserID)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		result.User, result.Created, err = s.userForClaims(claims)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.link(result.User.ID, providerName, claims); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// userForClaims finds the user with the provider's verified email or
+// registers a new one.
+func (s *OIDCService) userForClaims(claims *oidc.Claims) (*models.User, bool, error) {
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, false, ErrOIDCEmailNotVerified
+	}
+	email := claims.Email
+
+	user, err := s.userService.GetUserByEmail(email)
+	if err == nil {
+		if !user.IsVerified {
+			// Whoever registered this address never proved they own it, so
+			// the password they chose must not keep working once the real
+			// owner signs in.
+			if err := s.lockPassword(user.ID); err != nil {
+				return nil, false, err
+			}
+			if err := s.userService.MarkEmailVerified(user.ID); err != nil {
+				return nil, false, err
+			}
+			user.IsVerified = true
+			user.Verified = true
+		}
+		return user, false, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, false, err
+	}
+
+	password, err := generateRandomToken(32)
+	if err != nil {
+		return nil, false, err
+	}
+	name := claims.Name
+	if name == "" {
+		name, _, _ = strings.Cut(email, "@")
+	}
+	user = &models.User{
+		Email:    email,
+		Password: password,
+		FullName: name,
+		Avatar:   claims.Picture,
+		Role:     "tenant",
+		// The random password above is never shown to anyone.
+		PasswordUnset: true,
+	}
+	if err := s.userService.CreateUser(user); err != nil {
+		return nil, false, err
+	}
+	if err := s.userService.MarkEmailVerified(user.ID); err != nil {
+		return nil, false, err
+	}
+	user.IsVerified = true
+	user.Verified = true
+	return user, true, nil
+}
+
+func (s *OIDCService) lockPassword(userID primitive.ObjectID) error {
+	if err := s.userService.LockPassword(userID); err != nil {
+		return err
+	}
+	return s.tokenService.RevokeAllForUser(userID)
+}
+
+func (s *OIDCService) link(userID primitive.ObjectID, providerName string, claims *oidc.Claims) error {
+	now := time.Now()
+	_, err := s.identities.InsertOne(context.Background(), models.UserIdentity{
+		UserID:     userID,
+		Provider:   providerName,
+		Subject:    claims.Subject,
+		Email:      claims.Email,
+		LastUsedAt: &now,
+		CreatedAt:  now,
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrProviderAlreadyLinked
+	}
+	return err
+}
+
+// GetIdentities lists the providers linked to the user.
+func (s *OIDCService) GetIdentities(userID primitive.ObjectID) ([]*models.UserIdentity, error) {
+	identities := []*models.UserIdentity{}
+	if err := findAll(s.identities, bson.M{"user_id": userID}, &identities); err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+// Unlink removes the user's identity at the provider. A user who never set a
+// password must keep at least one identity to sign in with.
+func (s *OIDCService) Unlink(user *models.User, providerName string) error {
+	userID := user.ID
+	if user.PasswordUnset {
+		others, err := s.identities.CountDocuments(context.Background(), bson.M{"user_id": userID, "provider": bson.M{"$ne": providerName}})
+		if err != nil {
+			return err
+		}
+		if others == 0 {
+			linked, err := s.identities.CountDocuments(context.Background(), bson.M{"user_id": userID, "provider": providerName})
+			if err != nil {
+				return err
+			}
+			if linked == 0 {
+				return ErrIdentityNotFound
+			}
+			return ErrLastIdentity
+		}
+	}
+
+	result, err := s.identities.DeleteOne(context.Background(), bson.M{"user_id": userID, "provider": providerName})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrIdentityNotFound
+	}
+	return nil
+}
+
+// DeleteIdentities removes every identity linked to the user.
+func (s *OIDCService) DeleteIdentities(userID primitive.ObjectID) error {
+	_, err := s.identities.DeleteMany(context.Background(), bson.M{"user_id": userID})
+	return err
+}
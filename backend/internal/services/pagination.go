@@ -0,0 +1,70 @@
+package services
+
+import (
+	"rent-help-backend/pkg/pagination"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// PageRequest selects one page of a keyset-paginated list.
+type PageRequest struct {
+	Sort  pagination.Sort
+	After *pagination.Cursor
+	Limit int64
+}
+
+// sortField maps a public sort name to the document field it orders by.
+type sortField struct {
+	path   string
+	isTime bool
+}
+
+func (p PageRequest) direction() int {
+	if p.Sort.Desc {
+		return -1
+	}
+	return 1
+}
+
+// stages returns the pipeline stages that select the page: a range match
+// past the cursor, the sort with _id as tie-breaker, and a limit one larger
+// than the page so the caller can tell whether another page follows.
+func (p PageRequest) stages(field sortField) (mongo.Pipeline, error) {
+	var stages mongo.Pipeline
+
+	if p.After != nil {
+		id, err := primitive.ObjectIDFromHex(p.After.ID)
+		if err != nil {
+			return nil, pagination.ErrInvalidCursor
+		}
+
+		var value interface{} = p.After.Value
+		if field.isTime {
+			value = p.After.Time()
+		}
+		op := "$gt"
+		if p.Sort.Desc {
+			op = "$lt"
+		}
+
+		stages = append(stages, bson.D{{Key: "$match", Value: bson.M{"$or": bson.A{
+			bson.M{field.path: bson.M{op: value}},
+			bson.M{field.path: value, "_id": bson.M{op: id}},
+		}}}})
+	}
+
+	stages = append(stages,
+		bson.D{{Key: "$sort", Value: bson.D{{Key: field.path, Value: p.direction()}, {Key: "_id", Value: p.direction()}}}},
+		bson.D{{Key: "$limit", Value: p.Limit + 1}},
+	)
+	return stages, nil
+}
+
+// nextCursor returns the cursor for the page following an item with the
+// given sort value and id.
+func (p PageRequest) nextCursor(value float64, id primitive.ObjectID) *string {
+	cursor := pagination.Encode(pagination.Cursor{Sort: p.Sort.String(), Value: value, ID: id.Hex()})
+	return &cursor
+}
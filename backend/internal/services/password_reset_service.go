@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"rent-help-backend/internal/config"
+	"rent-help-backend/internal/models"
+	"rent-help-backend/pkg/mailer"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const passwordResetTTL = time.Hour
+
+var ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+
+type PasswordResetService struct {
+	collection   *mongo.Collection
+	userService  *UserService
+	tokenService *TokenService
+	mailer       mailer.Mailer
+	appURL       string
+}
+
+func NewPasswordResetService(db *mongo.Database, userService *UserService, tokenService *TokenService, m mailer.Mailer, cfg *config.Config) *PasswordResetService {
+	return &PasswordResetService{
+		collection:   db.Collection("password_reset_tokens"),
+		userService:  userService,
+		tokenService: tokenService,
+		mailer:       m,
+		appURL:       cfg.AppURL,
+	}
+}
+
+func (s *PasswordResetService) EnsureIndexes() error {
+	_, err := s.collection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	return err
+}
+
+// RequestReset emails a reset link when the address belongs to a user. It
+// returns nil for unknown addresses so callers cannot probe for accounts.
+func (s *PasswordResetService) RequestReset(email string) error {
+	user, err := s.userService.GetUserByEmail(email)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.sendResetEmail(user)
+}
+
+// ForceReset is used by admins. It replaces the user's password with a random
+// one, signs them out everywhere and emails them a reset link.
+func (s *PasswordResetService) ForceReset(user *models.User) error {
+	if err := s.userService.LockPassword(user.ID); err != nil {
+		return err
+	}
+	if err := s.tokenService.RevokeAllForUser(user.ID); err != nil {
+		return err
+	}
+	return s.sendResetEmail(user)
+}
+
+func (s *PasswordResetService) sendResetEmail(user *models.User) error {
+	rawToken, err := s.CreateToken(user.ID)
+	if err != nil {
+		return err
+	}
+
+	link := s.appURL + "/reset-password?token=" + url.QueryEscape(rawToken)
+	return s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your RentHelp password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nWe received a request to reset your password. Open the link below to choose a new one:\n\n%s\n\nThe link expires in %d minutes and can only be used once. If you did not ask for this, you can ignore this email.\n",
+			user.FullName, link, int(passwordResetTTL.Minutes()),
+		),
+	})
+}
+
+// CreateToken issues a new reset token for the user and invalidates any
+// earlier ones that have not been used yet.
+func (s *PasswordResetService) CreateToken(userID primitive.ObjectID) (string, error) {
+	if _, err := s.collection.DeleteMany(context.Background(), bson.M{"user_id": userID, "used_at": nil}); err != nil {
+		return "", err
+	}
+
+	rawToken, err := generateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	record := models.PasswordResetToken{
+		UserID:    userID,
+		TokenHash: hashToken(rawToken),
+		ExpiresAt: now.Add(passwordResetTTL),
+		CreatedAt: now,
+	}
+	if _, err := s.collection.InsertOne(context.Background(), record); err != nil {
+		return "", err
+	}
+	return rawToken, nil
+}
+
+// ResetPassword consumes the token, stores the new password and revokes all
+// of the user's refresh tokens. The password must already be validated.
+func (s *PasswordResetService) ResetPassword(rawToken, password string) error {
+	now := time.Now()
+	var record models.PasswordResetToken
+	err := s.collection.FindOneAndUpdate(
+		context.Background(),
+		bson.M{
+			"token_hash": hashToken(rawToken),
+			"used_at":    nil,
+			"expires_at": bson.M{"$gt": now},
+		},
+		bson.M{"$set": bson.M{"used_at": now}},
+	).Decode(&record)
+	if err == mongo.ErrNoDocuments {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.userService.UpdatePassword(record.UserID, password); err != nil {
+		return err
+	}
+	return s.tokenService.RevokeAllForUser(record.UserID)
+}
@@ -0,0 +1,272 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"rent-help-backend/internal/models"
+	"rent-help-backend/pkg/imaging"
+	"rent-help-backend/pkg/storage"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrImageNotFound     = errors.New("image not found")
+	ErrTooManyImages     = errors.New("too many images")
+	ErrInvalidImageOrder = errors.New("image order must list every image exactly once")
+	ErrImagesChanged     = errors.New("property images were changed concurrently")
+)
+
+// variantExtensions maps variant formats to file extensions.
+var variantExtensions = map[string]string{
+	imaging.FormatJPEG: ".jpg",
+	imaging.FormatWebP: ".webp",
+}
+
+// ImageUpload is a validated image file ready to be stored. Data has already
+// been stripped of metadata and Variants rendered by imaging.Process.
+type ImageUpload struct {
+	Data        []byte
+	ContentType string
+	Extension   string
+	Caption     string
+	Width       int
+	Height      int
+	Variants    []imaging.Variant
+}
+
+// PropertyImageService manages the photos of a listing. Image files live in
+// the blob store and their metadata in the property's property_images array.
+//
+// Every change rewrites the whole array, guarded by the property's
+// updated_at, so concurrent edits cannot leave two primary images or
+// duplicate positions behind. A lost race is reported as ErrImagesChanged.
+type PropertyImageService struct {
+	collection *mongo.Collection
+	store      storage.BlobStore
+	maxImages  int
+}
+
+func NewPropertyImageService(db *mongo.Database, store storage.BlobStore, maxImages int) *PropertyImageService {
+	return &PropertyImageService{
+		collection: db.Collection("properties"),
+		store:      store,
+		maxImages:  maxImages,
+	}
+}
+
+// AddImages stores the uploads and their variants and appends them to the
+// property's images. The first image of a property becomes its primary image.
+// The thumbnail is the first JPEG variant, the smallest with
+// imaging.DefaultSizes.
+func (s *PropertyImageService) AddImages(property *models.Property, uploads []ImageUpload) ([]models.PropertyImage, error) {
+	if len(property.PropertyImages)+len(uploads) > s.maxImages {
+		return nil, ErrTooManyImages
+	}
+
+	images := append([]models.PropertyImage{}, property.PropertyImages...)
+	var stored []string
+	for _, upload := range uploads {
+		id := primitive.NewObjectID()
+		prefix := "properties/" + property.ID.Hex() + "/" + id.Hex()
+		key := prefix + upload.Extension
+		if err := s.store.Put(key, bytes.NewReader(upload.Data), upload.ContentType); err != nil {
+			s.deleteBlobs(stored)
+			return nil, err
+		}
+		stored = append(stored, key)
+
+		image := models.PropertyImage{
+			ID:          id,
+			URL:         s.store.URL(key),
+			Key:         key,
+			ContentType: upload.ContentType,
+			Size:        int64(len(upload.Data)),
+			Width:       upload.Width,
+			Height:      upload.Height,
+			Caption:     upload.Caption,
+			UploadedAt:  time.Now(),
+		}
+
+		for _, v := range upload.Variants {
+			variantKey := prefix + "_" + v.Name + variantExtensions[v.Format]
+			if err := s.store.Put(variantKey, bytes.NewReader(v.Data), v.ContentType); err != nil {
+				s.deleteBlobs(stored)
+				return nil, err
+			}
+			stored = append(stored, variantKey)
+
+			variant := models.ImageVariant{
+				Name:   v.Name,
+				Format: v.Format,
+				Width:  v.Width,
+				Height: v.Height,
+				Size:   int64(len(v.Data)),
+				URL:    s.store.URL(variantKey),
+				Key:    variantKey,
+			}
+			if image.ThumbnailURL == "" && v.Format == imaging.FormatJPEG {
+				image.ThumbnailURL = variant.URL
+			}
+			image.Variants = append(image.Variants, variant)
+		}
+
+		images = append(images, image)
+	}
+
+	if err := s.save(property, images); err != nil {
+		s.deleteBlobs(stored)
+		return nil, err
+	}
+	return images, nil
+}
+
+// ReorderImages puts the images in the order of ids, which must contain
+// every image of the property exactly once.
+func (s *PropertyImageService) ReorderImages(property *models.Property, ids []primitive.ObjectID) ([]models.PropertyImage, error) {
+	if len(ids) != len(property.PropertyImages) {
+		return nil, ErrInvalidImageOrder
+	}
+
+	byID := make(map[primitive.ObjectID]models.PropertyImage, len(property.PropertyImages))
+	for _, image := range property.PropertyImages {
+		byID[image.ID] = image
+	}
+
+	images := make([]models.PropertyImage, 0, len(ids))
+	for _, id := range ids {
+		image, ok := byID[id]
+		if !ok {
+			return nil, ErrInvalidImageOrder
+		}
+		delete(byID, id)
+		images = append(images, image)
+	}
+
+	if err := s.save(property, images); err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// SetPrimaryImage makes the image the property's primary image.
+func (s *PropertyImageService) SetPrimaryImage(property *models.Property, imageID primitive.ObjectID) ([]models.PropertyImage, error) {
+	images := append([]models.PropertyImage{}, property.PropertyImages...)
+	found := false
+	for i := range images {
+		images[i].IsPrimary = images[i].ID == imageID
+		found = found || images[i].IsPrimary
+	}
+	if !found {
+		return nil, ErrImageNotFound
+	}
+
+	if err := s.save(property, images); err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// DeleteImage removes the image and its file. If it was the primary image,
+// the next image in order takes its place.
+func (s *PropertyImageService) DeleteImage(property *models.Property, imageID primitive.ObjectID) ([]models.PropertyImage, error) {
+	var images []models.PropertyImage
+	var removed *models.PropertyImage
+	for i, image := range property.PropertyImages {
+		if image.ID == imageID {
+			removed = &property.PropertyImages[i]
+			continue
+		}
+		images = append(images, image)
+	}
+	if removed == nil {
+		return nil, ErrImageNotFound
+	}
+
+	if err := s.save(property, images); err != nil {
+		return nil, err
+	}
+	s.deleteBlobs(imageKeys([]models.PropertyImage{*removed}))
+	return images, nil
+}
+
+// DeleteAllImages removes the files of every uploaded image of a property
+// that is being deleted.
+func (s *PropertyImageService) DeleteAllImages(property *models.Property) {
+	s.deleteBlobs(imageKeys(property.PropertyImages))
+}
+
+// save renumbers the images, makes sure exactly one is primary and writes
+// them if the property has not changed since it was read.
+func (s *PropertyImageService) save(property *models.Property, images []models.PropertyImage) error {
+	if images == nil {
+		images = []models.PropertyImage{}
+	}
+	normalizeImages(images)
+
+	now := time.Now()
+	result, err := s.collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": property.ID, "updated_at": property.UpdatedAt},
+		bson.M{"$set": bson.M{"property_images": images, "updated_at": now}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrImagesChanged
+	}
+
+	property.PropertyImages = images
+	property.UpdatedAt = now
+	return nil
+}
+
+func normalizeImages(images []models.PropertyImage) {
+	primary := -1
+	for i := range images {
+		images[i].Order = i
+		if images[i].ID.IsZero() {
+			images[i].ID = primitive.NewObjectID()
+		}
+		if images[i].IsPrimary {
+			if primary >= 0 {
+				images[i].IsPrimary = false
+			} else {
+				primary = i
+			}
+		}
+	}
+	if primary < 0 && len(images) > 0 {
+		images[0].IsPrimary = true
+	}
+}
+
+func (s *PropertyImageService) deleteBlobs(keys []string) {
+	for _, key := range keys {
+		if err := s.store.Delete(key); err != nil {
+			log.Printf("Failed to delete image %s: %v", key, err)
+		}
+	}
+}
+
+func imageKeys(images []models.PropertyImage) []string {
+	var keys []string
+	for _, image := range images {
+		if image.Key != "" {
+			keys = append(keys, image.Key)
+		}
+		for _, v := range image.Variants {
+			if v.Key != "" {
+				keys = append(keys, v.Key)
+			}
+		}
+	}
+	return keys
+}
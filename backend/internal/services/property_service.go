@@ -2,9 +2,16 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"rent-help-backend/internal/models"
+	"rent-help-backend/pkg/geo"
+	"rent-help-backend/pkg/listing"
+	"rent-help-backend/pkg/pagination"
+	"rent-help-backend/pkg/textsearch"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -12,6 +19,43 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// Text search ranking: the text score is multiplied by 1 plus these boosts,
+// so featured and high-priority listings rise among equally relevant results
+// without outranking clearly better matches.
+const (
+	featuredSearchBoost = 0.5
+	prioritySearchBoost = 0.05
+	maxSearchPriority   = 10
+)
+
+const earthRadiusKm = 6378.1
+
+var (
+	ErrInvalidStatusTransition = errors.New("listing cannot be moved to that status")
+	ErrPropertyStatusChanged   = errors.New("listing status was changed concurrently")
+)
+
+// PropertySorts are the sort names accepted by ListProperties and friends.
+// "distance" needs a near point and "relevance" a text query.
+var PropertySorts = []string{"price", "created_at", "rating", "distance", "relevance"}
+
+var propertySortFields = map[string]sortField{
+	"price":      {path: "price"},
+	"created_at": {path: "created_at", isTime: true},
+	"rating":     {path: "rating.average"},
+	"distance":   {path: "distance"},
+	"relevance":  {path: "score"},
+}
+
+// searchTextFields are the fields covered by the text index. Updating any of
+// them refreshes the listing's search terms.
+var searchTextFields = []string{"title", "description", "tags", "amenities", "address"}
+
+// reviewedFields are the fields a reviewer approves before a listing goes
+// public. Changing any of them sends a published or paused listing back to
+// review.
+var reviewedFields = []string{"title", "description", "price", "currency", "images", "videos", "virtual_tour", "floor_plan"}
+
 type PropertyService struct {
 	collection *mongo.Collection
 }
@@ -22,10 +66,73 @@ func NewPropertyService(db *mongo.Database) *PropertyService {
 	}
 }
 
+func (s *PropertyService) EnsureIndexes() error {
+	_, err := s.collection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "location", Value: "2dsphere"}}},
+		{
+			Keys: bson.D{
+				{Key: "title", Value: "text"},
+				{Key: "description", Value: "text"},
+				{Key: "tags", Value: "text"},
+				{Key: "amenities", Value: "text"},
+				{Key: "address.street", Value: "text"},
+				{Key: "address.city", Value: "text"},
+				{Key: "address.state", Value: "text"},
+				{Key: "address.country", Value: "text"},
+				{Key: "search_terms", Value: "text"},
+			},
+			Options: options.Index().SetName("property_text").SetWeights(bson.M{
+				"title":           10,
+				"tags":            5,
+				"search_terms":    5,
+				"amenities":       3,
+				"address.city":    3,
+				"address.street":  2,
+				"address.state":   2,
+				"address.country": 1,
+				"description":     1,
+			}),
+		},
+		{Keys: bson.D{{Key: "owner_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "first_published_at", Value: 1}}},
+	})
+	return err
+}
+
+// CreateProperty stores a new listing. Listings start as drafts unless
+// Status is set; the initial status is the first entry of StatusHistory.
+// Counters, ratings and the admin-only featured and priority fields always
+// start at zero, whatever the request body held.
 func (s *PropertyService) CreateProperty(property *models.Property) error {
-	property.CreatedAt = time.Now()
-	property.UpdatedAt = time.Now()
-	property.Available = true
+	now := time.Now()
+	property.ID = primitive.NilObjectID
+	property.ViewCount = 0
+	property.FavoriteCount = 0
+	property.Rating = models.PropertyRating{}
+	property.Featured = false
+	property.Priority = 0
+	property.CreatedAt = now
+	property.UpdatedAt = now
+	if property.Status == "" {
+		property.Status = listing.Draft
+	}
+	property.Available = listing.Public(property.Status)
+	property.StatusHistory = []models.StatusChange{{
+		To:        property.Status,
+		ChangedBy: property.OwnerID,
+		ChangedAt: now,
+	}}
+	property.PublishedAt = nil
+	property.FirstPublishedAt = nil
+	if property.Status == listing.Published {
+		property.PublishedAt = &now
+		property.FirstPublishedAt = &now
+	}
+	property.SearchTerms = propertySearchTerms(property)
+	normalizeImages(property.PropertyImages)
+	property.Distance = nil
+	property.Score = nil
 
 	result, err := s.collection.InsertOne(context.Background(), property)
 	if err != nil {
@@ -57,6 +164,177 @@ func (s *PropertyService) GetProperties(filter bson.M, limit, skip int64) ([]*mo
 	return properties, nil
 }
 
+// ListProperties returns one page of the properties matching filter.
+func (s *PropertyService) ListProperties(filter bson.M, page PageRequest) ([]*models.Property, *string, error) {
+	return s.listPage(mongo.Pipeline{{{Key: "$match", Value: filter}}}, page)
+}
+
+// GetPropertiesNear returns one page of the properties matching filter within
+// radiusKm of near. Each result has Distance set in kilometres.
+func (s *PropertyService) GetPropertiesNear(filter bson.M, near geo.Point, radiusKm float64, page PageRequest) ([]*models.Property, *string, error) {
+	return s.listPage(mongo.Pipeline{
+		{{Key: "$geoNear", Value: bson.M{
+			"near":               bson.M{"type": "Point", "coordinates": near.Coordinates()},
+			"distanceField":      "distance",
+			"distanceMultiplier": 0.001,
+			"maxDistance":        radiusKm * 1000,
+			"query":              filter,
+			"spherical":          true,
+		}}},
+	}, page)
+}
+
+// CountPropertiesNear counts the properties GetPropertiesNear would return
+// across all pages.
+func (s *PropertyService) CountPropertiesNear(filter bson.M, near geo.Point, radiusKm float64) (int64, error) {
+	count := bson.M{"location": bson.M{"$geoWithin": bson.M{
+		"$centerSphere": bson.A{near.Coordinates(), radiusKm / earthRadiusKm},
+	}}}
+	for key, value := range filter {
+		count[key] = value
+	}
+	return s.collection.CountDocuments(context.Background(), count)
+}
+
+// SearchProperties runs a full-text search for query, which must already be
+// in textsearch.Query form, within the listings matching filter. Each result
+// has Score set to the text score blended with Featured and Priority, which
+// the "relevance" sort orders by.
+func (s *PropertyService) SearchProperties(filter bson.M, query string, page PageRequest) ([]*models.Property, *string, error) {
+	boost := bson.M{"$add": bson.A{
+		1,
+		bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$featured", true}}, featuredSearchBoost, 0}},
+		bson.M{"$multiply": bson.A{
+			prioritySearchBoost,
+			bson.M{"$min": bson.A{bson.M{"$max": bson.A{"$priority", 0}}, maxSearchPriority}},
+		}},
+	}}
+
+	return s.listPage(mongo.Pipeline{
+		{{Key: "$match", Value: textSearchFilter(filter, query)}},
+		{{Key: "$addFields", Value: bson.M{"score": bson.M{"$multiply": bson.A{bson.M{"$meta": "textScore"}, boost}}}}},
+	}, page)
+}
+
+// CountSearchProperties counts the properties SearchProperties would return
+// across all pages.
+func (s *PropertyService) CountSearchProperties(filter bson.M, query string) (int64, error) {
+	return s.collection.CountDocuments(context.Background(), textSearchFilter(filter, query))
+}
+
+// PublishedIDs returns the IDs of the listings first published after since
+// and no later than until that are still published. Listings that are paused
+// and published again are not new.
+func (s *PropertyService) PublishedIDs(since, until time.Time) ([]primitive.ObjectID, error) {
+	var properties []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	opts := options.Find().SetProjection(bson.M{"_id": 1})
+	cursor, err := s.collection.Find(context.Background(), bson.M{
+		"status":             listing.Published,
+		"first_published_at": bson.M{"$gt": since, "$lte": until},
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(context.Background(), &properties); err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, len(properties))
+	for i, property := range properties {
+		ids[i] = property.ID
+	}
+	return ids, nil
+}
+
+// MatchProperties returns up to limit listings that match both the search
+// and filter, earliest first published first. A proximity search matches the
+// listings within its radius; unlike GetPropertiesNear the results are not
+// sorted by distance.
+func (s *PropertyService) MatchProperties(search *SearchCriteria, filter bson.M, limit int64) ([]*models.Property, error) {
+	match := bson.M{}
+	for key, value := range search.Filter {
+		match[key] = value
+	}
+	for key, value := range filter {
+		match[key] = value
+	}
+	if search.Near != nil {
+		match["location"] = bson.M{"$geoWithin": bson.M{
+			"$centerSphere": bson.A{search.Near.Coordinates(), search.RadiusKm / earthRadiusKm},
+		}}
+	}
+	if search.Query != "" {
+		match = textSearchFilter(match, search.Query)
+	}
+
+	properties := []*models.Property{}
+	opts := options.Find().SetSort(bson.D{{Key: "first_published_at", Value: 1}}).SetLimit(limit)
+	cursor, err := s.collection.Find(context.Background(), match, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(context.Background(), &properties); err != nil {
+		return nil, err
+	}
+	return properties, nil
+}
+
+func textSearchFilter(filter bson.M, query string) bson.M {
+	match := bson.M{"$text": bson.M{"$search": query}}
+	for key, value := range filter {
+		match[key] = value
+	}
+	return match
+}
+
+// listPage runs stages followed by the paging stages for page.
+func (s *PropertyService) listPage(stages mongo.Pipeline, page PageRequest) ([]*models.Property, *string, error) {
+	field, ok := propertySortFields[page.Sort.Field]
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported property sort %q", page.Sort.Field)
+	}
+	paging, err := page.stages(field)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	properties := []*models.Property{}
+	cursor, err := s.collection.Aggregate(context.Background(), append(stages, paging...))
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cursor.All(context.Background(), &properties); err != nil {
+		return nil, nil, err
+	}
+
+	if int64(len(properties)) <= page.Limit {
+		return properties, nil, nil
+	}
+	properties = properties[:page.Limit]
+	last := properties[len(properties)-1]
+	return properties, page.nextCursor(propertySortValue(last, page.Sort.Field), last.ID), nil
+}
+
+func propertySortValue(property *models.Property, sort string) float64 {
+	switch sort {
+	case "price":
+		return property.Price
+	case "rating":
+		return property.Rating.Average
+	case "distance":
+		if property.Distance != nil {
+			return *property.Distance
+		}
+	case "relevance":
+		if property.Score != nil {
+			return *property.Score
+		}
+	}
+	return pagination.TimeValue(property.CreatedAt)
+}
+
 func (s *PropertyService) GetPropertyByID(id primitive.ObjectID) (*models.Property, error) {
 	var property models.Property
 	err := s.collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&property)
@@ -73,9 +351,88 @@ func (s *PropertyService) UpdateProperty(id primitive.ObjectID, updates bson.M)
 		bson.M{"_id": id},
 		bson.M{"$set": updates},
 	)
+	if err != nil {
+		return err
+	}
+
+	if touchesFields(updates, searchTextFields) {
+		return s.refreshSearchTerms(id)
+	}
+	return nil
+}
+
+// BackfillSearchTerms computes search terms for listings written before they
+// existed.
+func (s *PropertyService) BackfillSearchTerms() error {
+	cursor, err := s.collection.Find(context.Background(), bson.M{"search_terms": bson.M{"$exists": false}})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(context.Background()) {
+		var property models.Property
+		if err := cursor.Decode(&property); err != nil {
+			return err
+		}
+		if err := s.setSearchTerms(&property); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+func (s *PropertyService) refreshSearchTerms(id primitive.ObjectID) error {
+	property, err := s.GetPropertyByID(id)
+	if err != nil {
+		return err
+	}
+	return s.setSearchTerms(property)
+}
+
+func (s *PropertyService) setSearchTerms(property *models.Property) error {
+	terms := propertySearchTerms(property)
+	if terms == nil {
+		terms = []string{}
+	}
+	_, err := s.collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": property.ID},
+		bson.M{"$set": bson.M{"search_terms": terms}},
+	)
 	return err
 }
 
+func propertySearchTerms(property *models.Property) []string {
+	texts := []string{
+		property.Title,
+		property.Description,
+		property.Address.Street,
+		property.Address.City,
+		property.Address.State,
+		property.Address.Country,
+	}
+	texts = append(texts, property.Tags...)
+	texts = append(texts, property.Amenities...)
+	return textsearch.IndexTerms(texts...)
+}
+
+// NeedsReview reports whether updates change content a reviewer approved.
+func NeedsReview(updates bson.M) bool {
+	return touchesFields(updates, reviewedFields)
+}
+
+func touchesFields(updates bson.M, fields []string) bool {
+	for key := range updates {
+		for _, field := range fields {
+			if key == field || strings.HasPrefix(key, field+".") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (s *PropertyService) DeleteProperty(id primitive.ObjectID) error {
 	_, err := s.collection.DeleteOne(context.Background(), bson.M{"_id": id})
 	return err
@@ -84,3 +441,139 @@ func (s *PropertyService) DeleteProperty(id primitive.ObjectID) error {
 func (s *PropertyService) GetPropertiesByOwner(ownerID primitive.ObjectID) ([]*models.Property, error) {
 	return s.GetProperties(bson.M{"owner_id": ownerID}, 100, 0)
 }
+
+// ChangeStatus moves a listing to another state if actor is allowed to. The
+// update only applies if the status has not changed since property was read,
+// otherwise ErrPropertyStatusChanged is returned. Available follows the
+// status and PublishedAt records the latest publication.
+func (s *PropertyService) ChangeStatus(property *models.Property, to string, actor listing.Actor, changedBy primitive.ObjectID, reason string) error {
+	if !listing.CanTransition(actor, property.Status, to) {
+		return ErrInvalidStatusTransition
+	}
+
+	now := time.Now()
+	change := models.StatusChange{
+		From:      property.Status,
+		To:        to,
+		ChangedBy: changedBy,
+		Reason:    reason,
+		ChangedAt: now,
+	}
+	set := bson.M{
+		"status":     to,
+		"available":  listing.Public(to),
+		"updated_at": now,
+	}
+	if to == listing.Published {
+		set["published_at"] = now
+		if property.FirstPublishedAt == nil {
+			set["first_published_at"] = now
+		}
+	}
+
+	result, err := s.collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": property.ID, "status": property.Status},
+		bson.M{"$set": set, "$push": bson.M{"status_history": change}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrPropertyStatusChanged
+	}
+
+	property.Status = to
+	property.Available = listing.Public(to)
+	property.StatusHistory = append(property.StatusHistory, change)
+	property.UpdatedAt = now
+	if to == listing.Published {
+		property.PublishedAt = &now
+		if property.FirstPublishedAt == nil {
+			property.FirstPublishedAt = &now
+		}
+	}
+	return nil
+}
+
+// ResubmitForReview moves a published or paused listing back to pending
+// review because its owner changed reviewed content. Listings in any other
+// state are not public and are left as they are.
+func (s *PropertyService) ResubmitForReview(property *models.Property) error {
+	if !listing.CanTransition(listing.System, property.Status, listing.PendingReview) {
+		return nil
+	}
+	return s.ChangeStatus(property, listing.PendingReview, listing.System, property.OwnerID, "Listing content changed")
+}
+
+// ArchivePropertiesByOwner archives every listing of an owner that is not
+// archived yet.
+func (s *PropertyService) ArchivePropertiesByOwner(ownerID, changedBy primitive.ObjectID, reason string) error {
+	now := time.Now()
+	// A pipeline update, so that each history entry can record the status
+	// the listing had before.
+	_, err := s.collection.UpdateMany(
+		context.Background(),
+		bson.M{"owner_id": ownerID, "status": bson.M{"$ne": listing.Archived}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"status_history": bson.M{"$concatArrays": bson.A{
+				bson.M{"$ifNull": bson.A{"$status_history", bson.A{}}},
+				bson.A{bson.M{
+					"from":       "$status",
+					"to":         listing.Archived,
+					"changed_by": changedBy,
+					"reason":     reason,
+					"changed_at": now,
+				}},
+			}},
+			"status":     listing.Archived,
+			"available":  false,
+			"updated_at": now,
+		}}}},
+	)
+	return err
+}
+
+// BackfillStatus moves listings written before the listing lifecycle existed
+// into it: those that were available become published, the rest paused. It
+// then sets first_published_at on published listings that lack it from the
+// earliest publish in their history, or from published_at.
+func (s *PropertyService) BackfillStatus() error {
+	legacy := bson.M{"status": bson.M{"$nin": listing.States}}
+	for _, backfill := range []struct {
+		available interface{}
+		set       bson.M
+	}{
+		{true, bson.M{"status": listing.Published, "published_at": "$created_at", "first_published_at": "$created_at"}},
+		{bson.M{"$ne": true}, bson.M{"status": listing.Paused}},
+	} {
+		_, err := s.collection.UpdateMany(
+			context.Background(),
+			bson.M{"$and": bson.A{legacy, bson.M{"available": backfill.available}}},
+			mongo.Pipeline{{{Key: "$set", Value: backfill.set}}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	publishes := bson.M{"$filter": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$status_history", bson.A{}}},
+		"cond":  bson.M{"$eq": bson.A{"$$this.to", listing.Published}},
+	}}
+	_, err := s.collection.UpdateMany(
+		context.Background(),
+		bson.M{"first_published_at": nil, "published_at": bson.M{"$ne": nil}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"first_published_at": bson.M{"$ifNull": bson.A{
+				bson.M{"$min": bson.M{"$map": bson.M{"input": publishes, "in": "$$this.changed_at"}}},
+				"$published_at",
+			}},
+		}}}},
+	)
+	return err
+}
+
+func (s *PropertyService) CountProperties(filter bson.M) (int64, error) {
+	return s.collection.CountDocuments(context.Background(), filter)
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"rent-help-backend/internal/models"
+	"rent-help-backend/pkg/listing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	publicReviewLimit  = 5
+	responseRateWindow = 90 * 24 * time.Hour
+)
+
+// PublicProfileService builds the profile other users see, combining the
+// user's own fields with their reviews, listings and messaging activity.
+type PublicProfileService struct {
+	users      *mongo.Collection
+	properties *mongo.Collection
+	bookings   *mongo.Collection
+	messages   *mongo.Collection
+}
+
+func NewPublicProfileService(db *mongo.Database) *PublicProfileService {
+	return &PublicProfileService{
+		users:      db.Collection("users"),
+		properties: db.Collection("properties"),
+		bookings:   db.Collection("bookings"),
+		messages:   db.Collection("messages"),
+	}
+}
+
+// GetPublicProfile returns mongo.ErrNoDocuments for unknown, deactivated and
+// deleted users.
+func (s *PublicProfileService) GetPublicProfile(userID primitive.ObjectID) (*models.PublicProfile, error) {
+	var user models.User
+	err := s.users.FindOne(context.Background(), bson.M{"_id": userID, "is_active": true}).Decode(&user)
+	if err != nil {
+		return nil, err
+	}
+
+	reviews, err := s.recentReviews(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	listings, err := s.properties.CountDocuments(context.Background(), bson.M{"owner_id": userID, "status": listing.Published})
+	if err != nil {
+		return nil, err
+	}
+
+	responseRate, err := s.responseRate(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PublicProfile{
+		ID:             user.ID,
+		FullName:       user.FullName,
+		Avatar:         user.Avatar,
+		Role:           user.Role,
+		IsVerified:     user.IsVerified,
+		Bio:            user.Bio,
+		Occupation:     user.Occupation,
+		Languages:      user.Languages,
+		SocialLinks:    user.SocialLinks,
+		City:           user.Address.City,
+		Country:        user.Address.Country,
+		Rating:         user.Rating,
+		RecentReviews:  reviews,
+		ActiveListings: listings,
+		ResponseRate:   responseRate,
+		MemberSince:    user.CreatedAt,
+	}, nil
+}
+
+// recentReviews returns the latest public reviews written about the user.
+// Reviews are embedded in bookings, one per side of the stay, and only
+// completed stays count. A side nobody has reviewed yet is stored as null, so
+// reviews are filtered before they become the root document.
+func (s *PublicProfileService) recentReviews(userID primitive.ObjectID) ([]models.PublicReview, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"status": "completed",
+			"$or": bson.A{
+				bson.M{"reviews.tenant_review.reviewee_id": userID},
+				bson.M{"reviews.landlord_review.reviewee_id": userID},
+			},
+		}}},
+		{{Key: "$project", Value: bson.M{"review": bson.A{"$reviews.tenant_review", "$reviews.landlord_review"}}}},
+		{{Key: "$unwind", Value: "$review"}},
+		{{Key: "$match", Value: bson.M{"review.reviewee_id": userID, "review.is_public": true}}},
+		{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$review"}}},
+		{{Key: "$sort", Value: bson.M{"created_at": -1}}},
+		{{Key: "$limit", Value: publicReviewLimit}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "users",
+			"localField":   "reviewer_id",
+			"foreignField": "_id",
+			"as":           "reviewer",
+		}}},
+		{{Key: "$unwind", Value: bson.M{"path": "$reviewer", "preserveNullAndEmptyArrays": true}}},
+	}
+
+	reviews := []models.PublicReview{}
+	cursor, err := s.bookings.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(context.Background(), &reviews); err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+// responseRate is the share of people who messaged the user in the recent
+// window that the user also wrote back to. It is nil when nobody did.
+func (s *PublicProfileService) responseRate(userID primitive.ObjectID) (*float64, error) {
+	since := time.Now().Add(-responseRateWindow)
+
+	senders, err := s.messages.Distinct(context.Background(), "sender_id", bson.M{
+		"receiver_id": userID,
+		"created_at":  bson.M{"$gte": since},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(senders) == 0 {
+		return nil, nil
+	}
+
+	answered, err := s.messages.Distinct(context.Background(), "receiver_id", bson.M{
+		"sender_id":   userID,
+		"receiver_id": bson.M{"$in": senders},
+		"created_at":  bson.M{"$gte": since},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rate := float64(len(answered)) / float64(len(senders))
+	return &rate, nil
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"rent-help-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MaxSavedSearches is how many searches a user can save.
+const MaxSavedSearches = 20
+
+var (
+	ErrSavedSearchNotFound  = errors.New("saved search not found")
+	ErrTooManySavedSearches = fmt.Errorf("a user can save at most %d searches", MaxSavedSearches)
+)
+
+// SavedSearchService stores users' named listing searches. The alert matcher
+// in SearchAlertService advances LastCheckedAt as it works through them.
+type SavedSearchService struct {
+	collection *mongo.Collection
+}
+
+func NewSavedSearchService(db *mongo.Database) *SavedSearchService {
+	return &SavedSearchService{
+		collection: db.Collection("saved_searches"),
+	}
+}
+
+func (s *SavedSearchService) EnsureIndexes() error {
+	_, err := s.collection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "alerts", Value: 1}, {Key: "last_checked_at", Value: 1}}},
+	})
+	return err
+}
+
+// CreateSavedSearch stores a search. Alerts only cover listings published
+// from now on.
+func (s *SavedSearchService) CreateSavedSearch(search *models.SavedSearch) error {
+	count, err := s.collection.CountDocuments(context.Background(), bson.M{"user_id": search.UserID})
+	if err != nil {
+		return err
+	}
+	if count >= MaxSavedSearches {
+		return ErrTooManySavedSearches
+	}
+
+	now := time.Now()
+	search.ID = primitive.NewObjectID()
+	search.LastCheckedAt = now
+	search.CreatedAt = now
+	search.UpdatedAt = now
+	_, err = s.collection.InsertOne(context.Background(), search)
+	return err
+}
+
+// ListSavedSearches returns all searches of a user, newest first.
+func (s *SavedSearchService) ListSavedSearches(userID primitive.ObjectID) ([]*models.SavedSearch, error) {
+	searches := []*models.SavedSearch{}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := s.collection.Find(context.Background(), bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(context.Background(), &searches); err != nil {
+		return nil, err
+	}
+	return searches, nil
+}
+
+// UpdateSavedSearch renames a search or turns its alerts on or off. Turning
+// alerts back on starts them from now, so the listings published while they
+// were off are not sent all at once.
+func (s *SavedSearchService) UpdateSavedSearch(userID, id primitive.ObjectID, req *models.UpdateSavedSearchRequest) (*models.SavedSearch, error) {
+	now := time.Now()
+	set := bson.M{"updated_at": now}
+	if req.Name != nil {
+		// This is a pipeline update, where a name starting with $ would be
+		// read as a field path.
+		set["name"] = bson.M{"$literal": *req.Name}
+	}
+	if req.Alerts != nil {
+		set["alerts"] = *req.Alerts
+		if *req.Alerts {
+			set["last_checked_at"] = bson.M{"$cond": bson.A{"$alerts", "$last_checked_at", now}}
+		}
+	}
+
+	search := &models.SavedSearch{}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := s.collection.FindOneAndUpdate(
+		context.Background(),
+		bson.M{"_id": id, "user_id": userID},
+		mongo.Pipeline{{{Key: "$set", Value: set}}},
+		opts,
+	).Decode(search)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrSavedSearchNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return search, nil
+}
+
+func (s *SavedSearchService) DeleteSavedSearch(userID, id primitive.ObjectID) error {
+	result, err := s.collection.DeleteOne(context.Background(), bson.M{"_id": id, "user_id": userID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrSavedSearchNotFound
+	}
+	return nil
+}
+
+// DeleteForUser removes all saved searches of a user.
+func (s *SavedSearchService) DeleteForUser(userID primitive.ObjectID) error {
+	_, err := s.collection.DeleteMany(context.Background(), bson.M{"user_id": userID})
+	return err
+}
+
+// dueSearches returns the searches with alerts that were last checked before
+// until.
+func (s *SavedSearchService) dueSearches(until time.Time) ([]*models.SavedSearch, error) {
+	var searches []*models.SavedSearch
+	err := findAll(s.collection, bson.M{"alerts": true, "last_checked_at": bson.M{"$lt": until}}, &searches)
+	return searches, err
+}
+
+// claim advances the search's LastCheckedAt to until. It reports false when
+// another matcher advanced it first, so every window is checked only once.
+func (s *SavedSearchService) claim(search *models.SavedSearch, until time.Time) (bool, error) {
+	result, err := s.collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": search.ID, "alerts": true, "last_checked_at": search.LastCheckedAt},
+		bson.M{"$set": bson.M{"last_checked_at": until}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (s *SavedSearchService) markMatched(id primitive.ObjectID, at time.Time) error {
+	_, err := s.collection.UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": bson.M{"last_matched_at": at}})
+	return err
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"rent-help-backend/internal/config"
+	"rent-help-backend/internal/models"
+	"rent-help-backend/pkg/geo"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// alertSettleDelay leaves the listings published in the last moments to
+	// the next pass, so that a publish that is still being written when the
+	// window closes is not skipped.
+	alertSettleDelay = 30 * time.Second
+	// maxAlertListings is how many listings a single alert names.
+	maxAlertListings = 10
+)
+
+// SearchCriteria is a listing search in the form PropertyService takes it.
+// Query is in textsearch.Query form.
+type SearchCriteria struct {
+	Filter   bson.M
+	Near     *geo.Point
+	RadiusKm float64
+	Query    string
+}
+
+// SearchParser turns the query of a saved search into SearchCriteria, the
+// same way GET /properties reads its parameters.
+type SearchParser func(query string) (*SearchCriteria, error)
+
+// SearchAlertService tells users about newly published listings that match
+// their saved searches.
+//
+// Each pass looks at the listings first published since a search was last
+// checked and advances the search's LastCheckedAt before notifying, so a listing is
+// sent at most once per search even with several servers running.
+type SearchAlertService struct {
+	savedSearchService  *SavedSearchService
+	propertyService     *PropertyService
+	userService         *UserService
+	notificationService *NotificationService
+	parse               SearchParser
+	appURL              string
+}
+
+func NewSearchAlertService(savedSearchService *SavedSearchService, propertyService *PropertyService, userService *UserService, notificationService *NotificationService, parse SearchParser, cfg *config.Config) *SearchAlertService {
+	return &SearchAlertService{
+		savedSearchService:  savedSearchService,
+		propertyService:     propertyService,
+		userService:         userService,
+		notificationService: notificationService,
+		parse:               parse,
+		appURL:              strings.TrimSuffix(cfg.AppURL, "/"),
+	}
+}
+
+// Run matches the saved searches every interval until ctx is cancelled.
+func (s *SearchAlertService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.MatchNewListings(); err != nil {
+				log.Printf("Failed to match saved searches: %v", err)
+			}
+		}
+	}
+}
+
+// MatchNewListings runs one pass over the saved searches with alerts.
+// Failing to notify one user is logged and does not stop the pass.
+func (s *SearchAlertService) MatchNewListings() error {
+	until := time.Now().Add(-alertSettleDelay)
+	searches, err := s.savedSearchService.dueSearches(until)
+	if err != nil || len(searches) == 0 {
+		return err
+	}
+
+	since := searches[0].LastCheckedAt
+	for _, search := range searches {
+		if search.LastCheckedAt.Before(since) {
+			since = search.LastCheckedAt
+		}
+	}
+	published, err := s.propertyService.PublishedIDs(since, until)
+	if err != nil {
+		return err
+	}
+
+	for _, search := range searches {
+		claimed, err := s.savedSearchService.claim(search, until)
+		if err != nil {
+			return err
+		}
+		if !claimed || len(published) == 0 {
+			continue
+		}
+		if err := s.alert(search, published, until); err != nil {
+			log.Printf("Failed to send alert for saved search %s: %v", search.ID.Hex(), err)
+		}
+	}
+	return nil
+}
+
+// alert notifies the owner of search about the listings among published that
+// match it and were first published within the window the search was
+// claimed for.
+// The user's own listings are left out.
+func (s *SearchAlertService) alert(search *models.SavedSearch, published []primitive.ObjectID, until time.Time) error {
+	criteria, err := s.parse(search.Query)
+	if err != nil {
+		return err
+	}
+
+	matches, err := s.propertyService.MatchProperties(criteria, bson.M{
+		"_id":                bson.M{"$in": published},
+		"owner_id":           bson.M{"$ne": search.UserID},
+		"first_published_at": bson.M{"$gt": search.LastCheckedAt, "$lte": until},
+	}, maxAlertListings+1)
+	if err != nil || len(matches) == 0 {
+		return err
+	}
+
+	user, err := s.userService.GetUserByID(search.UserID)
+	if err != nil {
+		return err
+	}
+	if !user.IsActive || user.DeletedAt != nil {
+		return nil
+	}
+
+	if err := s.savedSearchService.markMatched(search.ID, time.Now()); err != nil {
+		return err
+	}
+	return s.notificationService.Notify(user, s.notification(search, matches))
+}
+
+func (s *SearchAlertService) notification(search *models.SavedSearch, matches []*models.Property) *models.Notification {
+	more := len(matches) > maxAlertListings
+	if more {
+		matches = matches[:maxAlertListings]
+	}
+
+	ids := make([]primitive.ObjectID, len(matches))
+	var content strings.Builder
+	for i, property := range matches {
+		ids[i] = property.ID
+		fmt.Fprintf(&content, "- %s, %s: %.2f %s\n", property.Title, property.Address.City, property.Price, property.Currency)
+	}
+	if more {
+		content.WriteString("- and more\n")
+	}
+
+	title := fmt.Sprintf("New listings for your search %q", search.Name)
+	actionURL := s.appURL + "/properties?" + search.Query
+	if len(matches) == 1 {
+		title = fmt.Sprintf("New listing for your search %q", search.Name)
+		actionURL = s.appURL + "/properties/" + matches[0].ID.Hex()
+	}
+
+	return &models.Notification{
+		Type:      "search_alert",
+		Title:     title,
+		Content:   strings.TrimSuffix(content.String(), "\n"),
+		Data:      bson.M{"saved_search_id": search.ID, "property_ids": ids},
+		ActionURL: actionURL,
+		Priority:  "low",
+	}
+}
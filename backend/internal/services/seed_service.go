@@ -11,6 +11,7 @@ import (
 	"golang.org/x/crypto/bcrypt"
 
 	"rent-help-backend/internal/models"
+	"rent-help-backend/pkg/listing"
 )
 
 type SeedService struct {
@@ -162,7 +163,7 @@ func (s *SeedService) seedProperties(ctx context.Context) error {
 				ZipCode: "90210",
 				Country: "USA",
 			},
-			Location: models.GeoLocation{
+			Location: &models.GeoLocation{
 				Type:        "Point",
 				Coordinates: []float64{-118.2437, 34.0522},
 			},
@@ -173,6 +174,7 @@ func (s *SeedService) seedProperties(ctx context.Context) error {
 			},
 			OwnerID:           landlords[0].ID,
 			Available:         true,
+			Status:            listing.Published,
 			AvailableFrom:     time.Now(),
 			LeaseTerms:        []string{"6_months", "12_months"},
 			PetsAllowed:       false,
@@ -197,7 +199,7 @@ func (s *SeedService) seedProperties(ctx context.Context) error {
 				ZipCode: "90211",
 				Country: "USA",
 			},
-			Location: models.GeoLocation{
+			Location: &models.GeoLocation{
 				Type:        "Point",
 				Coordinates: []float64{-118.2537, 34.0622},
 			},
@@ -208,6 +210,7 @@ func (s *SeedService) seedProperties(ctx context.Context) error {
 			},
 			OwnerID:           landlords[1].ID,
 			Available:         true,
+			Status:            listing.Published,
 			AvailableFrom:     time.Now(),
 			LeaseTerms:        []string{"3_months", "6_months", "12_months"},
 			PetsAllowed:       false,
@@ -232,7 +235,7 @@ func (s *SeedService) seedProperties(ctx context.Context) error {
 				ZipCode: "90212",
 				Country: "USA",
 			},
-			Location: models.GeoLocation{
+			Location: &models.GeoLocation{
 				Type:        "Point",
 				Coordinates: []float64{-118.2637, 34.0722},
 			},
@@ -243,6 +246,7 @@ func (s *SeedService) seedProperties(ctx context.Context) error {
 			},
 			OwnerID:           landlords[0].ID,
 			Available:         true,
+			Status:            listing.Published,
 			AvailableFrom:     time.Now().AddDate(0, 0, 30), // Available in 30 days
 			LeaseTerms:        []string{"12_months", "24_months"},
 			PetsAllowed:       true,
@@ -267,7 +271,7 @@ func (s *SeedService) seedProperties(ctx context.Context) error {
 				ZipCode: "90213",
 				Country: "USA",
 			},
-			Location: models.GeoLocation{
+			Location: &models.GeoLocation{
 				Type:        "Point",
 				Coordinates: []float64{-118.2337, 34.0422},
 			},
@@ -278,6 +282,7 @@ func (s *SeedService) seedProperties(ctx context.Context) error {
 			},
 			OwnerID:           landlords[1].ID,
 			Available:         true,
+			Status:            listing.Published,
 			AvailableFrom:     time.Now(),
 			LeaseTerms:        []string{"12_months", "24_months"},
 			PetsAllowed:       false,
@@ -302,7 +307,7 @@ func (s *SeedService) seedProperties(ctx context.Context) error {
 				ZipCode: "90214",
 				Country: "USA",
 			},
-			Location: models.GeoLocation{
+			Location: &models.GeoLocation{
 				Type:        "Point",
 				Coordinates: []float64{-118.2737, 34.0822},
 			},
@@ -313,6 +318,7 @@ func (s *SeedService) seedProperties(ctx context.Context) error {
 			},
 			OwnerID:           landlords[0].ID,
 			Available:         true,
+			Status:            listing.Published,
 			AvailableFrom:     time.Now().AddDate(0, 0, 15), // Available in 15 days
 			LeaseTerms:        []string{"6_months", "12_months", "24_months"},
 			PetsAllowed:       true,
@@ -337,7 +343,7 @@ func (s *SeedService) seedProperties(ctx context.Context) error {
 				ZipCode: "90215",
 				Country: "USA",
 			},
-			Location: models.GeoLocation{
+			Location: &models.GeoLocation{
 				Type:        "Point",
 				Coordinates: []float64{-118.2837, 34.0922},
 			},
@@ -348,6 +354,7 @@ func (s *SeedService) seedProperties(ctx context.Context) error {
 			},
 			OwnerID:           landlords[1].ID,
 			Available:         true,
+			Status:            listing.Published,
 			AvailableFrom:     time.Now(),
 			LeaseTerms:        []string{"6_months", "12_months"},
 			PetsAllowed:       true,
@@ -372,7 +379,7 @@ func (s *SeedService) seedProperties(ctx context.Context) error {
 				ZipCode: "90216",
 				Country: "USA",
 			},
-			Location: models.GeoLocation{
+			Location: &models.GeoLocation{
 				Type:        "Point",
 				Coordinates: []float64{-118.2937, 34.1022},
 			},
@@ -383,6 +390,7 @@ func (s *SeedService) seedProperties(ctx context.Context) error {
 			},
 			OwnerID:           landlords[0].ID,
 			Available:         true,
+			Status:            listing.Published,
 			AvailableFrom:     time.Now(),
 			LeaseTerms:        []string{"1_month", "3_months", "6_months"},
 			PetsAllowed:       false,
@@ -0,0 +1,357 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"rent-help-backend/internal/config"
+	"rent-help-backend/internal/models"
+	"rent-help-backend/pkg/useragent"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// sessionTouchInterval is how stale a session's last_seen_at may get before
+// a request updates it.
+const sessionTouchInterval = time.Minute
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrSessionNotFound     = errors.New("session not found")
+)
+
+// TokenService issues access and refresh tokens. Every login starts a refresh
+// token family, which is also the user's session on that device.
+type TokenService struct {
+	collection      *mongo.Collection
+	revokedTokens   *mongo.Collection
+	sessions        *mongo.Collection
+	secret          []byte
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+}
+
+func NewTokenService(db *mongo.Database, cfg *config.Config) *TokenService {
+	return &TokenService{
+		collection:      db.Collection("refresh_tokens"),
+		revokedTokens:   db.Collection("revoked_tokens"),
+		sessions:        db.Collection("sessions"),
+		secret:          []byte(cfg.JWTSecret),
+		accessTokenTTL:  cfg.AccessTokenTTL,
+		refreshTokenTTL: cfg.RefreshTokenTTL,
+	}
+}
+
+// EnsureIndexes creates the lookup indexes and lets MongoDB drop refresh
+// tokens and denylist entries once they have expired.
+func (s *TokenService) EnsureIndexes() error {
+	_, err := s.collection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "family_id", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.revokedTokens.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "jti", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.sessions.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "last_seen_at", Value: -1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	return err
+}
+
+// GenerateAccessToken signs a short-lived HS256 access token for the user.
+// The token carries a unique jti so it can be revoked, and the id of the
+// refresh token family (sid) it was issued with.
+func (s *TokenService) GenerateAccessToken(user *models.User, sessionID primitive.ObjectID) (string, error) {
+	jti, err := generateRandomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": user.ID.Hex(),
+		"email":   user.Email,
+		"role":    user.Role,
+		"sid":     sessionID.Hex(),
+		"jti":     jti,
+		"iat":     now.Unix(),
+		"exp":     now.Add(s.accessTokenTTL).Unix(),
+	})
+	return token.SignedString(s.secret)
+}
+
+// RevokeAccessToken adds an access token to the denylist until it expires.
+func (s *TokenService) RevokeAccessToken(jti string, userID primitive.ObjectID, expiresAt time.Time) error {
+	_, err := s.revokedTokens.UpdateOne(
+		context.Background(),
+		bson.M{"jti": jti},
+		bson.M{"$setOnInsert": models.RevokedToken{
+			JTI:       jti,
+			UserID:    userID,
+			ExpiresAt: expiresAt,
+			RevokedAt: time.Now(),
+		}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// IsAccessTokenRevoked reports whether the access token is on the denylist.
+func (s *TokenService) IsAccessTokenRevoked(jti string) (bool, error) {
+	count, err := s.revokedTokens.CountDocuments(context.Background(), bson.M{"jti": jti}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// IssueTokens starts a new session for the user and returns its first token
+// pair.
+func (s *TokenService) IssueTokens(user *models.User, client models.SessionClient) (*models.TokenResponse, error) {
+	now := time.Now()
+	device := useragent.Parse(client.UserAgent)
+	session := models.Session{
+		ID:          primitive.NewObjectID(),
+		UserID:      user.ID,
+		Device:      device.String(),
+		DeviceType:  device.DeviceType,
+		UserAgent:   client.UserAgent,
+		IP:          client.IP,
+		LoginMethod: client.LoginMethod,
+		CreatedAt:   now,
+		LastSeenAt:  now,
+		ExpiresAt:   now.Add(s.refreshTokenTTL),
+	}
+	if _, err := s.sessions.InsertOne(context.Background(), session); err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := s.createRefreshToken(user.ID, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.tokenResponse(user, session.ID, refreshToken)
+}
+
+// RotateTokens issues a new token pair in an existing family. It is called
+// after ConsumeRefreshToken has accepted the previous token of that family.
+func (s *TokenService) RotateTokens(user *models.User, familyID primitive.ObjectID) (*models.TokenResponse, error) {
+	refreshToken, err := s.createRefreshToken(user.ID, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.sessions.UpdateOne(
+		context.Background(),
+		bson.M{"_id": familyID},
+		bson.M{"$set": bson.M{"expires_at": time.Now().Add(s.refreshTokenTTL)}},
+	); err != nil {
+		return nil, err
+	}
+	return s.tokenResponse(user, familyID, refreshToken)
+}
+
+// RevokeFamily revokes every token that was rotated from the same login and
+// ends the session.
+func (s *TokenService) RevokeFamily(familyID primitive.ObjectID) error {
+	now := time.Now()
+	if _, err := s.collection.UpdateMany(
+		context.Background(),
+		bson.M{"family_id": familyID, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": now}},
+	); err != nil {
+		return err
+	}
+
+	_, err := s.sessions.UpdateOne(
+		context.Background(),
+		bson.M{"_id": familyID, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": now}},
+	)
+	return err
+}
+
+// RevokeAllForUser revokes every refresh token the user holds and ends all
+// of their sessions.
+func (s *TokenService) RevokeAllForUser(userID primitive.ObjectID) error {
+	now := time.Now()
+	if _, err := s.collection.UpdateMany(
+		context.Background(),
+		bson.M{"user_id": userID, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": now}},
+	); err != nil {
+		return err
+	}
+
+	_, err := s.sessions.UpdateMany(
+		context.Background(),
+		bson.M{"user_id": userID, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": now}},
+	)
+	return err
+}
+
+// TouchSession records that the session was just used from ip. It returns
+// false when the session has been revoked or has expired, in which case its
+// access tokens must no longer be accepted. The check is a read; last_seen_at
+// and ip are only written once they are sessionTouchInterval old, so busy
+// clients do not write on every request.
+func (s *TokenService) TouchSession(sessionID primitive.ObjectID, ip string) (bool, error) {
+	now := time.Now()
+	active, err := s.sessions.CountDocuments(
+		context.Background(),
+		bson.M{"_id": sessionID, "revoked_at": nil, "expires_at": bson.M{"$gt": now}},
+		options.Count().SetLimit(1),
+	)
+	if err != nil || active == 0 {
+		return false, err
+	}
+
+	_, err = s.sessions.UpdateOne(
+		context.Background(),
+		bson.M{"_id": sessionID, "last_seen_at": bson.M{"$lt": now.Add(-sessionTouchInterval)}},
+		bson.M{"$set": bson.M{"last_seen_at": now, "ip": ip}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetSessions returns the user's active sessions, most recently used first.
+func (s *TokenService) GetSessions(userID primitive.ObjectID) ([]*models.Session, error) {
+	sessions := []*models.Session{}
+	cursor, err := s.sessions.Find(
+		context.Background(),
+		bson.M{"user_id": userID, "revoked_at": nil, "expires_at": bson.M{"$gt": time.Now()}},
+		options.Find().SetSort(bson.D{{Key: "last_seen_at", Value: -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(context.Background(), &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// RevokeSession ends one of the user's sessions.
+func (s *TokenService) RevokeSession(userID, sessionID primitive.ObjectID) error {
+	count, err := s.sessions.CountDocuments(context.Background(), bson.M{"_id": sessionID, "user_id": userID, "revoked_at": nil})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrSessionNotFound
+	}
+	return s.RevokeFamily(sessionID)
+}
+
+// ConsumeRefreshToken marks a refresh token as used and returns its record.
+// Presenting a token that was already used revokes its whole family and
+// returns ErrRefreshTokenReused.
+func (s *TokenService) ConsumeRefreshToken(rawToken string) (*models.RefreshToken, error) {
+	now := time.Now()
+	tokenHash := hashToken(rawToken)
+
+	var current models.RefreshToken
+	err := s.collection.FindOneAndUpdate(
+		context.Background(),
+		bson.M{
+			"token_hash": tokenHash,
+			"used_at":    nil,
+			"revoked_at": nil,
+			"expires_at": bson.M{"$gt": now},
+		},
+		bson.M{"$set": bson.M{"used_at": now}},
+	).Decode(&current)
+	if err == nil {
+		return &current, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	// The token is unknown, expired, revoked or already used. A used token
+	// being presented again means it has leaked, so kill the whole family.
+	var previous models.RefreshToken
+	if err := s.collection.FindOne(context.Background(), bson.M{"token_hash": tokenHash}).Decode(&previous); err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if previous.UsedAt != nil {
+		if err := s.RevokeFamily(previous.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	return nil, ErrInvalidRefreshToken
+}
+
+func (s *TokenService) createRefreshToken(userID, familyID primitive.ObjectID) (string, error) {
+	rawToken, err := generateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	record := models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(rawToken),
+		ExpiresAt: now.Add(s.refreshTokenTTL),
+		CreatedAt: now,
+	}
+	if _, err := s.collection.InsertOne(context.Background(), record); err != nil {
+		return "", err
+	}
+	return rawToken, nil
+}
+
+func (s *TokenService) tokenResponse(user *models.User, familyID primitive.ObjectID, refreshToken string) (*models.TokenResponse, error) {
+	accessToken, err := s.GenerateAccessToken(user, familyID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.accessTokenTTL.Seconds()),
+	}, nil
+}
+
+// generateRandomToken returns n random bytes encoded as URL-safe base64.
+func generateRandomToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashToken returns the hex encoded SHA-256 digest stored in place of a token.
+func hashToken(rawToken string) string {
+	sum := sha256.Sum256([]byte(rawToken))
+	return hex.EncodeToString(sum[:])
+}
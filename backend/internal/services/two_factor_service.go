@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"rent-help-backend/internal/config"
+	"rent-help-backend/internal/models"
+	"rent-help-backend/pkg/totp"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	twoFactorIssuer        = "RentHelp"
+	twoFactorChallengeTTL  = 5 * time.Minute
+	twoFactorChallengeType = "2fa_challenge"
+	recoveryCodeCount      = 10
+	// Accept codes from one step before or after the current one.
+	twoFactorSkew = 1
+)
+
+var (
+	ErrTwoFactorNotPending     = errors.New("two-factor setup has not been started")
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor code")
+	ErrInvalidChallengeToken   = errors.New("invalid or expired two-factor challenge")
+)
+
+// TwoFactorSetup is returned when enrolment starts
+type TwoFactorSetup struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// TwoFactorService implements RFC 6238 TOTP enrolment, hashed recovery
+// codes and the second step of the login challenge.
+type TwoFactorService struct {
+	collection *mongo.Collection
+	secret     []byte
+}
+
+func NewTwoFactorService(db *mongo.Database, cfg *config.Config) *TwoFactorService {
+	return &TwoFactorService{
+		collection: db.Collection("users"),
+		secret:     []byte(cfg.JWTSecret),
+	}
+}
+
+// BeginSetup generates a new secret for the user. It is stored but not
+// enforced until ConfirmSetup accepts a code generated from it.
+func (s *TwoFactorService) BeginSetup(user *models.User) (*TwoFactorSetup, error) {
+	if user.TwoFactor.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.update(user.ID, bson.M{"$set": bson.M{
+		"two_factor": models.TwoFactorSettings{Secret: secret},
+		"updated_at": time.Now(),
+	}}); err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorSetup{
+		Secret:     secret,
+		OTPAuthURI: totp.KeyURI(twoFactorIssuer, user.Email, secret, totp.DefaultOptions()),
+	}, nil
+}
+
+// ConfirmSetup enables 2FA once the user proves their authenticator works and
+// returns the recovery codes. The codes are only stored hashed, so this is
+// the only time they can be shown.
+func (s *TwoFactorService) ConfirmSetup(user *models.User, code string) ([]string, error) {
+	if user.TwoFactor.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TwoFactor.Secret == "" {
+		return nil, ErrTwoFactorNotPending
+	}
+
+	step, ok := s.validateCode(user.TwoFactor.Secret, code)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := s.update(user.ID, bson.M{"$set": bson.M{
+		"two_factor.enabled":        true,
+		"two_factor.recovery_codes": hashes,
+		"two_factor.last_used_step": step,
+		"two_factor.enabled_at":     now,
+		"updated_at":                now,
+	}}); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable turns 2FA off after checking a current code or recovery code.
+func (s *TwoFactorService) Disable(user *models.User, code string) error {
+	if !user.TwoFactor.Enabled {
+		return ErrTwoFactorNotEnabled
+	}
+	if err := s.VerifyCode(user, code); err != nil {
+		return err
+	}
+
+	return s.update(user.ID, bson.M{
+		"$unset": bson.M{"two_factor": ""},
+		"$set":   bson.M{"updated_at": time.Now()},
+	})
+}
+
+// VerifyCode accepts either a TOTP code or an unused recovery code. TOTP
+// codes can only be used once and recovery codes are removed when used.
+func (s *TwoFactorService) VerifyCode(user *models.User, code string) error {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+
+	if step, ok := s.validateCode(user.TwoFactor.Secret, code); ok {
+		result, err := s.collection.UpdateOne(
+			context.Background(),
+			bson.M{
+				"_id": user.ID,
+				"$or": bson.A{
+					bson.M{"two_factor.last_used_step": bson.M{"$lt": step}},
+					bson.M{"two_factor.last_used_step": bson.M{"$exists": false}},
+				},
+			},
+			bson.M{"$set": bson.M{"two_factor.last_used_step": step}},
+		)
+		if err != nil {
+			return err
+		}
+		if result.ModifiedCount == 0 {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	hash := hashToken(normalizeRecoveryCode(code))
+	result, err := s.collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": user.ID, "two_factor.recovery_codes": hash},
+		bson.M{"$pull": bson.M{"two_factor.recovery_codes": hash}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.ModifiedCount == 0 {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// CreateChallenge returns a short-lived token proving that the password step
+// of the login succeeded for the user.
+func (s *TwoFactorService) CreateChallenge(user *models.User) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":     user.ID.Hex(),
+		"purpose": twoFactorChallengeType,
+		"exp":     time.Now().Add(twoFactorChallengeTTL).Unix(),
+	})
+	return token.SignedString(s.secret)
+}
+
+// ParseChallenge returns the id of the user a challenge token was issued for.
+func (s *TwoFactorService) ParseChallenge(challengeToken string) (primitive.ObjectID, error) {
+	parsed, err := jwt.Parse(challengeToken, func(token *jwt.Token) (interface{}, error) {
+		return s.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !parsed.Valid {
+		return primitive.NilObjectID, ErrInvalidChallengeToken
+	}
+
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != twoFactorChallengeType {
+		return primitive.NilObjectID, ErrInvalidChallengeToken
+	}
+
+	subject, _ := claims.GetSubject()
+	userID, err := primitive.ObjectIDFromHex(subject)
+	if err != nil {
+		return primitive.NilObjectID, ErrInvalidChallengeToken
+	}
+	return userID, nil
+}
+
+func (s *TwoFactorService) validateCode(secret, code string) (uint64, bool) {
+	if secret == "" {
+		return 0, false
+	}
+	key, err := totp.DecodeSecret(secret)
+	if err != nil {
+		return 0, false
+	}
+	return totp.Validate(key, code, time.Now(), twoFactorSkew, totp.DefaultOptions())
+}
+
+func (s *TwoFactorService) update(userID primitive.ObjectID, update bson.M) error {
+	_, err := s.collection.UpdateOne(context.Background(), bson.M{"_id": userID}, update)
+	return err
+}
+
+// generateRecoveryCodes returns codes formatted as xxxxx-xxxxx together with
+// the hashes that are stored.
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(secret[:10])
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, hashToken(raw))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(code, "-", ""))
+}
@@ -2,9 +2,11 @@ package services
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"rent-help-backend/internal/models"
+	"rent-help-backend/pkg/pagination"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -12,6 +14,13 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// UserSorts are the sort names accepted by ListUsers.
+var UserSorts = []string{"created_at"}
+
+var userSortFields = map[string]sortField{
+	"created_at": {path: "created_at", isTime: true},
+}
+
 type UserService struct {
 	collection *mongo.Collection
 }
@@ -32,6 +41,7 @@ func (s *UserService) CreateUser(user *models.User) error {
 	user.CreatedAt = time.Now()
 	user.UpdatedAt = time.Now()
 	user.Verified = false
+	user.IsActive = true
 
 	result, err := s.collection.InsertOne(context.Background(), user)
 	if err != nil {
@@ -42,6 +52,36 @@ func (s *UserService) CreateUser(user *models.User) error {
 	return nil
 }
 
+// BackfillActive activates the accounts stored with is_active false because
+// registration did not set it. Deleted accounts and accounts an admin
+// deactivated, either marked by deactivated_at or found in the audit trail
+// from before that field existed, are left alone. It is safe to run on every
+// start.
+func (s *UserService) BackfillActive(auditService *AuditService) error {
+	targets, err := auditService.TargetIDs("admin.users.status")
+	if err != nil {
+		return err
+	}
+	excluded := make([]primitive.ObjectID, 0, len(targets))
+	for _, target := range targets {
+		if id, err := primitive.ObjectIDFromHex(target); err == nil {
+			excluded = append(excluded, id)
+		}
+	}
+
+	_, err = s.collection.UpdateMany(
+		context.Background(),
+		bson.M{
+			"is_active":      bson.M{"$ne": true},
+			"deleted_at":     nil,
+			"deactivated_at": nil,
+			"_id":            bson.M{"$nin": excluded},
+		},
+		bson.M{"$set": bson.M{"is_active": true, "updated_at": time.Now()}},
+	)
+	return err
+}
+
 func (s *UserService) GetUserByEmail(email string) (*models.User, error) {
 	var user models.User
 	err := s.collection.FindOne(context.Background(), bson.M{"email": email}).Decode(&user)
@@ -81,3 +121,79 @@ func (s *UserService) EmailExists(email string) (bool, error) {
 	}
 	return count > 0, nil
 }
+
+func (s *UserService) CountUsers(filter bson.M) (int64, error) {
+	return s.collection.CountDocuments(context.Background(), filter)
+}
+
+// ListUsers returns one page of the users matching filter.
+func (s *UserService) ListUsers(filter bson.M, page PageRequest) ([]*models.User, *string, error) {
+	field, ok := userSortFields[page.Sort.Field]
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported user sort %q", page.Sort.Field)
+	}
+	paging, err := page.stages(field)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	users := []*models.User{}
+	pipeline := append(mongo.Pipeline{{{Key: "$match", Value: filter}}}, paging...)
+	cursor, err := s.collection.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cursor.All(context.Background(), &users); err != nil {
+		return nil, nil, err
+	}
+
+	if int64(len(users)) <= page.Limit {
+		return users, nil, nil
+	}
+	users = users[:page.Limit]
+	last := users[len(users)-1]
+	return users, page.nextCursor(pagination.TimeValue(last.CreatedAt), last.ID), nil
+}
+
+// RecordLogin stores the time of the user's latest successful login.
+func (s *UserService) RecordLogin(id primitive.ObjectID) error {
+	return s.UpdateUser(id, bson.M{"last_login_at": time.Now()})
+}
+
+func (s *UserService) MarkEmailVerified(id primitive.ObjectID) error {
+	return s.UpdateUser(id, bson.M{"is_verified": true, "verified": true})
+}
+
+// UpdatePassword hashes and stores a new password and records when it changed.
+func (s *UserService) UpdatePassword(id primitive.ObjectID, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	return s.UpdateUser(id, bson.M{
+		"password":            string(hashedPassword),
+		"password_changed_at": time.Now(),
+		"password_unset":      false,
+	})
+}
+
+// LockPassword replaces the user's password with a random one that nobody
+// knows. The user can only sign in with a linked provider until they set a
+// new password through a reset.
+func (s *UserService) LockPassword(id primitive.ObjectID) error {
+	password, err := generateRandomToken(32)
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	return s.UpdateUser(id, bson.M{
+		"password":            string(hashedPassword),
+		"password_changed_at": time.Now(),
+		"password_unset":      true,
+	})
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"rent-help-backend/internal/config"
+	"rent-help-backend/internal/models"
+	"rent-help-backend/pkg/mailer"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	emailVerificationPurpose = "verify_email"
+	emailVerificationTTL     = 48 * time.Hour
+	accountDeletionPurpose   = "delete_account"
+	accountDeletionTTL       = 30 * time.Minute
+)
+
+var ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+
+// VerificationService issues signed email verification links and confirms
+// them. It also mails the confirmation for deleting an account without a
+// password. The tokens are stateless JWTs bound to the user's current email.
+type VerificationService struct {
+	userService *UserService
+	mailer      mailer.Mailer
+	secret      []byte
+	publicURL   string
+	appURL      string
+}
+
+func NewVerificationService(userService *UserService, m mailer.Mailer, cfg *config.Config) *VerificationService {
+	return &VerificationService{
+		userService: userService,
+		mailer:      m,
+		secret:      []byte(cfg.JWTSecret),
+		publicURL:   cfg.PublicURL,
+		appURL:      cfg.AppURL,
+	}
+}
+
+// SendVerificationEmail mails the user a link to GET /auth/verify.
+func (s *VerificationService) SendVerificationEmail(user *models.User) error {
+	token, err := s.generateToken(user, emailVerificationPurpose, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	link := s.publicURL + "/api/v1/auth/verify?token=" + url.QueryEscape(token)
+	return s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your RentHelp email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\nThe link expires in %d hours.\n",
+			user.FullName, link, int(emailVerificationTTL.Hours()),
+		),
+	})
+}
+
+// VerifyEmail validates the token and marks the user as verified.
+func (s *VerificationService) VerifyEmail(token string) (*models.User, error) {
+	user, err := s.parseToken(token, emailVerificationPurpose)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.IsVerified {
+		if err := s.userService.MarkEmailVerified(user.ID); err != nil {
+			return nil, err
+		}
+		user.IsVerified = true
+		user.Verified = true
+	}
+	return user, nil
+}
+
+// SendAccountDeletionEmail mails the user a token that confirms
+// DELETE /users/me in place of their password. Users who only sign in
+// through an identity provider never chose a password.
+func (s *VerificationService) SendAccountDeletionEmail(user *models.User) error {
+	token, err := s.generateToken(user, accountDeletionPurpose, accountDeletionTTL)
+	if err != nil {
+		return err
+	}
+
+	link := s.appURL + "/account/delete?token=" + url.QueryEscape(token)
+	return s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm deleting your RentHelp account",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nWe received a request to delete your account. Open the link below to confirm:\n\n%s\n\nThe link expires in %d minutes. If you did not ask for this, you can ignore this email.\n",
+			user.FullName, link, int(accountDeletionTTL.Minutes()),
+		),
+	})
+}
+
+// VerifyAccountDeletion checks that token was issued by
+// SendAccountDeletionEmail for user.
+func (s *VerificationService) VerifyAccountDeletion(user *models.User, token string) error {
+	owner, err := s.parseToken(token, accountDeletionPurpose)
+	if err != nil {
+		return err
+	}
+	if owner.ID != user.ID {
+		return ErrInvalidVerificationToken
+	}
+	return nil
+}
+
+func (s *VerificationService) generateToken(user *models.User, purpose string, ttl time.Duration) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":     user.ID.Hex(),
+		"email":   user.Email,
+		"purpose": purpose,
+		"exp":     time.Now().Add(ttl).Unix(),
+	})
+	return token.SignedString(s.secret)
+}
+
+// parseToken validates a token issued for purpose and returns its user.
+func (s *VerificationService) parseToken(token, purpose string) (*models.User, error) {
+	parsed, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		return s.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !parsed.Valid {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != purpose {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	subject, _ := claims.GetSubject()
+	userID, err := primitive.ObjectIDFromHex(subject)
+	if err != nil {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	user, err := s.userService.GetUserByID(userID)
+	if err != nil {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	// A token issued for an earlier address must not work for a changed one.
+	if claims["email"] != user.Email {
+		return nil, ErrInvalidVerificationToken
+	}
+	return user, nil
+}
@@ -0,0 +1,132 @@
+// Package calendar works with the date ranges of a listing's availability
+// calendar. Ranges are half-open: a range from March 1 to April 1 covers the
+// nights of March 1 through March 31, just like a booking that checks out on
+// April 1.
+package calendar
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// DateLayout is the format of dates in query parameters.
+const DateLayout = "2006-01-02"
+
+var ErrInvalidDate = errors.New("expected a date in YYYY-MM-DD format")
+
+// Date returns midnight UTC of the calendar day t falls on in its own
+// location, so "2025-03-01T00:00:00+08:00" stays March 1.
+func Date(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// ParseDate parses a YYYY-MM-DD date.
+func ParseDate(s string) (time.Time, error) {
+	t, err := time.Parse(DateLayout, s)
+	if err != nil {
+		return time.Time{}, ErrInvalidDate
+	}
+	return t, nil
+}
+
+// Range is the nights from Start up to, but not including, End.
+type Range struct {
+	Start time.Time
+	End   time.Time
+}
+
+// NewRange returns the range between the calendar days of start and end.
+func NewRange(start, end time.Time) Range {
+	return Range{Start: Date(start), End: Date(end)}
+}
+
+// Valid reports whether the range covers at least one night.
+func (r Range) Valid() bool {
+	return r.End.After(r.Start)
+}
+
+// Nights returns the number of nights in the range.
+func (r Range) Nights() int {
+	if !r.Valid() {
+		return 0
+	}
+	return int(r.End.Sub(r.Start).Round(time.Hour).Hours() / 24)
+}
+
+// Overlaps reports whether the two ranges share a night. Ranges that only
+// touch, one ending on the day the other starts, do not overlap.
+func (r Range) Overlaps(other Range) bool {
+	return r.Start.Before(other.End) && other.Start.Before(r.End)
+}
+
+// Contains reports whether the night of day is in the range.
+func (r Range) Contains(day time.Time) bool {
+	return !day.Before(r.Start) && day.Before(r.End)
+}
+
+// FirstOverlap returns the indexes of the first two ranges that overlap, or
+// -1, -1 if they are disjoint.
+func FirstOverlap(ranges []Range) (int, int) {
+	for i := range ranges {
+		for j := i + 1; j < len(ranges); j++ {
+			if ranges[i].Overlaps(ranges[j]) {
+				return i, j
+			}
+		}
+	}
+	return -1, -1
+}
+
+// Days returns the days of the range, one per night.
+func (r Range) Days() []time.Time {
+	days := make([]time.Time, 0, r.Nights())
+	for day := r.Start; day.Before(r.End); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+	return days
+}
+
+// MonthNights is how many nights a monthly price pays for. Stays of at least
+// this many nights are charged at monthly prices.
+const MonthNights = 30
+
+// Rate overrides the listing price for the nights of Range. Either price may
+// be nil to keep the one derived from the listing price.
+type Rate struct {
+	Range   Range
+	Nightly *float64
+	Monthly *float64
+}
+
+// StayPrice returns the rent for the nights of stay, rounded to cents.
+// monthly is the listing price, which is a monthly rent.
+//
+// Each night is charged at the rate covering it, or at the listing price if
+// none does. Stays of MonthNights nights or more pay a MonthNights share of
+// the monthly price per night; shorter stays pay the nightly price, which
+// defaults to that same share.
+func StayPrice(stay Range, monthly float64, rates []Rate) float64 {
+	long := stay.Nights() >= MonthNights
+	total := 0.0
+	for _, night := range stay.Days() {
+		month, nightly := monthly, (*float64)(nil)
+		for _, rate := range rates {
+			if !rate.Range.Contains(night) {
+				continue
+			}
+			if rate.Monthly != nil {
+				month = *rate.Monthly
+			}
+			nightly = rate.Nightly
+			break
+		}
+		if nightly != nil && !long {
+			total += *nightly
+		} else {
+			total += month / MonthNights
+		}
+	}
+	return math.Round(total*100) / 100
+}
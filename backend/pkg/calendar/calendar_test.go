@@ -0,0 +1,144 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+)
+
+func day(s string) time.Time {
+	t, err := ParseDate(s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestDate(t *testing.T) {
+	shanghai := time.FixedZone("CST", 8*3600)
+	got := Date(time.Date(2025, 3, 1, 0, 30, 0, 0, shanghai))
+	if !got.Equal(day("2025-03-01")) || got.Location() != time.UTC {
+		t.Fatalf("Date = %v", got)
+	}
+	if got := Date(time.Date(2025, 2, 28, 23, 59, 0, 0, time.UTC)); !got.Equal(day("2025-02-28")) {
+		t.Fatalf("Date = %v", got)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	for _, s := range []string{"", "2025-3-1", "2025-02-30", "2025-03-01T00:00:00Z"} {
+		if _, err := ParseDate(s); err != ErrInvalidDate {
+			t.Errorf("ParseDate(%q) error = %v", s, err)
+		}
+	}
+}
+
+func TestNights(t *testing.T) {
+	tests := []struct {
+		start, end string
+		want       int
+	}{
+		{"2025-03-01", "2025-04-01", 31},
+		{"2025-03-01", "2025-03-02", 1},
+		{"2025-03-01", "2025-03-01", 0},
+		{"2025-03-02", "2025-03-01", 0},
+		{"2024-02-28", "2024-03-01", 2},
+	}
+	for _, tt := range tests {
+		r := Range{Start: day(tt.start), End: day(tt.end)}
+		if got := r.Nights(); got != tt.want {
+			t.Errorf("Nights(%s, %s) = %d, want %d", tt.start, tt.end, got, tt.want)
+		}
+		if r.Valid() != (tt.want > 0) {
+			t.Errorf("Valid(%s, %s) = %v", tt.start, tt.end, r.Valid())
+		}
+	}
+}
+
+func TestOverlaps(t *testing.T) {
+	march := Range{Start: day("2025-03-01"), End: day("2025-04-01")}
+	tests := []struct {
+		start, end string
+		want       bool
+	}{
+		{"2025-02-01", "2025-03-01", false},
+		{"2025-04-01", "2025-05-01", false},
+		{"2025-02-01", "2025-03-02", true},
+		{"2025-03-31", "2025-04-02", true},
+		{"2025-03-10", "2025-03-11", true},
+		{"2025-02-01", "2025-05-01", true},
+	}
+	for _, tt := range tests {
+		r := Range{Start: day(tt.start), End: day(tt.end)}
+		if got := march.Overlaps(r); got != tt.want {
+			t.Errorf("Overlaps(%s, %s) = %v, want %v", tt.start, tt.end, got, tt.want)
+		}
+		if got := r.Overlaps(march); got != tt.want {
+			t.Errorf("Overlaps is not symmetric for %s, %s", tt.start, tt.end)
+		}
+	}
+}
+
+func TestFirstOverlap(t *testing.T) {
+	ranges := []Range{
+		{Start: day("2025-03-01"), End: day("2025-03-10")},
+		{Start: day("2025-03-10"), End: day("2025-03-20")},
+		{Start: day("2025-04-01"), End: day("2025-04-10")},
+	}
+	if i, j := FirstOverlap(ranges); i != -1 || j != -1 {
+		t.Fatalf("FirstOverlap = %d, %d for disjoint ranges", i, j)
+	}
+
+	ranges = append(ranges, Range{Start: day("2025-03-15"), End: day("2025-03-16")})
+	if i, j := FirstOverlap(ranges); i != 1 || j != 3 {
+		t.Fatalf("FirstOverlap = %d, %d, want 1, 3", i, j)
+	}
+}
+
+func TestDays(t *testing.T) {
+	days := Range{Start: day("2024-02-27"), End: day("2024-03-02")}.Days()
+	want := []string{"2024-02-27", "2024-02-28", "2024-02-29", "2024-03-01"}
+	if len(days) != len(want) {
+		t.Fatalf("Days = %v", days)
+	}
+	for i, d := range days {
+		if d.Format(DateLayout) != want[i] {
+			t.Errorf("Days[%d] = %s, want %s", i, d.Format(DateLayout), want[i])
+		}
+	}
+
+	if days := (Range{Start: day("2024-03-02"), End: day("2024-03-01")}).Days(); len(days) != 0 {
+		t.Fatalf("Days of an empty range = %v", days)
+	}
+}
+
+func TestStayPrice(t *testing.T) {
+	price := func(p float64) *float64 { return &p }
+	rates := []Rate{
+		{Range: Range{Start: day("2025-03-01"), End: day("2025-03-05")}, Nightly: price(200)},
+		{Range: Range{Start: day("2025-03-05"), End: day("2025-04-01")}, Nightly: price(150), Monthly: price(3600)},
+	}
+
+	tests := []struct {
+		name       string
+		start, end string
+		want       float64
+	}{
+		{"listing price only", "2025-02-01", "2025-02-04", 300},
+		{"nightly override", "2025-03-01", "2025-03-03", 400},
+		{"across two rates", "2025-03-04", "2025-03-06", 200 + 150},
+		{"rate and listing price", "2025-02-27", "2025-03-02", 100 + 100 + 200},
+		{"monthly stay uses monthly prices", "2025-03-05", "2025-04-04", 27*120 + 3*100},
+		{"monthly stay ignores nightly prices", "2025-02-01", "2025-03-03", 28*100 + 2*100},
+		{"empty stay", "2025-03-02", "2025-03-01", 0},
+	}
+	for _, tt := range tests {
+		stay := Range{Start: day(tt.start), End: day(tt.end)}
+		if got := StayPrice(stay, 3000, rates); got != tt.want {
+			t.Errorf("%s: StayPrice = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if got := StayPrice(Range{Start: day("2025-05-01"), End: day("2025-05-02")}, 1000, nil); got != 33.33 {
+		t.Errorf("StayPrice = %v, want 33.33", got)
+	}
+}
@@ -0,0 +1,160 @@
+// Package geo parses the location parameters accepted by the property search
+// endpoints and converts them to GeoJSON coordinates. GeoJSON positions are
+// always [longitude, latitude].
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// MaxPolygonPoints bounds the size of polygon queries so a single request
+// cannot hand MongoDB an arbitrarily large geometry.
+const MaxPolygonPoints = 100
+
+var (
+	ErrInvalidPoint   = errors.New("expected lat,lng")
+	ErrInvalidBBox    = errors.New("expected min_lng,min_lat,max_lng,max_lat")
+	ErrInvalidPolygon = errors.New("expected at least 3 lat,lng points separated by ';'")
+)
+
+// Point is a WGS84 coordinate.
+type Point struct {
+	Lat float64
+	Lng float64
+}
+
+// Validate reports whether the point lies within the valid latitude and
+// longitude ranges.
+func (p Point) Validate() error {
+	if math.IsNaN(p.Lat) || p.Lat < -90 || p.Lat > 90 {
+		return fmt.Errorf("latitude %v out of range [-90, 90]", p.Lat)
+	}
+	if math.IsNaN(p.Lng) || p.Lng < -180 || p.Lng > 180 {
+		return fmt.Errorf("longitude %v out of range [-180, 180]", p.Lng)
+	}
+	return nil
+}
+
+// Coordinates returns the point as a GeoJSON position.
+func (p Point) Coordinates() []float64 {
+	return []float64{p.Lng, p.Lat}
+}
+
+// FromCoordinates converts a GeoJSON position back to a Point.
+func FromCoordinates(coordinates []float64) (Point, error) {
+	if len(coordinates) != 2 {
+		return Point{}, errors.New("expected [longitude, latitude]")
+	}
+	p := Point{Lat: coordinates[1], Lng: coordinates[0]}
+	return p, p.Validate()
+}
+
+// ParsePoint parses "lat,lng", the order used by the near= parameter.
+func ParsePoint(s string) (Point, error) {
+	values, err := parseFloats(s, 2)
+	if err != nil {
+		return Point{}, ErrInvalidPoint
+	}
+	p := Point{Lat: values[0], Lng: values[1]}
+	return p, p.Validate()
+}
+
+// BBox is a bounding box in GeoJSON order: west, south, east, north.
+type BBox struct {
+	MinLng float64
+	MinLat float64
+	MaxLng float64
+	MaxLat float64
+}
+
+// ParseBBox parses "min_lng,min_lat,max_lng,max_lat", the format produced by
+// most map libraries for the visible viewport.
+func ParseBBox(s string) (BBox, error) {
+	values, err := parseFloats(s, 4)
+	if err != nil {
+		return BBox{}, ErrInvalidBBox
+	}
+	b := BBox{MinLng: values[0], MinLat: values[1], MaxLng: values[2], MaxLat: values[3]}
+	for _, p := range []Point{{b.MinLat, b.MinLng}, {b.MaxLat, b.MaxLng}} {
+		if err := p.Validate(); err != nil {
+			return BBox{}, err
+		}
+	}
+	if b.MinLat >= b.MaxLat || b.MinLng >= b.MaxLng {
+		return BBox{}, errors.New("bounding box minimums must be less than maximums")
+	}
+	return b, nil
+}
+
+// Ring returns the box as a closed, counter-clockwise GeoJSON linear ring.
+func (b BBox) Ring() [][]float64 {
+	return [][]float64{
+		{b.MinLng, b.MinLat},
+		{b.MaxLng, b.MinLat},
+		{b.MaxLng, b.MaxLat},
+		{b.MinLng, b.MaxLat},
+		{b.MinLng, b.MinLat},
+	}
+}
+
+// ParsePolygon parses "lat,lng;lat,lng;..." with at least three distinct
+// points. The ring does not need to be closed by the caller.
+func ParsePolygon(s string) ([]Point, error) {
+	parts := strings.Split(strings.TrimSuffix(strings.TrimSpace(s), ";"), ";")
+	if len(parts) > MaxPolygonPoints+1 {
+		return nil, fmt.Errorf("polygon cannot have more than %d points", MaxPolygonPoints)
+	}
+
+	points := make([]Point, 0, len(parts))
+	for _, part := range parts {
+		p, err := ParsePoint(part)
+		if err != nil {
+			if err == ErrInvalidPoint {
+				return nil, ErrInvalidPolygon
+			}
+			return nil, err
+		}
+		points = append(points, p)
+	}
+
+	if len(points) > 1 && points[0] == points[len(points)-1] {
+		points = points[:len(points)-1]
+	}
+	if len(points) < 3 {
+		return nil, ErrInvalidPolygon
+	}
+	return points, nil
+}
+
+// Ring returns the points as a closed GeoJSON linear ring.
+func Ring(points []Point) [][]float64 {
+	ring := make([][]float64, 0, len(points)+1)
+	for _, p := range points {
+		ring = append(ring, p.Coordinates())
+	}
+	if len(points) > 0 {
+		ring = append(ring, points[0].Coordinates())
+	}
+	return ring
+}
+
+func parseFloats(s string, n int) ([]float64, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != n {
+		return nil, errors.New("wrong number of values")
+	}
+
+	values := make([]float64, n)
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil, errors.New("invalid number")
+		}
+		values[i] = v
+	}
+	return values, nil
+}
@@ -0,0 +1,86 @@
+package geo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePoint(t *testing.T) {
+	p, err := ParsePoint(" 34.0522, -118.2437 ")
+	if err != nil {
+		t.Fatalf("ParsePoint: %v", err)
+	}
+	if p != (Point{Lat: 34.0522, Lng: -118.2437}) {
+		t.Fatalf("ParsePoint = %+v", p)
+	}
+	if got := p.Coordinates(); !reflect.DeepEqual(got, []float64{-118.2437, 34.0522}) {
+		t.Fatalf("Coordinates = %v", got)
+	}
+
+	for _, s := range []string{"", "34.05", "a,b", "1,2,3", "91,0", "0,181", "NaN,0"} {
+		if _, err := ParsePoint(s); err == nil {
+			t.Errorf("ParsePoint(%q) succeeded", s)
+		}
+	}
+}
+
+func TestFromCoordinates(t *testing.T) {
+	p, err := FromCoordinates([]float64{116.4, 39.9})
+	if err != nil || p != (Point{Lat: 39.9, Lng: 116.4}) {
+		t.Fatalf("FromCoordinates = %+v, %v", p, err)
+	}
+	if _, err := FromCoordinates([]float64{1}); err == nil {
+		t.Fatal("expected error for a single value")
+	}
+	if _, err := FromCoordinates([]float64{0, 95}); err == nil {
+		t.Fatal("expected error for latitude out of range")
+	}
+}
+
+func TestParseBBox(t *testing.T) {
+	b, err := ParseBBox("-118.5,33.9,-118.1,34.2")
+	if err != nil {
+		t.Fatalf("ParseBBox: %v", err)
+	}
+	want := [][]float64{
+		{-118.5, 33.9},
+		{-118.1, 33.9},
+		{-118.1, 34.2},
+		{-118.5, 34.2},
+		{-118.5, 33.9},
+	}
+	if got := b.Ring(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Ring = %v", got)
+	}
+
+	for _, s := range []string{"1,2,3", "-118.1,33.9,-118.5,34.2", "0,0,0,0", "-200,0,10,10", "x,0,1,1"} {
+		if _, err := ParseBBox(s); err == nil {
+			t.Errorf("ParseBBox(%q) succeeded", s)
+		}
+	}
+}
+
+func TestParsePolygon(t *testing.T) {
+	points, err := ParsePolygon("39.9,116.3;39.9,116.5;40.0,116.4")
+	if err != nil {
+		t.Fatalf("ParsePolygon: %v", err)
+	}
+	want := [][]float64{{116.3, 39.9}, {116.5, 39.9}, {116.4, 40.0}, {116.3, 39.9}}
+	if got := Ring(points); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Ring = %v", got)
+	}
+
+	closed, err := ParsePolygon("39.9,116.3;39.9,116.5;40.0,116.4;39.9,116.3;")
+	if err != nil {
+		t.Fatalf("ParsePolygon closed: %v", err)
+	}
+	if !reflect.DeepEqual(closed, points) {
+		t.Fatalf("closed ring parsed as %v, want %v", closed, points)
+	}
+
+	for _, s := range []string{"", "39.9,116.3;39.9,116.5", "39.9,116.3;39.9,116.5;39.9,116.3", "39.9,116.3;bad;40,116"} {
+		if _, err := ParsePolygon(s); err == nil {
+			t.Errorf("ParsePolygon(%q) succeeded", s)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+// Package imaging prepares uploaded photos for publishing: it removes
+// metadata such as GPS coordinates, applies the EXIF orientation and renders
+// smaller JPEG and WebP copies. Everything is pure Go.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	stddraw "image/draw"
+	"image/jpeg"
+	_ "image/png"
+	"sort"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrInvalidImage      = errors.New("imaging: invalid image")
+	ErrUnsupportedFormat = errors.New("imaging: unsupported image format")
+	ErrImageTooLarge     = errors.New("imaging: image dimensions are too large")
+)
+
+// MaxPixels bounds the decoded size of an image, so that a small file that
+// declares huge dimensions cannot exhaust memory.
+const MaxPixels = 50_000_000
+
+const (
+	// Quality of the JPEG variants, and of the original when it has to be
+	// re-encoded to apply its orientation.
+	variantQuality  = 82
+	originalQuality = 92
+)
+
+// Variant formats
+const (
+	FormatJPEG = "jpeg"
+	FormatWebP = "webp"
+)
+
+// Size is a named bounding box for a variant. Images are scaled down to fit
+// into MaxSide x MaxSide, keeping their aspect ratio, and never scaled up.
+type Size struct {
+	Name    string
+	MaxSide int
+}
+
+// DefaultSizes are the variants rendered for listing photos.
+var DefaultSizes = []Size{
+	{Name: "small", MaxSide: 320},
+	{Name: "medium", MaxSide: 800},
+	{Name: "large", MaxSide: 1600},
+}
+
+// Variant is an encoded copy of an image in one size and format.
+type Variant struct {
+	Name        string
+	Format      string
+	ContentType string
+	Width       int
+	Height      int
+	Data        []byte
+}
+
+// Result is a processed upload.
+type Result struct {
+	// Data is the original image without metadata. It is the uploaded file
+	// with metadata removed, or a re-encoded JPEG if the photo had to be
+	// rotated.
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+	Variants    []Variant
+}
+
+// Process strips the metadata from an encoded JPEG, PNG or WebP image and
+// renders a JPEG and a WebP variant for every size.
+func Process(data []byte, sizes []Size) (*Result, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			return nil, ErrUnsupportedFormat
+		}
+		return nil, ErrInvalidImage
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, ErrInvalidImage
+	}
+	if int64(config.Width)*int64(config.Height) > MaxPixels {
+		return nil, ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	result := &Result{ContentType: "image/" + format}
+	orientation := 1
+	if format == "jpeg" {
+		orientation = jpegOrientation(data)
+	}
+	if orientation != 1 {
+		// Viewers would lose the rotation along with the EXIF data, so it
+		// is applied to the pixels instead.
+		img = Orient(img, orientation)
+		if result.Data, err = encodeJPEG(img, originalQuality); err != nil {
+			return nil, err
+		}
+	} else if result.Data, err = StripMetadata(data, format); err != nil {
+		return nil, err
+	}
+	result.Width, result.Height = img.Bounds().Dx(), img.Bounds().Dy()
+
+	// Render from the largest size down, scaling each variant from the
+	// previous one, which is much cheaper than scaling the original each
+	// time and looks the same.
+	sorted := append([]Size{}, sizes...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].MaxSide > sorted[j].MaxSide })
+
+	source := img
+	variants := make(map[string][]Variant, len(sorted))
+	for _, size := range sorted {
+		source = Fit(source, size.MaxSide)
+		width, height := source.Bounds().Dx(), source.Bounds().Dy()
+
+		jpg, err := encodeJPEG(flatten(source), variantQuality)
+		if err != nil {
+			return nil, err
+		}
+		var webp bytes.Buffer
+		if err := EncodeWebP(&webp, source, variantQuality); err != nil {
+			return nil, err
+		}
+
+		variants[size.Name] = []Variant{
+			{Name: size.Name, Format: FormatJPEG, ContentType: "image/jpeg", Width: width, Height: height, Data: jpg},
+			{Name: size.Name, Format: FormatWebP, ContentType: "image/webp", Width: width, Height: height, Data: webp.Bytes()},
+		}
+	}
+	for _, size := range sizes {
+		result.Variants = append(result.Variants, variants[size.Name]...)
+	}
+	return result, nil
+}
+
+// Fit scales img down to fit into maxSide x maxSide. Images that already fit
+// are returned unchanged.
+func Fit(img image.Image, maxSide int) image.Image {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width <= maxSide && height <= maxSide {
+		return img
+	}
+
+	if width >= height {
+		height = max(1, (height*maxSide+width/2)/width)
+		width = maxSide
+	} else {
+		width = max(1, (width*maxSide+height/2)/height)
+		height = maxSide
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Rect, img, b, draw.Src, nil)
+	return dst
+}
+
+// Orient applies an EXIF orientation (1 to 8) to img.
+func Orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	src := image.NewRGBA(image.Rect(0, 0, width, height))
+	stddraw.Draw(src, src.Rect, img, b.Min, stddraw.Src)
+
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = width-1-x, y
+			case 3: // rotated 180°
+				dx, dy = width-1-x, height-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, height-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotate 90° clockwise
+				dx, dy = height-1-y, x
+			case 7: // transversed
+				dx, dy = height-1-y, width-1-x
+			case 8: // rotate 90° counter-clockwise
+				dx, dy = y, width-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):][:4], src.Pix[src.PixOffset(x, y):][:4])
+		}
+	}
+	return dst
+}
+
+// flatten draws images with transparency onto white, as JPEG has no alpha
+// channel.
+func flatten(img image.Image) image.Image {
+	if opaque(img) {
+		return img
+	}
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	stddraw.Draw(dst, dst.Rect, image.NewUniform(color.White), image.Point{}, stddraw.Src)
+	stddraw.Draw(dst, dst.Rect, img, b.Min, stddraw.Over)
+	return dst
+}
+
+func encodeJPEG(img image.Image, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
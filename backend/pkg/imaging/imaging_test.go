@@ -0,0 +1,252 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+func testImage(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 0xff})
+		}
+	}
+	return img
+}
+
+// exifSegment returns an APP1 segment with a big-endian TIFF structure that
+// holds the orientation and a GPS IFD pointer.
+func exifSegment(orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 2)
+	tiff = append(tiff, 0x01, 0x12, 0x00, 0x03, 0, 0, 0, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0)
+	tiff = append(tiff, 0x88, 0x25, 0x00, 0x04, 0, 0, 0, 1, 0, 0, 0, 0)
+	tiff = append(tiff, 0, 0, 0, 0)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xff, markerAPP1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// jpegWithSegments encodes img and inserts the segments after SOI.
+func jpegWithSegments(t *testing.T, img image.Image, segments ...[]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	data := append([]byte{}, buf.Bytes()[:2]...)
+	for _, s := range segments {
+		data = append(data, s...)
+	}
+	return append(data, buf.Bytes()[2:]...)
+}
+
+func comment(text string) []byte {
+	segment := []byte{0xff, markerCOM}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(text)+2))
+	return append(segment, text...)
+}
+
+func pngChunk(kind string, data []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, kind...)
+	chunk = append(chunk, data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+func TestStripJPEG(t *testing.T) {
+	data := jpegWithSegments(t, testImage(16, 8), exifSegment(1), comment("taken at home"))
+	if got := jpegOrientation(data); got != 1 {
+		t.Fatalf("orientation = %d, want 1", got)
+	}
+
+	stripped, err := StripMetadata(data, "jpeg")
+	if err != nil {
+		t.Fatalf("StripMetadata: %v", err)
+	}
+	if bytes.Contains(stripped, []byte("Exif")) || bytes.Contains(stripped, []byte("taken at home")) {
+		t.Fatal("metadata was not removed")
+	}
+	if len(data)-len(stripped) != len(exifSegment(1))+len(comment("taken at home")) {
+		t.Fatalf("removed %d bytes", len(data)-len(stripped))
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Fatalf("stripped JPEG does not decode: %v", err)
+	}
+}
+
+func TestStripPNG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(4, 4)); err != nil {
+		t.Fatal(err)
+	}
+	// Insert a text chunk after IHDR.
+	ihdrEnd := len(pngSignature) + 25
+	data := append([]byte{}, buf.Bytes()[:ihdrEnd]...)
+	data = append(data, pngChunk("tEXt", []byte("Comment\x00GPS 52.52,13.40"))...)
+	data = append(data, buf.Bytes()[ihdrEnd:]...)
+
+	stripped, err := StripMetadata(data, "png")
+	if err != nil {
+		t.Fatalf("StripMetadata: %v", err)
+	}
+	if !bytes.Equal(stripped, buf.Bytes()) {
+		t.Fatal("text chunk was not removed")
+	}
+}
+
+func TestStripWebP(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeWebP(&buf, testImage(5, 3), 90); err != nil {
+		t.Fatal(err)
+	}
+	vp8l := buf.Bytes()[12:]
+
+	vp8x := []byte("VP8X\x0a\x00\x00\x00")
+	vp8x = append(vp8x, vp8xFlagEXIF, 0, 0, 0, 4, 0, 0, 2, 0, 0)
+	exif := []byte("EXIF\x03\x00\x00\x00GPS\x00")
+
+	data := []byte("RIFF\x00\x00\x00\x00WEBP")
+	data = append(data, vp8x...)
+	data = append(data, vp8l...)
+	data = append(data, exif...)
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(data)-8))
+
+	stripped, err := StripMetadata(data, "webp")
+	if err != nil {
+		t.Fatalf("StripMetadata: %v", err)
+	}
+	if bytes.Contains(stripped, []byte("GPS")) {
+		t.Fatal("EXIF chunk was not removed")
+	}
+	if stripped[20]&vp8xFlagEXIF != 0 {
+		t.Fatal("EXIF flag is still set")
+	}
+	if got := binary.LittleEndian.Uint32(stripped[4:]); int(got) != len(stripped)-8 {
+		t.Fatalf("RIFF size %d, file has %d bytes", got, len(stripped))
+	}
+	if _, err := webp.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Fatalf("stripped WebP does not decode: %v", err)
+	}
+}
+
+func TestProcessAppliesOrientation(t *testing.T) {
+	// Orientation 6: the camera was held upright, so the stored landscape
+	// image has to be turned clockwise.
+	data := jpegWithSegments(t, testImage(40, 20), exifSegment(6))
+
+	result, err := Process(data, []Size{{Name: "small", MaxSide: 10}})
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if result.Width != 20 || result.Height != 40 {
+		t.Fatalf("size %dx%d, want 20x40", result.Width, result.Height)
+	}
+	if bytes.Contains(result.Data, []byte("Exif")) {
+		t.Fatal("EXIF data was kept")
+	}
+	if jpegOrientation(result.Data) != 1 {
+		t.Fatal("orientation was kept")
+	}
+	for _, v := range result.Variants {
+		if v.Width != 5 || v.Height != 10 {
+			t.Errorf("%s %s variant is %dx%d, want 5x10", v.Name, v.Format, v.Width, v.Height)
+		}
+	}
+}
+
+func TestOrient(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+	img.Set(1, 0, color.RGBA{B: 255, A: 255})
+
+	red := color.RGBA{R: 255, A: 255}
+	for orientation, want := range map[int]image.Point{
+		1: {0, 0}, 2: {1, 0}, 3: {1, 0}, 4: {0, 0},
+		5: {0, 0}, 6: {0, 0}, 7: {0, 1}, 8: {0, 1},
+	} {
+		oriented := Orient(img, orientation)
+		if got := color.RGBAModel.Convert(oriented.At(want.X, want.Y)); got != red {
+			t.Errorf("orientation %d: red pixel not at %v", orientation, want)
+		}
+	}
+}
+
+func TestProcessVariants(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(1000, 500)); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Process(buf.Bytes(), DefaultSizes)
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if result.ContentType != "image/png" || result.Width != 1000 || result.Height != 500 {
+		t.Fatalf("original is %s %dx%d", result.ContentType, result.Width, result.Height)
+	}
+
+	want := []struct {
+		name          string
+		format        string
+		width, height int
+	}{
+		{"small", FormatJPEG, 320, 160},
+		{"small", FormatWebP, 320, 160},
+		{"medium", FormatJPEG, 800, 400},
+		{"medium", FormatWebP, 800, 400},
+		{"large", FormatJPEG, 1000, 500},
+		{"large", FormatWebP, 1000, 500},
+	}
+	if len(result.Variants) != len(want) {
+		t.Fatalf("got %d variants, want %d", len(result.Variants), len(want))
+	}
+	for i, w := range want {
+		v := result.Variants[i]
+		if v.Name != w.name || v.Format != w.format || v.Width != w.width || v.Height != w.height {
+			t.Errorf("variant %d = %s %s %dx%d, want %s %s %dx%d", i, v.Name, v.Format, v.Width, v.Height, w.name, w.format, w.width, w.height)
+		}
+
+		config, format, err := image.DecodeConfig(bytes.NewReader(v.Data))
+		if err != nil {
+			t.Fatalf("variant %d does not decode: %v", i, err)
+		}
+		if format != v.Format || config.Width != v.Width || config.Height != v.Height {
+			t.Errorf("variant %d decodes as %s %dx%d", i, format, config.Width, config.Height)
+		}
+	}
+}
+
+func TestProcessRejectsHugeImages(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(1, 1)); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	// Rewrite IHDR to claim 100000x100000 pixels.
+	ihdr := data[len(pngSignature)+8 : len(pngSignature)+21]
+	binary.BigEndian.PutUint32(ihdr[0:], 100000)
+	binary.BigEndian.PutUint32(ihdr[4:], 100000)
+	binary.BigEndian.PutUint32(data[len(pngSignature)+21:], crc32.ChecksumIEEE(data[len(pngSignature)+4:len(pngSignature)+21]))
+
+	if _, err := Process(data, DefaultSizes); err != ErrImageTooLarge {
+		t.Fatalf("Process = %v, want ErrImageTooLarge", err)
+	}
+	if _, err := Process([]byte("not an image"), DefaultSizes); err != ErrUnsupportedFormat {
+		t.Fatalf("Process = %v, want ErrUnsupportedFormat", err)
+	}
+}
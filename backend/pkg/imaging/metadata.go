@@ -0,0 +1,256 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// StripMetadata removes EXIF, XMP, IPTC, comments and text chunks from an
+// encoded JPEG, PNG or WebP image without re-encoding the pixels. format is
+// the name reported by image.DecodeConfig. Color profiles are kept.
+func StripMetadata(data []byte, format string) ([]byte, error) {
+	switch format {
+	case "jpeg":
+		return stripJPEG(data)
+	case "png":
+		return stripPNG(data)
+	case "webp":
+		return stripWebP(data)
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+// JPEG markers
+const (
+	markerSOI   = 0xd8
+	markerSOS   = 0xda
+	markerAPP0  = 0xe0
+	markerAPP1  = 0xe1
+	markerAPP2  = 0xe2
+	markerAPP14 = 0xee
+	markerAPP15 = 0xef
+	markerCOM   = 0xfe
+)
+
+// jpegSegment is a marker segment before the image data. payload excludes
+// the marker and length bytes.
+type jpegSegment struct {
+	marker  byte
+	raw     []byte
+	payload []byte
+}
+
+// jpegSegments splits data into the segments before the start of scan and
+// returns them with the offset of the SOS marker.
+func jpegSegments(data []byte) ([]jpegSegment, int, error) {
+	if len(data) < 4 || data[0] != 0xff || data[1] != markerSOI {
+		return nil, 0, ErrInvalidImage
+	}
+
+	var segments []jpegSegment
+	p := 2
+	for {
+		if p+1 >= len(data) || data[p] != 0xff {
+			return nil, 0, ErrInvalidImage
+		}
+		// Markers may be preceded by any number of fill bytes.
+		for p+1 < len(data) && data[p+1] == 0xff {
+			p++
+		}
+		if p+1 >= len(data) {
+			return nil, 0, ErrInvalidImage
+		}
+		marker := data[p+1]
+		if marker == markerSOS {
+			return segments, p, nil
+		}
+		if marker == 0x01 || marker >= 0xd0 && marker <= 0xd7 {
+			// Standalone markers have no length.
+			segments = append(segments, jpegSegment{marker: marker, raw: data[p : p+2]})
+			p += 2
+			continue
+		}
+
+		if p+4 > len(data) {
+			return nil, 0, ErrInvalidImage
+		}
+		length := int(binary.BigEndian.Uint16(data[p+2:]))
+		if length < 2 || p+2+length > len(data) {
+			return nil, 0, ErrInvalidImage
+		}
+		segments = append(segments, jpegSegment{
+			marker:  marker,
+			raw:     data[p : p+2+length],
+			payload: data[p+4 : p+2+length],
+		})
+		p += 2 + length
+	}
+}
+
+func stripJPEG(data []byte) ([]byte, error) {
+	segments, sos, err := jpegSegments(data)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xff, markerSOI)
+	for _, s := range segments {
+		if keepJPEGSegment(s) {
+			out = append(out, s.raw...)
+		}
+	}
+	return append(out, data[sos:]...), nil
+}
+
+// keepJPEGSegment reports whether a segment is needed to display the image.
+// Of the application segments only JFIF, ICC profiles and the Adobe color
+// transform are kept.
+func keepJPEGSegment(s jpegSegment) bool {
+	switch {
+	case s.marker == markerCOM:
+		return false
+	case s.marker == markerAPP0:
+		return bytes.HasPrefix(s.payload, []byte("JFIF\x00")) || bytes.HasPrefix(s.payload, []byte("JFXX\x00"))
+	case s.marker == markerAPP2:
+		return bytes.HasPrefix(s.payload, []byte("ICC_PROFILE\x00"))
+	case s.marker == markerAPP14:
+		return bytes.HasPrefix(s.payload, []byte("Adobe"))
+	case s.marker >= markerAPP0 && s.marker <= markerAPP15:
+		return false
+	}
+	return true
+}
+
+// jpegOrientation returns the EXIF orientation of a JPEG, from 1 to 8, or
+// 1 if it has none.
+func jpegOrientation(data []byte) int {
+	segments, _, err := jpegSegments(data)
+	if err != nil {
+		return 1
+	}
+	for _, s := range segments {
+		if s.marker == markerAPP1 && bytes.HasPrefix(s.payload, []byte("Exif\x00\x00")) {
+			return exifOrientation(s.payload[6:])
+		}
+	}
+	return 1
+}
+
+// exifOrientation reads the orientation tag from the first IFD of a TIFF
+// structure.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		e := ifd + 2 + 12*i
+		if e+12 > len(tiff) {
+			return 1
+		}
+		const tagOrientation, typeShort = 0x0112, 3
+		if order.Uint16(tiff[e:]) == tagOrientation && order.Uint16(tiff[e+2:]) == typeShort {
+			if v := int(order.Uint16(tiff[e+8:])); v >= 1 && v <= 8 {
+				return v
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngKeepChunks are the ancillary chunks that affect how an image looks.
+// Critical chunks are always kept.
+var pngKeepChunks = map[string]bool{
+	"tRNS": true, "gAMA": true, "cHRM": true, "sRGB": true, "iCCP": true,
+	"sBIT": true, "bKGD": true, "pHYs": true,
+	"acTL": true, "fcTL": true, "fdAT": true,
+}
+
+func stripPNG(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, ErrInvalidImage
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+	for p := len(pngSignature); p < len(data); {
+		if p+12 > len(data) {
+			return nil, ErrInvalidImage
+		}
+		length := int(binary.BigEndian.Uint32(data[p:]))
+		end := p + 12 + length
+		if length < 0 || end > len(data) || end < p {
+			return nil, ErrInvalidImage
+		}
+		kind := string(data[p+4 : p+8])
+		// An upper-case first letter marks a critical chunk.
+		if kind[0] >= 'A' && kind[0] <= 'Z' || pngKeepChunks[kind] {
+			out = append(out, data[p:end]...)
+		}
+		p = end
+		if kind == "IEND" {
+			break
+		}
+	}
+	return out, nil
+}
+
+// VP8X feature flags
+const (
+	vp8xFlagXMP  = 0x04
+	vp8xFlagEXIF = 0x08
+)
+
+func stripWebP(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, ErrInvalidImage
+	}
+
+	out := make([]byte, 12, len(data))
+	copy(out, data[:12])
+	for p := 12; p < len(data); {
+		if p+8 > len(data) {
+			return nil, ErrInvalidImage
+		}
+		size := int(binary.LittleEndian.Uint32(data[p+4:]))
+		end := p + 8 + size + size&1
+		if size < 0 || end < p || end > len(data)+size&1 {
+			return nil, ErrInvalidImage
+		}
+		end = min(end, len(data))
+
+		switch kind := string(data[p : p+4]); kind {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte{}, data[p:end]...)
+			if len(chunk) > 8 {
+				chunk[8] &^= vp8xFlagEXIF | vp8xFlagXMP
+			}
+			out = append(out, chunk...)
+		default:
+			out = append(out, data[p:end]...)
+		}
+		p = end
+	}
+
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out, nil
+}
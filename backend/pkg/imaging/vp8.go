@@ -0,0 +1,772 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"io"
+	"math"
+)
+
+// The encoder below writes lossy WebP: a single VP8 key frame (RFC 6386).
+// Every macroblock is predicted as a whole, picking the 16x16 luma and 8x8
+// chroma mode with the smallest error, and the residuals are coded with the
+// default token probabilities. It skips the rate-distortion search of
+// libwebp, so files are somewhat larger at the same quality.
+
+const (
+	numPlanes     = 4
+	numBands      = 8
+	numContexts   = 3
+	numTokenProbs = 11
+)
+
+// Coefficient planes, which select the token probabilities
+const (
+	planeYAfterY2 = 0
+	planeY2       = 1
+	planeUV       = 2
+)
+
+// Intra prediction modes of 16x16 luma and 8x8 chroma blocks
+const (
+	predDC = iota
+	predV
+	predH
+	predTM
+)
+
+// Blocks of a macroblock: 16 luma, 4 + 4 chroma and the second order luma DC
+// block.
+const (
+	firstU  = 16
+	firstV  = 20
+	blockY2 = 24
+)
+
+var (
+	// zigzag is the scan order of the coefficients of a 4x4 block.
+	zigzag = [16]int{0, 1, 4, 8, 5, 2, 3, 6, 9, 12, 13, 10, 7, 11, 14, 15}
+	// coeffBands maps a scan position to its probability band.
+	coeffBands = [17]int{0, 1, 2, 3, 6, 4, 5, 6, 6, 6, 6, 6, 6, 6, 6, 7, 0}
+	// catProbs are the probabilities of the extra bits of the DCT_CAT3 to
+	// DCT_CAT6 tokens.
+	catProbs = [4][]uint8{
+		{173, 148, 140},
+		{176, 155, 140, 135},
+		{180, 157, 141, 134, 130},
+		{254, 254, 243, 230, 196, 177, 153, 140, 133, 130, 129},
+	}
+	// dctBasis is the orthonormal 4-point DCT-II.
+	dctBasis = func() (b [4][4]float64) {
+		for k := 0; k < 4; k++ {
+			scale := math.Sqrt(0.5)
+			if k == 0 {
+				scale = 0.5
+			}
+			for n := 0; n < 4; n++ {
+				b[k][n] = scale * math.Cos(math.Pi*float64((2*n+1)*k)/8)
+			}
+		}
+		return b
+	}()
+)
+
+// maxLevel bounds the quantized coefficients to what DCT_CAT6 can code.
+const maxLevel = 2047
+
+type vp8Macroblock struct {
+	skip   bool
+	yMode  int
+	uvMode int
+	// Quantized coefficients of each block in raster order.
+	levels [25][16]int16
+}
+
+// nonZero holds, for the blocks along one edge of a macroblock, whether they
+// had coded coefficients. It is the context for the neighboring blocks.
+type nonZero struct {
+	y2   uint8
+	y    [4]uint8
+	u, v [2]uint8
+}
+
+type vp8Encoder struct {
+	width, height int
+	mbw, mbh      int
+
+	// Source planes padded to whole macroblocks, and the reconstruction the
+	// decoder will see, which the prediction has to use.
+	y, u, v    []uint8
+	ry, ru, rv []uint8
+
+	qIndex     int
+	y1, y2, uv [2]int32 // DC and AC step sizes
+
+	macroblocks []vp8Macroblock
+}
+
+// encodeLossy writes an opaque image as a lossy WebP. quality ranges from 1
+// to 100.
+func encodeLossy(w io.Writer, img image.Image, quality int) error {
+	b := img.Bounds()
+	e := &vp8Encoder{width: b.Dx(), height: b.Dy()}
+	if e.width < 1 || e.height < 1 || e.width >= maxWebPSide || e.height >= maxWebPSide {
+		return errWebPSize
+	}
+	e.mbw, e.mbh = (e.width+15)/16, (e.height+15)/16
+
+	quality = min(max(quality, 1), 100)
+	e.setQuantizer((100 - quality) * 127 / 99)
+	e.loadPlanes(img)
+
+	e.macroblocks = make([]vp8Macroblock, e.mbw*e.mbh)
+	for mby := 0; mby < e.mbh; mby++ {
+		for mbx := 0; mbx < e.mbw; mbx++ {
+			e.encodeMacroblock(mbx, mby)
+		}
+	}
+
+	first, tokens := e.partitions()
+	if len(first) >= 1<<19 {
+		return errWebPSize
+	}
+
+	frame := make([]byte, 10, 10+len(first)+len(tokens))
+	tag := uint32(1<<4 | len(first)<<5) // key frame, version 0, shown
+	frame[0], frame[1], frame[2] = byte(tag), byte(tag>>8), byte(tag>>16)
+	frame[3], frame[4], frame[5] = 0x9d, 0x01, 0x2a
+	binary.LittleEndian.PutUint16(frame[6:], uint16(e.width))
+	binary.LittleEndian.PutUint16(frame[8:], uint16(e.height))
+	frame = append(frame, first...)
+	frame = append(frame, tokens...)
+
+	return writeRIFF(w, "VP8 ", frame)
+}
+
+// writeRIFF writes a simple WebP file holding one image chunk.
+func writeRIFF(w io.Writer, fourCC string, data []byte) error {
+	padding := len(data) & 1
+
+	var header [20]byte
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(12+len(data)+padding))
+	copy(header[8:], "WEBP")
+	copy(header[12:], fourCC)
+	binary.LittleEndian.PutUint32(header[16:], uint32(len(data)))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	if padding == 1 {
+		data = append(data, 0)
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+func (e *vp8Encoder) setQuantizer(q int) {
+	q = min(max(q, 0), 127)
+	e.qIndex = q
+	e.y1 = [2]int32{dcQuant[q], acQuant[q]}
+	e.y2 = [2]int32{dcQuant[q] * 2, max(acQuant[q]*155/100, 8)}
+	e.uv = [2]int32{dcQuant[min(q, 117)], acQuant[q]}
+}
+
+// loadPlanes converts img to Y'CbCr with 4:2:0 chroma, repeating the last
+// row and column into the padding.
+func (e *vp8Encoder) loadPlanes(img image.Image) {
+	rgba := image.NewRGBA(image.Rect(0, 0, e.width, e.height))
+	draw.Draw(rgba, rgba.Rect, img, img.Bounds().Min, draw.Src)
+
+	yStride, cStride := 16*e.mbw, 8*e.mbw
+	e.y, e.ry = make([]uint8, yStride*16*e.mbh), make([]uint8, yStride*16*e.mbh)
+	e.u, e.ru = make([]uint8, cStride*8*e.mbh), make([]uint8, cStride*8*e.mbh)
+	e.v, e.rv = make([]uint8, cStride*8*e.mbh), make([]uint8, cStride*8*e.mbh)
+
+	pixel := func(x, y int) (r, g, b int32) {
+		p := rgba.PixOffset(min(x, e.width-1), min(y, e.height-1))
+		return int32(rgba.Pix[p]), int32(rgba.Pix[p+1]), int32(rgba.Pix[p+2])
+	}
+
+	// BT.601 with studio range, as libwebp does.
+	const half = 1 << 15
+	for y := 0; y < 16*e.mbh; y++ {
+		for x := 0; x < yStride; x++ {
+			r, g, b := pixel(x, y)
+			e.y[y*yStride+x] = uint8((16839*r + 33059*g + 6420*b + 16<<16 + half) >> 16)
+		}
+	}
+	for y := 0; y < 8*e.mbh; y++ {
+		for x := 0; x < cStride; x++ {
+			var r, g, b int32
+			for _, d := range [4][2]int{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+				pr, pg, pb := pixel(2*x+d[0], 2*y+d[1])
+				r, g, b = r+pr, g+pg, b+pb
+			}
+			e.u[y*cStride+x] = uint8((-9719*r - 19081*g + 28800*b + 128<<18 + half<<2) >> 18)
+			e.v[y*cStride+x] = uint8((28800*r - 24116*g - 4684*b + 128<<18 + half<<2) >> 18)
+		}
+	}
+}
+
+// encodeMacroblock picks the prediction modes, quantizes the residuals and
+// writes the reconstruction of the macroblock.
+func (e *vp8Encoder) encodeMacroblock(mbx, mby int) {
+	mb := &e.macroblocks[mby*e.mbw+mbx]
+	yStride, cStride := 16*e.mbw, 8*e.mbw
+	x0, y0 := 16*mbx, 16*mby
+	cx0, cy0 := 8*mbx, 8*mby
+
+	var pred [256]int32
+	mb.yMode = bestMode(pred[:], 16, func(mode int, dst []int32) int {
+		predictBlock(dst, e.ry, yStride, x0, y0, 16, mode)
+		return blockError(dst, e.y, yStride, x0, y0, 16)
+	})
+	predictBlock(pred[:], e.ry, yStride, x0, y0, 16, mb.yMode)
+
+	// Transform the 16 luma blocks and move their DC coefficients into the
+	// second order block.
+	var coeffs [16][16]int32
+	var dc [16]int32
+	for n := 0; n < 16; n++ {
+		bx, by := 4*(n%4), 4*(n/4)
+		var residual [16]int32
+		for j := 0; j < 4; j++ {
+			for i := 0; i < 4; i++ {
+				residual[4*j+i] = int32(e.y[(y0+by+j)*yStride+x0+bx+i]) - pred[(by+j)*16+bx+i]
+			}
+		}
+		coeffs[n] = forwardDCT(residual)
+		dc[n] = coeffs[n][0]
+	}
+	mb.levels[blockY2] = quantize(forwardWHT(dc), e.y2)
+	for n := 0; n < 16; n++ {
+		mb.levels[n] = quantize(coeffs[n], e.y1)
+		mb.levels[n][0] = 0
+	}
+
+	// Reconstruct as the decoder does.
+	dcs := inverseWHT(dequantize(mb.levels[blockY2], e.y2))
+	for n := 0; n < 16; n++ {
+		bx, by := 4*(n%4), 4*(n/4)
+		c := dequantize(mb.levels[n], e.y1)
+		c[0] = dcs[n]
+		var block [16]int32
+		for j := 0; j < 4; j++ {
+			copy(block[4*j:4*j+4], pred[(by+j)*16+bx:])
+		}
+		inverseDCT(c, &block)
+		for j := 0; j < 4; j++ {
+			for i := 0; i < 4; i++ {
+				e.ry[(y0+by+j)*yStride+x0+bx+i] = uint8(block[4*j+i])
+			}
+		}
+	}
+
+	mb.uvMode = bestMode(pred[:], 8, func(mode int, dst []int32) int {
+		predictBlock(dst, e.ru, cStride, cx0, cy0, 8, mode)
+		err := blockError(dst, e.u, cStride, cx0, cy0, 8)
+		predictBlock(dst, e.rv, cStride, cx0, cy0, 8, mode)
+		return err + blockError(dst, e.v, cStride, cx0, cy0, 8)
+	})
+	for _, c := range []struct {
+		src, recon []uint8
+		first      int
+	}{{e.u, e.ru, firstU}, {e.v, e.rv, firstV}} {
+		predictBlock(pred[:], c.recon, cStride, cx0, cy0, 8, mb.uvMode)
+		for n := 0; n < 4; n++ {
+			bx, by := 4*(n%2), 4*(n/2)
+			var residual, block [16]int32
+			for j := 0; j < 4; j++ {
+				for i := 0; i < 4; i++ {
+					residual[4*j+i] = int32(c.src[(cy0+by+j)*cStride+cx0+bx+i]) - pred[(by+j)*8+bx+i]
+					block[4*j+i] = pred[(by+j)*8+bx+i]
+				}
+			}
+			levels := quantize(forwardDCT(residual), e.uv)
+			mb.levels[c.first+n] = levels
+			inverseDCT(dequantize(levels, e.uv), &block)
+			for j := 0; j < 4; j++ {
+				for i := 0; i < 4; i++ {
+					c.recon[(cy0+by+j)*cStride+cx0+bx+i] = uint8(block[4*j+i])
+				}
+			}
+		}
+	}
+
+	mb.skip = true
+	for _, levels := range mb.levels {
+		if levels != ([16]int16{}) {
+			mb.skip = false
+			break
+		}
+	}
+}
+
+// bestMode returns the prediction mode with the smallest error.
+func bestMode(scratch []int32, n int, cost func(mode int, dst []int32) int) int {
+	best, bestCost := predDC, -1
+	for _, mode := range []int{predDC, predV, predH, predTM} {
+		if c := cost(mode, scratch[:n*n]); bestCost < 0 || c < bestCost {
+			best, bestCost = mode, c
+		}
+	}
+	return best
+}
+
+// predictBlock fills dst with the n x n prediction of the block at x0, y0
+// from the reconstructed plane. Outside the image the row above counts as
+// 127 and the column to the left as 129.
+func predictBlock(dst []int32, plane []uint8, stride, x0, y0, n, mode int) {
+	var top, left [16]int32
+	for i := 0; i < n; i++ {
+		top[i], left[i] = 127, 129
+		if y0 > 0 {
+			top[i] = int32(plane[(y0-1)*stride+x0+i])
+		}
+		if x0 > 0 {
+			left[i] = int32(plane[(y0+i)*stride+x0-1])
+		}
+	}
+	var topLeft int32
+	switch {
+	case y0 == 0:
+		topLeft = 127
+	case x0 == 0:
+		topLeft = 129
+	default:
+		topLeft = int32(plane[(y0-1)*stride+x0-1])
+	}
+
+	switch mode {
+	case predDC:
+		shift := 3
+		if n == 16 {
+			shift = 4
+		}
+		var sumTop, sumLeft int32
+		for i := 0; i < n; i++ {
+			sumTop += top[i]
+			sumLeft += left[i]
+		}
+		// Missing edges are left out of the average.
+		var dc int32
+		switch {
+		case x0 == 0 && y0 == 0:
+			dc = 128
+		case y0 == 0:
+			dc = (sumLeft + int32(n/2)) >> shift
+		case x0 == 0:
+			dc = (sumTop + int32(n/2)) >> shift
+		default:
+			dc = (sumTop + sumLeft + int32(n)) >> (shift + 1)
+		}
+		for i := range dst[:n*n] {
+			dst[i] = dc
+		}
+	case predV:
+		for j := 0; j < n; j++ {
+			copy(dst[j*n:j*n+n], top[:n])
+		}
+	case predH:
+		for j := 0; j < n; j++ {
+			for i := 0; i < n; i++ {
+				dst[j*n+i] = left[j]
+			}
+		}
+	case predTM:
+		for j := 0; j < n; j++ {
+			for i := 0; i < n; i++ {
+				dst[j*n+i] = int32(clampByte(int(left[j] + top[i] - topLeft)))
+			}
+		}
+	}
+}
+
+// blockError is the sum of absolute differences between the prediction and
+// the source block.
+func blockError(pred []int32, plane []uint8, stride, x0, y0, n int) int {
+	sum := 0
+	for j := 0; j < n; j++ {
+		for i := 0; i < n; i++ {
+			sum += absInt(int(int32(plane[(y0+j)*stride+x0+i]) - pred[j*n+i]))
+		}
+	}
+	return sum
+}
+
+// forwardDCT transforms a 4x4 residual. VP8 coefficients are twice those of
+// the orthonormal DCT.
+func forwardDCT(in [16]int32) [16]int32 {
+	var out [16]int32
+	for u := 0; u < 4; u++ {
+		for v := 0; v < 4; v++ {
+			var sum float64
+			for y := 0; y < 4; y++ {
+				for x := 0; x < 4; x++ {
+					sum += dctBasis[u][y] * dctBasis[v][x] * float64(in[4*y+x])
+				}
+			}
+			out[4*u+v] = int32(math.Round(2 * sum))
+		}
+	}
+	return out
+}
+
+// forwardWHT transforms the DC coefficients of the 16 luma blocks, the
+// inverse of inverseWHT.
+func forwardWHT(in [16]int32) [16]int32 {
+	var m, out [16]int32
+	for i := 0; i < 4; i++ {
+		a0, a1 := in[4*i+0]+in[4*i+3], in[4*i+1]+in[4*i+2]
+		a2, a3 := in[4*i+1]-in[4*i+2], in[4*i+0]-in[4*i+3]
+		m[4*i+0], m[4*i+1], m[4*i+2], m[4*i+3] = a0+a1, a3+a2, a0-a1, a3-a2
+	}
+	for i := 0; i < 4; i++ {
+		a0, a1 := m[0+i]+m[12+i], m[4+i]+m[8+i]
+		a2, a3 := m[4+i]-m[8+i], m[0+i]-m[12+i]
+		for k, v := range [4]int32{a0 + a1, a3 + a2, a0 - a1, a3 - a2} {
+			// Divide by two, rounding half away from zero.
+			if v < 0 {
+				out[4*k+i] = -((-v + 1) >> 1)
+			} else {
+				out[4*k+i] = (v + 1) >> 1
+			}
+		}
+	}
+	return out
+}
+
+// quantize divides the coefficients by the DC and AC step sizes. Rounding
+// AC coefficients down a little more often saves bits for little error.
+func quantize(coeffs [16]int32, step [2]int32) [16]int16 {
+	var levels [16]int16
+	for i, c := range coeffs {
+		q, bias := step[1], step[1]*3/8
+		if i == 0 {
+			q, bias = step[0], step[0]/2
+		}
+		level := (absInt32(c) + bias) / q
+		level = min(level, maxLevel)
+		if c < 0 {
+			level = -level
+		}
+		levels[i] = int16(level)
+	}
+	return levels
+}
+
+func dequantize(levels [16]int16, step [2]int32) [16]int32 {
+	var coeffs [16]int32
+	for i, l := range levels {
+		if i == 0 {
+			coeffs[i] = int32(l) * step[0]
+		} else {
+			coeffs[i] = int32(l) * step[1]
+		}
+	}
+	return coeffs
+}
+
+// inverseWHT and inverseDCT match the decoder bit for bit, so that the
+// encoder predicts from exactly the pixels the decoder will have.
+
+func inverseWHT(in [16]int32) [16]int32 {
+	var m, out [16]int32
+	for i := 0; i < 4; i++ {
+		a0, a1 := in[0+i]+in[12+i], in[4+i]+in[8+i]
+		a2, a3 := in[4+i]-in[8+i], in[0+i]-in[12+i]
+		m[0+i], m[8+i], m[4+i], m[12+i] = a0+a1, a0-a1, a3+a2, a3-a2
+	}
+	for i := 0; i < 4; i++ {
+		dc := m[4*i] + 3
+		a0, a1 := dc+m[4*i+3], m[4*i+1]+m[4*i+2]
+		a2, a3 := m[4*i+1]-m[4*i+2], dc-m[4*i+3]
+		out[4*i+0] = int32(int16((a0 + a1) >> 3))
+		out[4*i+1] = int32(int16((a3 + a2) >> 3))
+		out[4*i+2] = int32(int16((a0 - a1) >> 3))
+		out[4*i+3] = int32(int16((a3 - a2) >> 3))
+	}
+	return out
+}
+
+// inverseDCT adds the inverse transform of coeffs to the 4x4 block.
+func inverseDCT(coeffs [16]int32, block *[16]int32) {
+	const (
+		c1 = 85627 // 65536 * cos(pi/8) * sqrt(2)
+		c2 = 35468 // 65536 * sin(pi/8) * sqrt(2)
+	)
+	var m [4][4]int32
+	for i := 0; i < 4; i++ {
+		a := coeffs[i] + coeffs[8+i]
+		b := coeffs[i] - coeffs[8+i]
+		c := (coeffs[4+i]*c2)>>16 - (coeffs[12+i]*c1)>>16
+		d := (coeffs[4+i]*c1)>>16 + (coeffs[12+i]*c2)>>16
+		m[i] = [4]int32{a + d, b + c, b - c, a - d}
+	}
+	for j := 0; j < 4; j++ {
+		dc := m[0][j] + 4
+		a, b := dc+m[2][j], dc-m[2][j]
+		c := (m[1][j]*c2)>>16 - (m[3][j]*c1)>>16
+		d := (m[1][j]*c1)>>16 + (m[3][j]*c2)>>16
+		for i, v := range [4]int32{a + d, b + c, b - c, a - d} {
+			block[4*j+i] = int32(clampByte(int(block[4*j+i] + v>>3)))
+		}
+	}
+}
+
+// partitions writes the first partition, with the frame header and the
+// macroblock modes, and the partition with the coefficient tokens.
+func (e *vp8Encoder) partitions() (first, tokens []byte) {
+	skipped := 0
+	for _, mb := range e.macroblocks {
+		if mb.skip {
+			skipped++
+		}
+	}
+	probNotSkipped := uint8(min(max(255-skipped*255/len(e.macroblocks), 1), 254))
+
+	h := newBoolEncoder()
+	h.putLiteral(0, 1) // color space
+	h.putLiteral(0, 1) // clamping type
+	h.putLiteral(0, 1) // no segmentation
+	// A normal loop filter, stronger for coarser quantizers.
+	h.putLiteral(0, 1)
+	h.putLiteral(uint32(min(e.qIndex/4+4, 63)), 6)
+	h.putLiteral(0, 3)
+	h.putLiteral(0, 1) // no filter deltas
+	h.putLiteral(0, 2) // one token partition
+	h.putLiteral(uint32(e.qIndex), 7)
+	for i := 0; i < 5; i++ {
+		h.putLiteral(0, 1) // no quantizer deltas
+	}
+	h.putLiteral(0, 1) // refresh_entropy_probs
+	for i := range coeffUpdateProbs {
+		for j := range coeffUpdateProbs[i] {
+			for k := range coeffUpdateProbs[i][j] {
+				for _, p := range coeffUpdateProbs[i][j][k] {
+					h.put(false, p)
+				}
+			}
+		}
+	}
+	h.putLiteral(1, 1)
+	h.putLiteral(uint32(probNotSkipped), 8)
+
+	t := newBoolEncoder()
+	up := make([]nonZero, e.mbw)
+	for mby := 0; mby < e.mbh; mby++ {
+		var left nonZero
+		for mbx := 0; mbx < e.mbw; mbx++ {
+			mb := &e.macroblocks[mby*e.mbw+mbx]
+
+			h.put(mb.skip, probNotSkipped)
+			h.put(true, 145) // 16x16 luma prediction
+			switch mb.yMode {
+			case predDC:
+				h.put(false, 156)
+				h.put(false, 163)
+			case predV:
+				h.put(false, 156)
+				h.put(true, 163)
+			case predH:
+				h.put(true, 156)
+				h.put(false, 128)
+			case predTM:
+				h.put(true, 156)
+				h.put(true, 128)
+			}
+			h.put(mb.uvMode != predDC, 142)
+			if mb.uvMode != predDC {
+				h.put(mb.uvMode != predV, 114)
+				if mb.uvMode != predV {
+					h.put(mb.uvMode == predTM, 183)
+				}
+			}
+
+			if mb.skip {
+				left, up[mbx] = nonZero{}, nonZero{}
+				continue
+			}
+			e.writeTokens(t, mb, &left, &up[mbx])
+		}
+	}
+	return h.bytes(), t.bytes()
+}
+
+func (e *vp8Encoder) writeTokens(t *boolEncoder, mb *vp8Macroblock, left, up *nonZero) {
+	nz := writeBlockTokens(t, planeY2, int(left.y2+up.y2), &mb.levels[blockY2], 0)
+	left.y2, up.y2 = nz, nz
+
+	for n := 0; n < 16; n++ {
+		x, y := n%4, n/4
+		nz := writeBlockTokens(t, planeYAfterY2, int(left.y[y]+up.y[x]), &mb.levels[n], 1)
+		left.y[y], up.y[x] = nz, nz
+	}
+	for n := 0; n < 4; n++ {
+		x, y := n%2, n/2
+		nz := writeBlockTokens(t, planeUV, int(left.u[y]+up.u[x]), &mb.levels[firstU+n], 0)
+		left.u[y], up.u[x] = nz, nz
+	}
+	for n := 0; n < 4; n++ {
+		x, y := n%2, n/2
+		nz := writeBlockTokens(t, planeUV, int(left.v[y]+up.v[x]), &mb.levels[firstV+n], 0)
+		left.v[y], up.v[x] = nz, nz
+	}
+}
+
+// writeBlockTokens codes the coefficients of one block from scan position
+// first on, and returns 1 if any was non-zero.
+func writeBlockTokens(t *boolEncoder, plane, context int, levels *[16]int16, first int) uint8 {
+	probs := &defaultCoeffProbs[plane]
+
+	last := -1
+	for i := first; i < 16; i++ {
+		if levels[zigzag[i]] != 0 {
+			last = i
+		}
+	}
+
+	p := &probs[coeffBands[first]][context]
+	if last < 0 {
+		t.put(false, p[0])
+		return 0
+	}
+	t.put(true, p[0])
+
+	for i := first; i <= last; i++ {
+		level := int(levels[zigzag[i]])
+		v := absInt(level)
+		if v == 0 {
+			// A zero is never followed by the end of block.
+			t.put(false, p[1])
+			p = &probs[coeffBands[i+1]][0]
+			continue
+		}
+		t.put(true, p[1])
+
+		if v == 1 {
+			t.put(false, p[2])
+			p = &probs[coeffBands[i+1]][1]
+		} else {
+			t.put(true, p[2])
+			switch {
+			case v <= 4:
+				t.put(false, p[3])
+				t.put(v != 2, p[4])
+				if v != 2 {
+					t.put(v == 4, p[5])
+				}
+			case v <= 10:
+				t.put(true, p[3])
+				t.put(false, p[6])
+				if v <= 6 {
+					t.put(false, p[7])
+					t.put(v == 6, 159)
+				} else {
+					t.put(true, p[7])
+					t.put((v-7)&2 != 0, 165)
+					t.put((v-7)&1 != 0, 145)
+				}
+			default:
+				t.put(true, p[3])
+				t.put(true, p[6])
+				cat := 0
+				for cat < 3 && v >= 3+8<<(cat+1) {
+					cat++
+				}
+				t.put(cat >= 2, p[8])
+				t.put(cat&1 == 1, p[9+cat>>1])
+				extra, bits := v-3-8<<cat, catProbs[cat]
+				for b, prob := range bits {
+					t.put(extra>>(len(bits)-1-b)&1 == 1, prob)
+				}
+			}
+			p = &probs[coeffBands[i+1]][2]
+		}
+
+		t.put(level < 0, 128)
+		if i < 15 {
+			t.put(i < last, p[0])
+		}
+	}
+	return 1
+}
+
+// boolEncoder is the arithmetic coder of VP8 (RFC 6386 section 7).
+type boolEncoder struct {
+	buf      []byte
+	rng      uint32
+	bottom   uint32
+	bitCount int
+}
+
+func newBoolEncoder() *boolEncoder {
+	return &boolEncoder{rng: 255, bitCount: 24}
+}
+
+// put codes bit, where prob/256 is the probability that it is false.
+func (e *boolEncoder) put(bit bool, prob uint8) {
+	split := 1 + (e.rng-1)*uint32(prob)>>8
+	if bit {
+		e.bottom += split
+		e.rng -= split
+	} else {
+		e.rng = split
+	}
+	for e.rng < 128 {
+		e.rng <<= 1
+		if e.bottom&(1<<31) != 0 {
+			e.carry()
+		}
+		e.bottom <<= 1
+		e.bitCount--
+		if e.bitCount == 0 {
+			e.buf = append(e.buf, byte(e.bottom>>24))
+			e.bottom &= 1<<24 - 1
+			e.bitCount = 8
+		}
+	}
+}
+
+// putLiteral codes the n low bits of v, most significant first, at even
+// probability.
+func (e *boolEncoder) putLiteral(v uint32, n int) {
+	for i := n - 1; i >= 0; i-- {
+		e.put(v>>i&1 == 1, 128)
+	}
+}
+
+func (e *boolEncoder) carry() {
+	for i := len(e.buf) - 1; i >= 0; i-- {
+		if e.buf[i] != 255 {
+			e.buf[i]++
+			return
+		}
+		e.buf[i] = 0
+	}
+}
+
+// bytes flushes the coder and returns the coded data.
+func (e *boolEncoder) bytes() []byte {
+	c, v := e.bitCount, e.bottom
+	if v&(1<<(32-c)) != 0 {
+		e.carry()
+	}
+	v <<= c & 7
+	for c >>= 3; c > 0; c-- {
+		v <<= 8
+	}
+	for i := 0; i < 4; i++ {
+		e.buf = append(e.buf, byte(v>>24))
+		v <<= 8
+	}
+	return e.buf
+}
+
+func absInt32(v int32) int32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,526 @@
+package imaging
+
+import (
+	"bytes"
+	"container/heap"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+)
+
+// The lossless encoder below writes VP8L (RFC 9649). It applies the
+// subtract-green and predictor transforms and entropy codes the residuals
+// with one set of prefix codes. It does not use backward references or a
+// color cache, so files are larger than those of libwebp, but any WebP
+// decoder can read them.
+
+const (
+	maxWebPSide = 1 << 14
+
+	// Predictor tiles are 1<<predictorBits pixels square.
+	predictorBits = 4
+
+	maxCodeLength           = 15
+	maxCodeLengthCodeLength = 7
+	numCodeLengthCodes      = 19
+	numLengthPrefixCodes    = 24
+	numDistanceCodes        = 40
+)
+
+var errWebPSize = errors.New("imaging: image is too large for WebP")
+
+// codeLengthCodeOrder is the order in which the code lengths of the code
+// length code are written.
+var codeLengthCodeOrder = [numCodeLengthCodes]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// predictorModes are the predictors tried for each tile. The ones that read
+// the top-right pixel are left out because of their edge cases.
+var predictorModes = []int{1, 2, 11, 12, 13}
+
+// EncodeWebP writes img to w as a WebP image. Opaque images are encoded lossy
+// at quality 1 to 100; images with transparency are encoded lossless, since
+// lossy WebP keeps alpha in a separate chunk this encoder does not write.
+func EncodeWebP(w io.Writer, img image.Image, quality int) error {
+	if opaque(img) {
+		return encodeLossy(w, img, quality)
+	}
+	return encodeLossless(w, img)
+}
+
+func opaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a != 0xffff {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func encodeLossless(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width < 1 || height < 1 || width > maxWebPSide || height > maxWebPSide {
+		return errWebPSize
+	}
+
+	// VP8L stores non-premultiplied ARGB.
+	nrgba := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(nrgba, nrgba.Rect, img, b.Min, draw.Src)
+	pix := nrgba.Pix
+
+	hasAlpha := !nrgba.Opaque()
+
+	subtractGreen(pix)
+	modes, residuals := predict(pix, width, height)
+
+	bw := &bitWriter{}
+	bw.writeBits(0x2f, 8)
+	bw.writeBits(uint32(width-1), 14)
+	bw.writeBits(uint32(height-1), 14)
+	bw.writeBits(boolBit(hasAlpha), 1)
+	bw.writeBits(0, 3)
+
+	// Transforms are undone in the reverse order, so the decoder first
+	// reverses the predictor and then adds green back.
+	bw.writeBits(1, 1)
+	bw.writeBits(2, 2)
+	bw.writeBits(1, 1)
+	bw.writeBits(0, 2)
+	bw.writeBits(predictorBits-2, 3)
+	bw.writeBits(0, 1)
+	writeEntropyCodedImage(bw, modes)
+	bw.writeBits(0, 1)
+
+	// No color cache and a single prefix code group.
+	bw.writeBits(0, 1)
+	bw.writeBits(0, 1)
+	writeEntropyCodedImage(bw, residuals)
+
+	return writeRIFF(w, "VP8L", bw.bytes())
+}
+
+func subtractGreen(pix []byte) {
+	for p := 0; p < len(pix); p += 4 {
+		pix[p+0] -= pix[p+1]
+		pix[p+2] -= pix[p+1]
+	}
+}
+
+// predict picks a predictor for every tile and returns the tile image, with
+// the mode in the green channel, and the residuals of all pixels.
+func predict(pix []byte, width, height int) (modes []byte, residuals []byte) {
+	tilesX := (width + 1<<predictorBits - 1) >> predictorBits
+	tilesY := (height + 1<<predictorBits - 1) >> predictorBits
+	modes = make([]byte, 4*tilesX*tilesY)
+	residuals = make([]byte, len(pix))
+
+	for ty := 0; ty < tilesY; ty++ {
+		for tx := 0; tx < tilesX; tx++ {
+			x0, y0 := tx<<predictorBits, ty<<predictorBits
+			x1, y1 := min(x0+1<<predictorBits, width), min(y0+1<<predictorBits, height)
+
+			best, bestCost := predictorModes[0], -1
+			for _, mode := range predictorModes {
+				cost := 0
+				for y := y0; y < y1; y++ {
+					for x := x0; x < x1; x++ {
+						p := 4 * (y*width + x)
+						pred := predictPixel(pix, width, x, y, mode)
+						for c := 0; c < 4; c++ {
+							cost += absInt(int(int8(pix[p+c] - pred[c])))
+						}
+					}
+				}
+				if bestCost < 0 || cost < bestCost {
+					best, bestCost = mode, cost
+				}
+			}
+
+			t := 4 * (ty*tilesX + tx)
+			modes[t+1], modes[t+3] = byte(best), 0xff
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					p := 4 * (y*width + x)
+					pred := predictPixel(pix, width, x, y, best)
+					for c := 0; c < 4; c++ {
+						residuals[p+c] = pix[p+c] - pred[c]
+					}
+				}
+			}
+		}
+	}
+	return modes, residuals
+}
+
+// predictPixel returns the prediction for the pixel at x, y. The first row
+// and column use fixed predictors regardless of mode.
+func predictPixel(pix []byte, width, x, y, mode int) [4]byte {
+	p := 4 * (y*width + x)
+	switch {
+	case x == 0 && y == 0:
+		return [4]byte{0, 0, 0, 0xff}
+	case y == 0:
+		mode = 1
+	case x == 0:
+		mode = 2
+	}
+
+	l, t, tl := p-4, p-4*width, p-4*width-4
+	var pred [4]byte
+	switch mode {
+	case 1:
+		copy(pred[:], pix[l:l+4])
+	case 2:
+		copy(pred[:], pix[t:t+4])
+	case 11:
+		distL, distT := 0, 0
+		for c := 0; c < 4; c++ {
+			distL += absInt(int(pix[tl+c]) - int(pix[t+c]))
+			distT += absInt(int(pix[tl+c]) - int(pix[l+c]))
+		}
+		if distL < distT {
+			copy(pred[:], pix[l:l+4])
+		} else {
+			copy(pred[:], pix[t:t+4])
+		}
+	case 12:
+		for c := 0; c < 4; c++ {
+			pred[c] = clampByte(int(pix[l+c]) + int(pix[t+c]) - int(pix[tl+c]))
+		}
+	case 13:
+		for c := 0; c < 4; c++ {
+			avg := (int(pix[l+c]) + int(pix[t+c])) / 2
+			pred[c] = clampByte(avg + (avg-int(pix[tl+c]))/2)
+		}
+	}
+	return pred
+}
+
+// writeEntropyCodedImage writes the five prefix codes for pix followed by
+// its pixels as literals.
+func writeEntropyCodedImage(bw *bitWriter, pix []byte) {
+	green := make([]uint32, 256+numLengthPrefixCodes)
+	red := make([]uint32, 256)
+	blue := make([]uint32, 256)
+	alpha := make([]uint32, 256)
+	for p := 0; p < len(pix); p += 4 {
+		red[pix[p+0]]++
+		green[pix[p+1]]++
+		blue[pix[p+2]]++
+		alpha[pix[p+3]]++
+	}
+
+	codes := [5]*prefixCode{
+		newPrefixCode(green, maxCodeLength),
+		newPrefixCode(red, maxCodeLength),
+		newPrefixCode(blue, maxCodeLength),
+		newPrefixCode(alpha, maxCodeLength),
+		newPrefixCode(make([]uint32, numDistanceCodes), maxCodeLength),
+	}
+	for _, code := range codes {
+		code.writeTo(bw)
+	}
+
+	for p := 0; p < len(pix); p += 4 {
+		codes[0].writeSymbol(bw, int(pix[p+1]))
+		codes[1].writeSymbol(bw, int(pix[p+0]))
+		codes[2].writeSymbol(bw, int(pix[p+2]))
+		codes[3].writeSymbol(bw, int(pix[p+3]))
+	}
+}
+
+// prefixCode is a canonical Huffman code.
+type prefixCode struct {
+	lengths []uint8
+	codes   []uint16
+	// single is the only symbol of a code with one symbol, which takes no
+	// bits to write, or -1.
+	single int
+}
+
+func newPrefixCode(histogram []uint32, maxLength int) *prefixCode {
+	code := &prefixCode{lengths: huffmanLengths(histogram, maxLength), single: -1}
+
+	used := 0
+	for symbol, length := range code.lengths {
+		if length > 0 {
+			used++
+			code.single = symbol
+		}
+	}
+	if used != 1 {
+		code.single = -1
+	}
+
+	var count [maxCodeLength + 1]uint16
+	for _, length := range code.lengths {
+		count[length]++
+	}
+	count[0] = 0
+	var next [maxCodeLength + 1]uint16
+	for length, c := 1, uint16(0); length <= maxCodeLength; length++ {
+		c = (c + count[length-1]) << 1
+		next[length] = c
+	}
+
+	code.codes = make([]uint16, len(code.lengths))
+	for symbol, length := range code.lengths {
+		if length > 0 {
+			code.codes[symbol] = reverseBits(next[length], length)
+			next[length]++
+		}
+	}
+	return code
+}
+
+func (c *prefixCode) writeSymbol(bw *bitWriter, symbol int) {
+	if c.single >= 0 {
+		return
+	}
+	bw.writeBits(uint32(c.codes[symbol]), uint(c.lengths[symbol]))
+}
+
+// writeTo writes the code lengths, using the simple form for codes with at
+// most one symbol below 256.
+func (c *prefixCode) writeTo(bw *bitWriter) {
+	if c.single < 0 && !c.hasSymbols() || c.single >= 0 && c.single < 256 {
+		symbol := max(c.single, 0)
+		bw.writeBits(1, 1)
+		bw.writeBits(0, 1)
+		if symbol < 2 {
+			bw.writeBits(0, 1)
+			bw.writeBits(uint32(symbol), 1)
+		} else {
+			bw.writeBits(1, 1)
+			bw.writeBits(uint32(symbol), 8)
+		}
+		return
+	}
+
+	tokens := codeLengthTokens(c.lengths)
+	histogram := make([]uint32, numCodeLengthCodes)
+	for _, t := range tokens {
+		histogram[t.symbol]++
+	}
+	lengthCode := newPrefixCode(histogram, maxCodeLengthCodeLength)
+
+	n := numCodeLengthCodes
+	for n > 4 && lengthCode.lengths[codeLengthCodeOrder[n-1]] == 0 {
+		n--
+	}
+	bw.writeBits(0, 1)
+	bw.writeBits(uint32(n-4), 4)
+	for _, symbol := range codeLengthCodeOrder[:n] {
+		bw.writeBits(uint32(lengthCode.lengths[symbol]), 3)
+	}
+
+	// Code lengths are given for the whole alphabet.
+	bw.writeBits(0, 1)
+	for _, t := range tokens {
+		lengthCode.writeSymbol(bw, t.symbol)
+		switch t.symbol {
+		case 17:
+			bw.writeBits(uint32(t.extra), 3)
+		case 18:
+			bw.writeBits(uint32(t.extra), 7)
+		}
+	}
+}
+
+func (c *prefixCode) hasSymbols() bool {
+	for _, length := range c.lengths {
+		if length > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+type codeLengthToken struct {
+	symbol int
+	extra  int
+}
+
+// codeLengthTokens run-length encodes the zeros in lengths with the repeat
+// codes 17 (3 to 10 zeros) and 18 (11 to 138 zeros).
+func codeLengthTokens(lengths []uint8) []codeLengthToken {
+	var tokens []codeLengthToken
+	for i := 0; i < len(lengths); {
+		if lengths[i] != 0 {
+			tokens = append(tokens, codeLengthToken{symbol: int(lengths[i])})
+			i++
+			continue
+		}
+
+		run := 1
+		for i+run < len(lengths) && lengths[i+run] == 0 && run < 138 {
+			run++
+		}
+		switch {
+		case run >= 11:
+			tokens = append(tokens, codeLengthToken{symbol: 18, extra: run - 11})
+		case run >= 3:
+			tokens = append(tokens, codeLengthToken{symbol: 17, extra: run - 3})
+		default:
+			for j := 0; j < run; j++ {
+				tokens = append(tokens, codeLengthToken{symbol: 0})
+			}
+		}
+		i += run
+	}
+	return tokens
+}
+
+// huffmanLengths returns Huffman code lengths of at most maxLength bits for
+// the histogram. When the optimal code is too deep, the counts are flattened
+// and the code rebuilt until it fits.
+func huffmanLengths(histogram []uint32, maxLength int) []uint8 {
+	counts := append([]uint32{}, histogram...)
+	for {
+		lengths, depth := buildHuffman(counts)
+		if depth <= maxLength {
+			return lengths
+		}
+		for i, c := range counts {
+			if c > 0 {
+				counts[i] = c>>1 | 1
+			}
+		}
+	}
+}
+
+type huffmanNode struct {
+	count       uint64
+	symbol      int
+	left, right *huffmanNode
+}
+
+type huffmanHeap []*huffmanNode
+
+func (h huffmanHeap) Len() int { return len(h) }
+func (h huffmanHeap) Less(i, j int) bool {
+	if h[i].count != h[j].count {
+		return h[i].count < h[j].count
+	}
+	return h[i].symbol < h[j].symbol
+}
+func (h huffmanHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *huffmanHeap) Push(x interface{}) { *h = append(*h, x.(*huffmanNode)) }
+func (h *huffmanHeap) Pop() interface{} {
+	old := *h
+	n := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return n
+}
+
+// buildHuffman returns the code lengths of an optimal prefix code and the
+// longest length. A single used symbol gets length 1.
+func buildHuffman(counts []uint32) ([]uint8, int) {
+	lengths := make([]uint8, len(counts))
+
+	var h huffmanHeap
+	for symbol, c := range counts {
+		if c > 0 {
+			h = append(h, &huffmanNode{count: uint64(c), symbol: symbol})
+		}
+	}
+	switch len(h) {
+	case 0:
+		return lengths, 0
+	case 1:
+		lengths[h[0].symbol] = 1
+		return lengths, 1
+	}
+
+	heap.Init(&h)
+	next := len(counts)
+	for h.Len() > 1 {
+		a := heap.Pop(&h).(*huffmanNode)
+		b := heap.Pop(&h).(*huffmanNode)
+		heap.Push(&h, &huffmanNode{count: a.count + b.count, symbol: next, left: a, right: b})
+		next++
+	}
+
+	depth := 0
+	type entry struct {
+		node  *huffmanNode
+		depth int
+	}
+	stack := []entry{{h[0], 0}}
+	for len(stack) > 0 {
+		e := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if e.node.left == nil {
+			lengths[e.node.symbol] = uint8(e.depth)
+			depth = max(depth, e.depth)
+			continue
+		}
+		stack = append(stack, entry{e.node.left, e.depth + 1}, entry{e.node.right, e.depth + 1})
+	}
+	return lengths, depth
+}
+
+// bitWriter packs bits least significant bit first, as VP8L reads them.
+type bitWriter struct {
+	buf   bytes.Buffer
+	bits  uint64
+	nBits uint
+}
+
+func (w *bitWriter) writeBits(v uint32, n uint) {
+	w.bits |= uint64(v) << w.nBits
+	w.nBits += n
+	for w.nBits >= 8 {
+		w.buf.WriteByte(byte(w.bits))
+		w.bits >>= 8
+		w.nBits -= 8
+	}
+}
+
+func (w *bitWriter) bytes() []byte {
+	if w.nBits > 0 {
+		w.buf.WriteByte(byte(w.bits))
+		w.bits, w.nBits = 0, 0
+	}
+	return w.buf.Bytes()
+}
+
+func reverseBits(v uint16, n uint8) uint16 {
+	var r uint16
+	for i := uint8(0); i < n; i++ {
+		r = r<<1 | v&1
+		v >>= 1
+	}
+	return r
+}
+
+func boolBit(b bool) uint32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func clampByte(v int) byte {
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return byte(v)
+}
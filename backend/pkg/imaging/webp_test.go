@@ -0,0 +1,183 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+func TestEncodeWebPRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	noisy := image.NewNRGBA(image.Rect(0, 0, 67, 45))
+	for y := 0; y < 45; y++ {
+		for x := 0; x < 67; x++ {
+			noisy.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(x * 3),
+				G: uint8(y*5 + rng.Intn(8)),
+				B: uint8(rng.Intn(256)),
+				A: 0xff,
+			})
+		}
+	}
+
+	translucent := image.NewNRGBA(image.Rect(0, 0, 33, 17))
+	for y := 0; y < 17; y++ {
+		for x := 0; x < 33; x++ {
+			translucent.SetNRGBA(x, y, color.NRGBA{R: 200, G: uint8(x), B: 10, A: uint8(y * 15)})
+		}
+	}
+
+	solid := image.NewNRGBA(image.Rect(0, 0, 40, 40))
+	for i := range solid.Pix {
+		solid.Pix[i] = 0x80
+	}
+
+	pixel := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	pixel.SetNRGBA(0, 0, color.NRGBA{R: 1, G: 2, B: 3, A: 4})
+
+	for name, img := range map[string]*image.NRGBA{"noisy": noisy, "translucent": translucent, "solid": solid, "pixel": pixel} {
+		var buf bytes.Buffer
+		if err := encodeLossless(&buf, img); err != nil {
+			t.Fatalf("%s: EncodeWebP: %v", name, err)
+		}
+		if buf.Len()%2 != 0 {
+			t.Errorf("%s: RIFF data has odd length %d", name, buf.Len())
+		}
+
+		decoded, err := webp.Decode(&buf)
+		if err != nil {
+			t.Fatalf("%s: Decode: %v", name, err)
+		}
+		if decoded.Bounds() != img.Bounds() {
+			t.Fatalf("%s: bounds %v, want %v", name, decoded.Bounds(), img.Bounds())
+		}
+		for y := 0; y < img.Rect.Dy(); y++ {
+			for x := 0; x < img.Rect.Dx(); x++ {
+				got := color.NRGBAModel.Convert(decoded.At(x, y)).(color.NRGBA)
+				if want := img.NRGBAAt(x, y); got != want {
+					t.Fatalf("%s: pixel (%d, %d) = %v, want %v", name, x, y, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestHuffmanLengthsLimit(t *testing.T) {
+	// Fibonacci counts give the deepest possible optimal code.
+	counts := make([]uint32, 30)
+	a, b := uint32(1), uint32(1)
+	for i := range counts {
+		counts[i] = a
+		a, b = b, a+b
+	}
+
+	lengths := huffmanLengths(counts, 7)
+	kraft := 0.0
+	for i, l := range lengths {
+		if l == 0 || l > 7 {
+			t.Fatalf("symbol %d has length %d", i, l)
+		}
+		kraft += 1 / float64(uint(1)<<l)
+	}
+	if kraft != 1 {
+		t.Fatalf("code is not complete, Kraft sum %v", kraft)
+	}
+}
+
+func TestEncodeWebPLossy(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	img := image.NewRGBA(image.Rect(0, 0, 100, 70))
+	for y := 0; y < 70; y++ {
+		for x := 0; x < 100; x++ {
+			n := uint8(rng.Intn(6))
+			img.SetRGBA(x, y, color.RGBA{R: uint8(2*x) + n, G: uint8(3*y) + n, B: uint8(x + y), A: 0xff})
+		}
+	}
+	// A hard edge exercises the larger coefficient tokens.
+	for y := 20; y < 40; y++ {
+		for x := 30; x < 60; x++ {
+			img.SetRGBA(x, y, color.RGBA{R: 250, G: 10, B: 10, A: 0xff})
+		}
+	}
+
+	for _, quality := range []int{20, 82, 100} {
+		var buf bytes.Buffer
+		if err := EncodeWebP(&buf, img, quality); err != nil {
+			t.Fatalf("quality %d: EncodeWebP: %v", quality, err)
+		}
+		if string(buf.Bytes()[12:16]) != "VP8 " {
+			t.Fatalf("quality %d: opaque image was not encoded lossy", quality)
+		}
+
+		decoded, err := webp.Decode(&buf)
+		if err != nil {
+			t.Fatalf("quality %d: Decode: %v", quality, err)
+		}
+		ycbcr, ok := decoded.(*image.YCbCr)
+		if !ok || ycbcr.Rect != img.Rect {
+			t.Fatalf("quality %d: decoded %T %v", quality, decoded, decoded.Bounds())
+		}
+
+		source := &vp8Encoder{width: 100, height: 70, mbw: 7, mbh: 5}
+		source.loadPlanes(img)
+		var sse float64
+		for y := 0; y < 70; y++ {
+			for x := 0; x < 100; x++ {
+				d := float64(ycbcr.Y[y*ycbcr.YStride+x]) - float64(source.y[y*16*source.mbw+x])
+				sse += d * d
+			}
+		}
+		psnr := 10 * math.Log10(255*255/(sse/7000))
+		if want := 30.0 + float64(quality)/10; psnr < want {
+			t.Errorf("quality %d: luma PSNR %.1f dB, want at least %.1f", quality, psnr, want)
+		}
+	}
+}
+
+func TestBoolEncoderCarry(t *testing.T) {
+	// Long runs of unlikely bits push carries through 0xff bytes.
+	e := newBoolEncoder()
+	for i := 0; i < 2000; i++ {
+		e.put(i%7 != 0, 250)
+	}
+	data := e.bytes()
+
+	// Decode with the reference algorithm of RFC 6386 section 7.3.
+	value, rng, bitCount, pos := uint32(0), uint32(255), 0, 0
+	next := func() uint32 {
+		if pos < len(data) {
+			pos++
+			return uint32(data[pos-1])
+		}
+		return 0
+	}
+	value = next()<<8 | next()
+	for i := 0; i < 2000; i++ {
+		split := 1 + (rng-1)*250>>8
+		bigSplit := split << 8
+		bit := value >= bigSplit
+		if bit {
+			rng -= split
+			value -= bigSplit
+		} else {
+			rng = split
+		}
+		for rng < 128 {
+			value <<= 1
+			rng <<= 1
+			if bitCount++; bitCount == 8 {
+				bitCount = 0
+				value |= next()
+			}
+		}
+		if bit != (i%7 != 0) {
+			t.Fatalf("bit %d decoded as %v", i, bit)
+		}
+	}
+}
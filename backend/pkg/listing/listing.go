@@ -0,0 +1,81 @@
+// Package listing defines the lifecycle of a property listing. Landlords
+// write drafts and submit them for review; a reviewer publishes or rejects
+// them. Published listings can be paused and resumed, and go back to review
+// when their owner changes reviewed content. Every listing can be archived
+// instead of being deleted.
+package listing
+
+// Listing states
+const (
+	Draft         = "draft"
+	PendingReview = "pending_review"
+	Published     = "published"
+	Paused        = "paused"
+	Archived      = "archived"
+)
+
+// States lists every listing state in lifecycle order.
+var States = []string{Draft, PendingReview, Published, Paused, Archived}
+
+// Actor is who changes the state of a listing.
+type Actor int
+
+const (
+	// Owner is the landlord of the listing.
+	Owner Actor = iota
+	// Reviewer is an administrator reviewing submitted listings.
+	Reviewer
+	// System is the server resubmitting a listing whose owner edited
+	// reviewed content.
+	System
+)
+
+// transitions maps each actor to the states they can move a listing to from
+// each state.
+var transitions = map[Actor]map[string][]string{
+	Owner: {
+		Draft:         {PendingReview, Archived},
+		PendingReview: {Draft, Archived},
+		Published:     {Paused, Archived},
+		Paused:        {Published, Archived},
+		Archived:      {Draft},
+	},
+	Reviewer: {
+		PendingReview: {Published, Draft},
+	},
+	System: {
+		Published: {PendingReview},
+		Paused:    {PendingReview},
+	},
+}
+
+// Valid reports whether state is a listing state.
+func Valid(state string) bool {
+	for _, s := range States {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the states actor can move a listing in state from to.
+func Next(actor Actor, from string) []string {
+	return transitions[actor][from]
+}
+
+// CanTransition reports whether actor may move a listing from one state to
+// another.
+func CanTransition(actor Actor, from, to string) bool {
+	for _, s := range Next(actor, from) {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Public reports whether listings in state are visible to everyone.
+func Public(state string) bool {
+	return state == Published
+}
@@ -0,0 +1,68 @@
+package listing
+
+import "testing"
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		actor    Actor
+		from, to string
+		want     bool
+	}{
+		{Owner, Draft, PendingReview, true},
+		{Owner, Draft, Published, false},
+		{Owner, PendingReview, Published, false},
+		{Owner, PendingReview, Draft, true},
+		{Owner, Published, Paused, true},
+		{Owner, Paused, Published, true},
+		{Owner, Published, Draft, false},
+		{Owner, Archived, Draft, true},
+		{Owner, Archived, Published, false},
+		{Owner, Draft, Draft, false},
+		{Reviewer, PendingReview, Published, true},
+		{Reviewer, PendingReview, Draft, true},
+		{Reviewer, Draft, Published, false},
+		{Reviewer, Paused, Published, false},
+		{System, Published, PendingReview, true},
+		{System, Paused, PendingReview, true},
+		{System, Draft, PendingReview, false},
+		{System, PendingReview, Published, false},
+		{Owner, "", Published, false},
+		{Owner, Draft, "rented", false},
+	}
+	for _, tt := range tests {
+		if got := CanTransition(tt.actor, tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransition(%d, %q, %q) = %v, want %v", tt.actor, tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestEveryStateCanBeArchived(t *testing.T) {
+	for _, state := range States {
+		if state != Archived && !CanTransition(Owner, state, Archived) {
+			t.Errorf("owner cannot archive a %s listing", state)
+		}
+	}
+}
+
+func TestTransitionsUseValidStates(t *testing.T) {
+	for actor, from := range transitions {
+		for state, next := range from {
+			if !Valid(state) {
+				t.Errorf("actor %d: unknown state %q", actor, state)
+			}
+			for _, to := range next {
+				if !Valid(to) || to == state {
+					t.Errorf("actor %d: invalid transition %q -> %q", actor, state, to)
+				}
+			}
+		}
+	}
+}
+
+func TestPublic(t *testing.T) {
+	for _, state := range States {
+		if Public(state) != (state == Published) {
+			t.Errorf("Public(%q) = %v", state, Public(state))
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"rent-help-backend/pkg/oidc/oidctest"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func provider(m *oidctest.Issuer) *Provider {
+	return NewProvider(Config{
+		Issuer:      m.URL,
+		ClientID:    "client",
+		RedirectURL: "http://localhost/callback",
+	}, m.Client())
+}
+
+func TestChallengeRFC7636(t *testing.T) {
+	// Appendix B of RFC 7636.
+	got := Challenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	if want := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"; got != want {
+		t.Fatalf("Challenge() = %q, want %q", got, want)
+	}
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	m := oidctest.NewIssuer(t)
+	p := provider(m)
+	ctx := context.Background()
+
+	verifier, _ := GenerateVerifier()
+	authURL, err := p.AuthCodeURL(ctx, "state", "nonce", Challenge(verifier))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _ := url.Parse(authURL)
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("state") != "state" || query.Get("client_id") != "client" {
+		t.Fatalf("unexpected authorization URL %s", authURL)
+	}
+
+	m.Code = "code"
+	m.Challenge = query.Get("code_challenge")
+	m.Claims = jwt.MapClaims{
+		"iss":            m.URL,
+		"aud":            "client",
+		"sub":            "user-1",
+		"email":          "user@example.com",
+		"email_verified": true,
+		"nonce":          "nonce",
+		"exp":            time.Now().Add(time.Hour).Unix(),
+	}
+
+	if _, err := p.Exchange(ctx, "code", "wrong-verifier"); err == nil {
+		t.Fatal("Exchange accepted a wrong code verifier")
+	}
+
+	token, err := p.Exchange(ctx, "code", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := p.VerifyIDToken(ctx, token.IDToken, "nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "user-1" || claims.Email != "user@example.com" || !claims.EmailVerified {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+}
+
+func TestVerifyIDTokenRejects(t *testing.T) {
+	m := oidctest.NewIssuer(t)
+	p := provider(m)
+	ctx := context.Background()
+
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":   m.URL,
+			"aud":   "client",
+			"sub":   "user-1",
+			"nonce": "nonce",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+		}
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(jwt.MapClaims)
+		nonce  string
+		want   error
+	}{
+		{"wrong audience", func(c jwt.MapClaims) { c["aud"] = "other" }, "nonce", ErrInvalidIDToken},
+		{"wrong issuer", func(c jwt.MapClaims) { c["iss"] = "https://evil.example" }, "nonce", ErrInvalidIDToken},
+		{"expired", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }, "nonce", ErrInvalidIDToken},
+		{"no expiry", func(c jwt.MapClaims) { delete(c, "exp") }, "nonce", ErrInvalidIDToken},
+		{"nonce mismatch", func(c jwt.MapClaims) {}, "other", ErrNonceMismatch},
+	}
+	for _, tt := range tests {
+		claims := valid()
+		tt.mutate(claims)
+		_, err := p.VerifyIDToken(ctx, m.Sign(t, claims), tt.nonce)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	// A token signed by a key the provider does not publish.
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+	forged := jwt.NewWithClaims(jwt.SigningMethodRS256, valid())
+	forged.Header["kid"] = "test"
+	signed, _ := forged.SignedString(other)
+	if _, err := p.VerifyIDToken(ctx, signed, "nonce"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("forged token: err = %v, want %v", err, ErrInvalidIDToken)
+	}
+}
@@ -0,0 +1,108 @@
+// Package pagination parses the paging parameters shared by list endpoints
+// and encodes keyset cursors.
+//
+// A cursor records the sort it was issued for together with the sort value
+// and id of the last item on a page, so the next page can be fetched with a
+// range query instead of an ever-growing skip. Cursors are opaque to clients:
+// they are base64url-encoded JSON and must be passed back unchanged.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrCursorSort    = errors.New("cursor was issued for a different sort")
+)
+
+// Sort is a field name and direction, written "field" for ascending and
+// "-field" for descending.
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort parses s, which must name one of allowed. An empty s yields def.
+func ParseSort(s string, allowed []string, def Sort) (Sort, error) {
+	if s == "" {
+		return def, nil
+	}
+
+	sort := Sort{Field: strings.TrimPrefix(s, "-"), Desc: strings.HasPrefix(s, "-")}
+	for _, field := range allowed {
+		if sort.Field == field {
+			return sort, nil
+		}
+	}
+	return Sort{}, errors.New("sort must be one of: " + strings.Join(allowed, ", ") + ", optionally prefixed with -")
+}
+
+func (s Sort) String() string {
+	if s.Desc {
+		return "-" + s.Field
+	}
+	return s.Field
+}
+
+// ParseLimit parses a page size. An empty s yields def and values above max
+// are capped to max.
+func ParseLimit(s string, def, max int64) (int64, error) {
+	if s == "" {
+		return def, nil
+	}
+	limit, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || limit <= 0 {
+		return 0, errors.New("limit must be a positive integer")
+	}
+	if limit > max {
+		limit = max
+	}
+	return limit, nil
+}
+
+// Cursor points just past the last item of a page. Value is the item's sort
+// value; times are stored as Unix milliseconds, matching MongoDB's precision.
+type Cursor struct {
+	Sort  string  `json:"s"`
+	Value float64 `json:"v"`
+	ID    string  `json:"id"`
+}
+
+// Time returns Value as a time, for cursors over date fields.
+func (c Cursor) Time() time.Time {
+	return time.UnixMilli(int64(c.Value)).UTC()
+}
+
+// TimeValue converts a time to a cursor value.
+func TimeValue(t time.Time) float64 {
+	return float64(t.UnixMilli())
+}
+
+// Encode returns the opaque string form of c.
+func Encode(c Cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode parses a cursor and checks that it belongs to sort.
+func Decode(s string, sort Sort) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	if c.Sort != sort.String() {
+		return nil, ErrCursorSort
+	}
+	return &c, nil
+}
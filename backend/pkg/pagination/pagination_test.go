@@ -0,0 +1,90 @@
+package pagination
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSort(t *testing.T) {
+	allowed := []string{"price", "created_at"}
+	def := Sort{Field: "created_at", Desc: true}
+
+	tests := []struct {
+		in   string
+		want Sort
+	}{
+		{"", def},
+		{"price", Sort{Field: "price"}},
+		{"-price", Sort{Field: "price", Desc: true}},
+		{"created_at", Sort{Field: "created_at"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseSort(tt.in, allowed, def)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseSort(%q) = %+v, %v; want %+v", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"rating", "--price", "+price"} {
+		if _, err := ParseSort(in, allowed, def); err == nil {
+			t.Errorf("ParseSort(%q) succeeded", in)
+		}
+	}
+
+	if s := (Sort{Field: "price", Desc: true}).String(); s != "-price" {
+		t.Fatalf("String = %q", s)
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"", 10},
+		{"5", 5},
+		{"1000", 100},
+	}
+	for _, tt := range tests {
+		got, err := ParseLimit(tt.in, 10, 100)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseLimit(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"0", "-1", "ten"} {
+		if _, err := ParseLimit(in, 10, 100); err == nil {
+			t.Errorf("ParseLimit(%q) succeeded", in)
+		}
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	sort := Sort{Field: "price"}
+	c := Cursor{Sort: sort.String(), Value: 1234.56, ID: "65a1b2c3d4e5f6a7b8c9d0e1"}
+
+	got, err := Decode(Encode(c), sort)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if *got != c {
+		t.Fatalf("Decode = %+v, want %+v", *got, c)
+	}
+
+	if _, err := Decode(Encode(c), Sort{Field: "price", Desc: true}); err != ErrCursorSort {
+		t.Fatalf("Decode with other sort = %v, want ErrCursorSort", err)
+	}
+	for _, s := range []string{"", "!!!", Encode(Cursor{Sort: "price"})} {
+		if _, err := Decode(s, sort); err != ErrInvalidCursor {
+			t.Errorf("Decode(%q) = %v, want ErrInvalidCursor", s, err)
+		}
+	}
+}
+
+func TestCursorTime(t *testing.T) {
+	created := time.Date(2025, 3, 1, 12, 30, 45, 123000000, time.UTC)
+	c := Cursor{Value: TimeValue(created)}
+	if got := c.Time(); !got.Equal(created) {
+		t.Fatalf("Time = %v, want %v", got, created)
+	}
+}
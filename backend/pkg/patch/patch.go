@@ -0,0 +1,79 @@
+// Package patch turns typed partial-update requests into MongoDB $set
+// documents.
+//
+// A patch struct lists the fields a client may change. Every field is a
+// pointer, a slice or a pointer to a nested patch struct, so that fields
+// missing from the request can be told apart from zero values. Fields are
+// named by their bson tag and nested structs produce dotted paths such as
+// "preferences.currency".
+package patch
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Fields returns the fields that are set in patch, keyed by their dotted bson
+// path. patch must be a struct or a pointer to one.
+func Fields(patch interface{}) map[string]interface{} {
+	fields := make(map[string]interface{})
+	v := reflect.ValueOf(patch)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return fields
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Struct {
+		collect(fields, "", v)
+	}
+	return fields
+}
+
+func collect(fields map[string]interface{}, prefix string, v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := fieldName(field)
+		if name == "" {
+			continue
+		}
+		path := prefix + name
+
+		value := v.Field(i)
+		switch value.Kind() {
+		case reflect.Ptr:
+			if value.IsNil() {
+				continue
+			}
+			elem := value.Elem()
+			if elem.Kind() == reflect.Struct && elem.Type() != timeType {
+				collect(fields, path+".", elem)
+				continue
+			}
+			fields[path] = elem.Interface()
+		case reflect.Slice, reflect.Map:
+			if value.IsNil() {
+				continue
+			}
+			fields[path] = value.Interface()
+		}
+	}
+}
+
+func fieldName(field reflect.StructField) string {
+	tag := field.Tag.Get("bson")
+	if tag == "-" {
+		return ""
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name
+	}
+	return strings.ToLower(field.Name)
+}
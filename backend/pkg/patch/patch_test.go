@@ -0,0 +1,64 @@
+package patch
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type preferencesPatch struct {
+	Currency *string `bson:"currency"`
+	Language *string `bson:"language"`
+}
+
+type profilePatch struct {
+	Name        *string           `bson:"full_name"`
+	Age         *int              `bson:"age"`
+	Born        *time.Time        `bson:"born"`
+	Languages   []string          `bson:"languages"`
+	Preferences *preferencesPatch `bson:"preferences"`
+	Ignored     *string           `bson:"-"`
+	hidden      *string
+}
+
+func TestFieldsSkipsMissingFields(t *testing.T) {
+	got := Fields(&profilePatch{})
+	if len(got) != 0 {
+		t.Fatalf("Fields() = %v, want empty", got)
+	}
+}
+
+func TestFieldsFlattensNestedStructs(t *testing.T) {
+	name := "Ada"
+	currency := "EUR"
+	zero := 0
+	born := time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC)
+	hidden := "x"
+
+	got := Fields(&profilePatch{
+		Name:        &name,
+		Age:         &zero,
+		Born:        &born,
+		Languages:   []string{},
+		Preferences: &preferencesPatch{Currency: &currency},
+		Ignored:     &hidden,
+		hidden:      &hidden,
+	})
+	want := map[string]interface{}{
+		"full_name":            "Ada",
+		"age":                  0,
+		"born":                 born,
+		"languages":            []string{},
+		"preferences.currency": "EUR",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Fields() = %v, want %v", got, want)
+	}
+}
+
+func TestFieldsNilPatch(t *testing.T) {
+	var p *profilePatch
+	if got := Fields(p); len(got) != 0 {
+		t.Fatalf("Fields(nil) = %v, want empty", got)
+	}
+}
@@ -0,0 +1,47 @@
+package sms
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync/atomic"
+	"time"
+)
+
+// Message represents a text message
+type Message struct {
+	To   string
+	Body string
+}
+
+// Sender sends text messages
+type Sender interface {
+	Send(msg Message) error
+}
+
+// OutboxSender writes every message as a .txt file into a directory instead
+// of delivering it. It is meant for local development and tests.
+type OutboxSender struct {
+	dir     string
+	counter uint64
+}
+
+// NewOutboxSender creates a sender that writes messages to dir
+func NewOutboxSender(dir string) *OutboxSender {
+	return &OutboxSender{dir: dir}
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// Send writes the message to the outbox directory
+func (s *OutboxSender) Send(msg Message) error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+
+	seq := atomic.AddUint64(&s.counter, 1)
+	name := fmt.Sprintf("%d-%d-%s.txt", time.Now().UnixNano(), seq, unsafeFileChars.ReplaceAllString(msg.To, "_"))
+	content := fmt.Sprintf("To: %s\nDate: %s\n\n%s", msg.To, time.Now().Format(time.RFC1123Z), msg.Body)
+	return os.WriteFile(filepath.Join(s.dir, name), []byte(content), 0o644)
+}
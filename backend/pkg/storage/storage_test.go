@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalStorePutDelete(t *testing.T) {
+	dir := t.TempDir()
+	store := NewLocalStore(dir, "http://localhost:8080/uploads/")
+
+	key := "properties/abc/photo.jpg"
+	if err := store.Put(key, strings.NewReader("image data"), "image/jpeg"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "properties", "abc", "photo.jpg"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(data) != "image data" {
+		t.Fatalf("stored %q", data)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, "properties", "abc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected only the stored file, found %d entries", len(entries))
+	}
+
+	if got := store.URL(key); got != "http://localhost:8080/uploads/properties/abc/photo.jpg" {
+		t.Fatalf("URL = %q", got)
+	}
+
+	if err := store.Delete(key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "properties", "abc", "photo.jpg")); !os.IsNotExist(err) {
+		t.Fatalf("file still exists after Delete: %v", err)
+	}
+	if err := store.Delete(key); err != nil {
+		t.Fatalf("Delete of a missing file: %v", err)
+	}
+}
+
+func TestLocalStoreRejectsUnsafeKeys(t *testing.T) {
+	store := NewLocalStore(t.TempDir(), "/uploads")
+
+	for _, key := range []string{"", "/etc/passwd", "../secret", "a/../../b", "a//b", "a/./b", `a\b`, ".hidden", "a/"} {
+		if err := store.Put(key, strings.NewReader("x"), "text/plain"); err != ErrInvalidKey {
+			t.Errorf("Put(%q) = %v, want ErrInvalidKey", key, err)
+		}
+		if err := store.Delete(key); err != ErrInvalidKey {
+			t.Errorf("Delete(%q) = %v, want ErrInvalidKey", key, err)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+// Package textsearch prepares listing text for MongoDB's text index and
+// highlights matches in search results.
+//
+// MongoDB's tokenizer splits on whitespace and punctuation, so a Chinese or
+// Japanese sentence ends up as a single token and a search for part of it
+// never matches. To work around this, CJK runs are broken into overlapping
+// unigrams and bigrams which are stored next to the listing and indexed
+// alongside the original fields. Queries are tokenized the same way.
+package textsearch
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	highlightOpen  = "<mark>"
+	highlightClose = "</mark>"
+)
+
+// IsCJK reports whether r is a Chinese, Japanese or Korean character.
+func IsCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// segment is a maximal run of either word characters or CJK characters.
+type segment struct {
+	text string
+	cjk  bool
+}
+
+func segments(s string) []segment {
+	var segs []segment
+	var current []rune
+	cjk := false
+
+	flush := func() {
+		if len(current) > 0 {
+			segs = append(segs, segment{text: string(current), cjk: cjk})
+			current = current[:0]
+		}
+	}
+
+	for _, r := range s {
+		switch {
+		case IsCJK(r):
+			if !cjk {
+				flush()
+			}
+			cjk = true
+			current = append(current, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if cjk {
+				flush()
+			}
+			cjk = false
+			current = append(current, unicode.ToLower(r))
+		default:
+			flush()
+		}
+	}
+	flush()
+	return segs
+}
+
+// Tokenize splits a search query into terms: lower-cased words for
+// alphabetic scripts and bigrams for CJK runs. A single CJK character is
+// kept as a unigram.
+func Tokenize(s string) []string {
+	var tokens []string
+	seen := make(map[string]bool)
+	add := func(token string) {
+		if !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+
+	for _, seg := range segments(s) {
+		if !seg.cjk {
+			add(seg.text)
+			continue
+		}
+		runes := []rune(seg.text)
+		if len(runes) == 1 {
+			add(seg.text)
+			continue
+		}
+		for i := 0; i+1 < len(runes); i++ {
+			add(string(runes[i : i+2]))
+		}
+	}
+	return tokens
+}
+
+// Query returns the $search string for a user query. Only plain terms are
+// emitted, so MongoDB's phrase and negation syntax cannot be injected.
+func Query(s string) string {
+	return strings.Join(Tokenize(s), " ")
+}
+
+// IndexTerms returns the CJK unigrams and bigrams found in texts. Words in
+// other scripts are left out because the text index already sees them in the
+// original fields.
+func IndexTerms(texts ...string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	add := func(term string) {
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+
+	for _, text := range texts {
+		for _, seg := range segments(text) {
+			if !seg.cjk {
+				continue
+			}
+			runes := []rune(seg.text)
+			for i := range runes {
+				add(string(runes[i]))
+				if i+1 < len(runes) {
+					add(string(runes[i : i+2]))
+				}
+			}
+		}
+	}
+	return terms
+}
+
+// Highlight returns an HTML-escaped excerpt of text of at most maxRunes
+// characters around the first match, with every match of tokens wrapped in
+// <mark> tags. Word tokens match case-insensitively at the start of a word,
+// so "apartment" also marks "Apartments". It returns "" when nothing matches.
+func Highlight(text string, tokens []string, maxRunes int) string {
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	if len(lower) != len(runes) {
+		// Lower-casing changed the length; fall back to exact matching.
+		lower = runes
+	}
+
+	matches := findMatches(lower, tokens)
+	if len(matches) == 0 {
+		return ""
+	}
+
+	start, end := 0, len(runes)
+	if maxRunes > 0 && len(runes) > maxRunes {
+		start = matches[0][0] - maxRunes/4
+		if start < 0 {
+			start = 0
+		}
+		end = start + maxRunes
+		if end > len(runes) {
+			end = len(runes)
+			start = end - maxRunes
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, m := range matches {
+		if m[1] <= start || m[0] >= end {
+			continue
+		}
+		from, to := max(m[0], start), min(m[1], end)
+		b.WriteString(html.EscapeString(string(runes[pos:from])))
+		b.WriteString(highlightOpen)
+		b.WriteString(html.EscapeString(string(runes[from:to])))
+		b.WriteString(highlightClose)
+		pos = to
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:end])))
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// findMatches returns the sorted, merged [start, end) rune ranges of text
+// matched by tokens.
+func findMatches(text []rune, tokens []string) [][2]int {
+	var matches [][2]int
+	for _, token := range tokens {
+		t := []rune(token)
+		if len(t) == 0 {
+			continue
+		}
+		word := !IsCJK(t[0])
+		for i := 0; i+len(t) <= len(text); i++ {
+			if word && i > 0 && isWordRune(text[i-1]) {
+				continue
+			}
+			if string(text[i:i+len(t)]) == token {
+				end := i + len(t)
+				if word {
+					for end < len(text) && isWordRune(text[end]) {
+						end++
+					}
+				}
+				matches = append(matches, [2]int{i, end})
+			}
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i][0] < matches[j][0] })
+
+	var merged [][2]int
+	for _, m := range matches {
+		if n := len(merged); n > 0 && m[0] <= merged[n-1][1] {
+			if m[1] > merged[n-1][1] {
+				merged[n-1][1] = m[1]
+			}
+			continue
+		}
+		merged = append(merged, m)
+	}
+	return merged
+}
+
+func isWordRune(r rune) bool {
+	return !IsCJK(r) && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
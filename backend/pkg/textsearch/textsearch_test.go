@@ -0,0 +1,96 @@
+package textsearch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"Sunny Apartment", []string{"sunny", "apartment"}},
+		{"朝阳区两居室", []string{"朝阳", "阳区", "区两", "两居", "居室"}},
+		{"近地铁 2-bedroom 房", []string{"近地", "地铁", "2", "bedroom", "房"}},
+		{"北京apartment北京", []string{"北京", "apartment"}},
+		{`"quoted" -negated`, []string{"quoted", "negated"}},
+		{"  ", nil},
+	}
+
+	for _, tt := range tests {
+		if got := Tokenize(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	if got := Query(`"loft" -studio 海淀`); got != "loft studio 海淀" {
+		t.Fatalf("Query = %q", got)
+	}
+}
+
+func TestIndexTerms(t *testing.T) {
+	got := IndexTerms("Modern 公寓", "近公园")
+	want := []string{"公", "公寓", "寓", "近", "近公", "公园", "园"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("IndexTerms = %q, want %q", got, want)
+	}
+
+	if got := IndexTerms("Only English words"); got != nil {
+		t.Fatalf("IndexTerms without CJK = %q", got)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		text   string
+		tokens []string
+		max    int
+		want   string
+	}{
+		{
+			"Bright Apartments near the park",
+			[]string{"apartment"},
+			0,
+			"Bright <mark>Apartments</mark> near the park",
+		},
+		{
+			"朝阳区精装两居室，近地铁",
+			Tokenize("两居室 地铁"),
+			0,
+			"朝阳区精装<mark>两居室</mark>，近<mark>地铁</mark>",
+		},
+		{
+			"No <b>match</b> here",
+			[]string{"missing"},
+			0,
+			"",
+		},
+		{
+			"Fish & <chips> shop",
+			[]string{"chips"},
+			0,
+			"Fish &amp; &lt;<mark>chips</mark>&gt; shop",
+		},
+		{
+			"one two three four five six seven eight nine ten",
+			[]string{"six"},
+			20,
+			"…five <mark>six</mark> seven eight…",
+		},
+		{
+			"supermarket market",
+			[]string{"market"},
+			0,
+			"supermarket <mark>market</mark>",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := Highlight(tt.text, tt.tokens, tt.max); got != tt.want {
+			t.Errorf("Highlight(%q, %q) = %q, want %q", tt.text, tt.tokens, got, tt.want)
+		}
+	}
+}
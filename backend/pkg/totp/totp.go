@@ -0,0 +1,150 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Algorithm is the HMAC hash used to derive codes
+type Algorithm string
+
+const (
+	SHA1   Algorithm = "SHA1"
+	SHA256 Algorithm = "SHA256"
+	SHA512 Algorithm = "SHA512"
+)
+
+// Options configures code generation. The zero value is replaced by the
+// defaults used by common authenticator apps: SHA1, 6 digits, 30 seconds.
+type Options struct {
+	Algorithm Algorithm
+	Digits    int
+	Period    time.Duration
+}
+
+// DefaultOptions returns the options understood by every authenticator app
+func DefaultOptions() Options {
+	return Options{Algorithm: SHA1, Digits: 6, Period: 30 * time.Second}
+}
+
+func (o Options) withDefaults() Options {
+	d := DefaultOptions()
+	if o.Algorithm == "" {
+		o.Algorithm = d.Algorithm
+	}
+	if o.Digits == 0 {
+		o.Digits = d.Digits
+	}
+	if o.Period == 0 {
+		o.Period = d.Period
+	}
+	return o
+}
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a random 160-bit secret encoded as unpadded base32
+func GenerateSecret() (string, error) {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return b32.EncodeToString(buf), nil
+}
+
+// DecodeSecret decodes a base32 secret, ignoring case, spaces and padding
+func DecodeSecret(secret string) ([]byte, error) {
+	cleaned := strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	return b32.DecodeString(strings.TrimRight(cleaned, "="))
+}
+
+// Step returns the RFC 6238 time step counter for t
+func Step(t time.Time, opts Options) uint64 {
+	opts = opts.withDefaults()
+	return uint64(t.Unix()) / uint64(opts.Period/time.Second)
+}
+
+// CodeAt returns the code for key at the given time step (RFC 4226 HOTP)
+func CodeAt(key []byte, step uint64, opts Options) string {
+	opts = opts.withDefaults()
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], step)
+
+	mac := hmac.New(hashFunc(opts.Algorithm), key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	binCode := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < opts.Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", opts.Digits, binCode%mod)
+}
+
+// Code returns the code for key at time t
+func Code(key []byte, t time.Time, opts Options) string {
+	return CodeAt(key, Step(t, opts), opts)
+}
+
+// Validate checks code against the steps around t, allowing skew steps of
+// clock drift either way. It returns the matching step so callers can reject
+// replays of the same code.
+func Validate(key []byte, code string, t time.Time, skew int, opts Options) (uint64, bool) {
+	opts = opts.withDefaults()
+	if len(code) != opts.Digits {
+		return 0, false
+	}
+
+	current := Step(t, opts)
+	for i := -skew; i <= skew; i++ {
+		if i < 0 && uint64(-i) > current {
+			continue
+		}
+		step := current + uint64(i)
+		if subtle.ConstantTimeCompare([]byte(CodeAt(key, step, opts)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// KeyURI returns the otpauth:// URI that authenticator apps read from a QR code
+func KeyURI(issuer, account, secret string, opts Options) string {
+	opts = opts.withDefaults()
+
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", string(opts.Algorithm))
+	params.Set("digits", fmt.Sprint(opts.Digits))
+	params.Set("period", fmt.Sprint(int(opts.Period/time.Second)))
+
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+func hashFunc(algorithm Algorithm) func() hash.Hash {
+	switch algorithm {
+	case SHA256:
+		return sha256.New
+	case SHA512:
+		return sha512.New
+	default:
+		return sha1.New
+	}
+}
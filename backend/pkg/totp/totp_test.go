@@ -0,0 +1,100 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test vectors from RFC 6238 Appendix B. Each algorithm uses an ASCII seed of
+// the digits 1234567890 repeated to the hash's block size.
+var (
+	seedSHA1   = []byte("12345678901234567890")
+	seedSHA256 = []byte("12345678901234567890123456789012")
+	seedSHA512 = []byte("1234567890123456789012345678901234567890123456789012345678901234")
+)
+
+func TestCodeRFC6238Vectors(t *testing.T) {
+	tests := []struct {
+		unix      int64
+		algorithm Algorithm
+		seed      []byte
+		want      string
+	}{
+		{59, SHA1, seedSHA1, "94287082"},
+		{59, SHA256, seedSHA256, "46119246"},
+		{59, SHA512, seedSHA512, "90693936"},
+		{1111111109, SHA1, seedSHA1, "07081804"},
+		{1111111109, SHA256, seedSHA256, "68084774"},
+		{1111111109, SHA512, seedSHA512, "25091201"},
+		{1111111111, SHA1, seedSHA1, "14050471"},
+		{1111111111, SHA256, seedSHA256, "67062674"},
+		{1111111111, SHA512, seedSHA512, "99943326"},
+		{1234567890, SHA1, seedSHA1, "89005924"},
+		{1234567890, SHA256, seedSHA256, "91819424"},
+		{1234567890, SHA512, seedSHA512, "93441116"},
+		{2000000000, SHA1, seedSHA1, "69279037"},
+		{2000000000, SHA256, seedSHA256, "90698825"},
+		{2000000000, SHA512, seedSHA512, "38618901"},
+		{20000000000, SHA1, seedSHA1, "65353130"},
+		{20000000000, SHA256, seedSHA256, "77737706"},
+		{20000000000, SHA512, seedSHA512, "47863826"},
+	}
+
+	for _, tt := range tests {
+		opts := Options{Algorithm: tt.algorithm, Digits: 8, Period: 30 * time.Second}
+		got := Code(tt.seed, time.Unix(tt.unix, 0), opts)
+		if got != tt.want {
+			t.Errorf("Code(%s, %d) = %s, want %s", tt.algorithm, tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateAllowsSkew(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	opts := DefaultOptions()
+	previous := Code(seedSHA1, now.Add(-30*time.Second), opts)
+
+	step, ok := Validate(seedSHA1, previous, now, 1, opts)
+	if !ok {
+		t.Fatal("expected code from the previous step to be accepted")
+	}
+	if step != Step(now, opts)-1 {
+		t.Errorf("step = %d, want %d", step, Step(now, opts)-1)
+	}
+
+	if _, ok := Validate(seedSHA1, previous, now, 0, opts); ok {
+		t.Error("expected code from the previous step to be rejected without skew")
+	}
+	if _, ok := Validate(seedSHA1, "12345", now, 1, opts); ok {
+		t.Error("expected code with the wrong length to be rejected")
+	}
+}
+
+func TestSecretRoundTrip(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := DecodeSecret(strings.ToLower(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(key) != 20 {
+		t.Errorf("len(key) = %d, want 20", len(key))
+	}
+}
+
+func TestKeyURI(t *testing.T) {
+	uri := KeyURI("RentHelp", "jane@example.com", "JBSWY3DPEHPK3PXP", DefaultOptions())
+
+	if !strings.HasPrefix(uri, "otpauth://totp/RentHelp:jane@example.com?") {
+		t.Errorf("unexpected label in %s", uri)
+	}
+	for _, part := range []string{"secret=JBSWY3DPEHPK3PXP", "issuer=RentHelp", "digits=6", "period=30", "algorithm=SHA1"} {
+		if !strings.Contains(uri, part) {
+			t.Errorf("%s missing %s", uri, part)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Package useragent extracts a short, human readable device description from
+// a User-Agent header. It only recognises the common browsers and operating
+// systems and falls back to "Unknown" for everything else.
+package useragent
+
+import "strings"
+
+// Info describes the client that sent a request.
+type Info struct {
+	Browser    string `json:"browser"`
+	OS         string `json:"os"`
+	DeviceType string `json:"device_type"` // "desktop", "mobile", "tablet", "bot", "unknown"
+}
+
+// String returns a description such as "Chrome on macOS".
+func (i Info) String() string {
+	return i.Browser + " on " + i.OS
+}
+
+// Order matters: several browsers include the tokens of the ones they are
+// based on, e.g. Edge and Opera also send "Chrome" and "Safari".
+var browsers = []struct {
+	token string
+	name  string
+}{
+	{"Edg/", "Edge"},
+	{"EdgA/", "Edge"},
+	{"EdgiOS/", "Edge"},
+	{"OPR/", "Opera"},
+	{"SamsungBrowser/", "Samsung Internet"},
+	{"Firefox/", "Firefox"},
+	{"FxiOS/", "Firefox"},
+	{"CriOS/", "Chrome"},
+	{"Chrome/", "Chrome"},
+	{"Safari/", "Safari"},
+	{"okhttp/", "Android app"},
+	{"CFNetwork/", "iOS app"},
+	{"curl/", "curl"},
+	{"PostmanRuntime/", "Postman"},
+}
+
+var systems = []struct {
+	token string
+	name  string
+}{
+	{"Windows", "Windows"},
+	{"iPhone", "iOS"},
+	{"iPad", "iPadOS"},
+	{"Android", "Android"},
+	{"CrOS", "ChromeOS"},
+	{"Mac OS X", "macOS"},
+	{"Macintosh", "macOS"},
+	{"Darwin", "macOS"},
+	{"Linux", "Linux"},
+}
+
+// Parse describes the client identified by userAgent.
+func Parse(userAgent string) Info {
+	info := Info{Browser: "Unknown", OS: "Unknown", DeviceType: "unknown"}
+	if userAgent == "" {
+		return info
+	}
+
+	for _, b := range browsers {
+		if strings.Contains(userAgent, b.token) {
+			info.Browser = b.name
+			break
+		}
+	}
+	for _, s := range systems {
+		if strings.Contains(userAgent, s.token) {
+			info.OS = s.name
+			break
+		}
+	}
+
+	lower := strings.ToLower(userAgent)
+	switch {
+	case strings.Contains(lower, "bot") || strings.Contains(lower, "spider") || strings.Contains(lower, "crawler"):
+		info.DeviceType = "bot"
+	case strings.Contains(userAgent, "iPad") || (strings.Contains(userAgent, "Android") && !strings.Contains(userAgent, "Mobile")):
+		info.DeviceType = "tablet"
+	case strings.Contains(userAgent, "Mobile") || strings.Contains(userAgent, "iPhone"):
+		info.DeviceType = "mobile"
+	case info.OS == "Windows" || info.OS == "macOS" || info.OS == "Linux" || info.OS == "ChromeOS":
+		info.DeviceType = "desktop"
+	}
+	return info
+}
@@ -3,7 +3,9 @@ package validation
 import (
 	"errors"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 	"unicode"
 )
 
@@ -132,21 +134,21 @@ func (v *Validator) ValidateMinLength(field, value string, minLength int, fieldN
 	}
 
 	if len(value) < minLength {
-		v.AddError(field, fieldName+" must be at least "+string(rune(minLength))+" characters long")
+		v.AddError(field, fieldName+" must be at least "+strconv.Itoa(minLength)+" characters long")
 	}
 }
 
 // ValidateMaxLength validates maximum length
 func (v *Validator) ValidateMaxLength(field, value string, maxLength int, fieldName string) {
 	if len(value) > maxLength {
-		v.AddError(field, fieldName+" must be no more than "+string(rune(maxLength))+" characters long")
+		v.AddError(field, fieldName+" must be no more than "+strconv.Itoa(maxLength)+" characters long")
 	}
 }
 
 // ValidateNumericRange validates that a number is within range
 func (v *Validator) ValidateNumericRange(field string, value, min, max float64, fieldName string) {
 	if value < min || value > max {
-		v.AddError(field, fieldName+" must be between "+string(rune(int(min)))+" and "+string(rune(int(max))))
+		v.AddError(field, fieldName+" must be between "+strconv.FormatFloat(min, 'f', -1, 64)+" and "+strconv.FormatFloat(max, 'f', -1, 64))
 	}
 }
 
@@ -190,6 +192,47 @@ func (v *Validator) ValidateOneOf(field, value string, allowed []string, fieldNa
 	v.AddError(field, fieldName+" must be one of: "+strings.Join(allowed, ", "))
 }
 
+// ValidateCurrencyCode validates a three-letter ISO 4217 currency code
+func (v *Validator) ValidateCurrencyCode(field, code string) {
+	if code == "" {
+		return // Skip if empty, use ValidateRequired separately
+	}
+
+	currencyRegex := regexp.MustCompile(`^[A-Z]{3}$`)
+	if !currencyRegex.MatchString(code) {
+		v.AddError(field, "Currency must be a three-letter ISO 4217 code")
+	}
+}
+
+// ValidateTimeOfDay validates a 24-hour HH:MM time
+func (v *Validator) ValidateTimeOfDay(field, value, fieldName string) {
+	if value == "" {
+		return // Skip if empty, use ValidateRequired separately
+	}
+
+	if _, err := time.Parse("15:04", value); err != nil {
+		v.AddError(field, fieldName+" must be in HH:MM format")
+	}
+}
+
+// ValidateTimezone validates an IANA time zone name
+func (v *Validator) ValidateTimezone(field, tz string) {
+	if tz == "" {
+		return // Skip if empty, use ValidateRequired separately
+	}
+
+	if _, err := time.LoadLocation(tz); err != nil {
+		v.AddError(field, "Invalid time zone")
+	}
+}
+
+// ValidateDateRange validates that end is after start
+func (v *Validator) ValidateDateRange(field string, start, end time.Time) {
+	if !end.After(start) {
+		v.AddError(field, "End date must be after start date")
+	}
+}
+
 // User validation functions
 
 // ValidateUserRegistration validates user registration data
@@ -0,0 +1,67 @@
+package validation
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLengthMessagesIncludeNumbers(t *testing.T) {
+	v := NewValidator()
+	v.ValidateMinLength("bio", "a", 20, "Bio")
+	v.ValidateMaxLength("title", "abcdef", 5, "Title")
+	v.ValidateNumericRange("price", -1, 0, 1000000, "Price")
+
+	errs := v.GetErrors()
+	want := []string{
+		"Bio must be at least 20 characters long",
+		"Title must be no more than 5 characters long",
+		"Price must be between 0 and 1000000",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("got %d errors, want %d: %v", len(errs), len(want), errs)
+	}
+	for i, msg := range want {
+		if errs[i].Message != msg {
+			t.Errorf("error %d = %q, want %q", i, errs[i].Message, msg)
+		}
+	}
+}
+
+func TestFormatValidators(t *testing.T) {
+	tests := []struct {
+		name  string
+		check func(v *Validator)
+		ok    bool
+	}{
+		{"currency", func(v *Validator) { v.ValidateCurrencyCode("currency", "EUR") }, true},
+		{"lowercase currency", func(v *Validator) { v.ValidateCurrencyCode("currency", "eur") }, false},
+		{"time of day", func(v *Validator) { v.ValidateTimeOfDay("check_in_time", "14:30", "Check-in time") }, true},
+		{"bad time of day", func(v *Validator) { v.ValidateTimeOfDay("check_in_time", "25:00", "Check-in time") }, false},
+		{"timezone", func(v *Validator) { v.ValidateTimezone("timezone", "UTC") }, true},
+		{"bad timezone", func(v *Validator) { v.ValidateTimezone("timezone", "Mars/Olympus") }, false},
+		{"empty is skipped", func(v *Validator) { v.ValidateCurrencyCode("currency", "") }, true},
+	}
+
+	for _, tt := range tests {
+		v := NewValidator()
+		tt.check(v)
+		if v.HasErrors() == tt.ok {
+			t.Errorf("%s: HasErrors() = %v, want %v", tt.name, v.HasErrors(), !tt.ok)
+		}
+	}
+}
+
+func TestValidateDateRange(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	v := NewValidator()
+	v.ValidateDateRange("end_date", start, start.AddDate(0, 0, 1))
+	if v.HasErrors() {
+		t.Fatalf("unexpected errors: %v", v.GetErrors())
+	}
+
+	v.ValidateDateRange("end_date", start, start)
+	if !v.HasErrors() {
+		t.Fatal("expected an error for an empty range")
+	}
+}
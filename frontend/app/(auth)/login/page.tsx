@@ -8,10 +8,13 @@ import { Input } from '@/components/ui/input'
 import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/card'
 import { authApi } from '@/lib/api'
 import { useAuthStore } from '@/store/auth'
+import { TokenResponse } from '@/types'
 
 export default function LoginPage() {
   const [email, setEmail] = useState('')
   const [password, setPassword] = useState('')
+  const [challengeToken, setChallengeToken] = useState('')
+  const [code, setCode] = useState('')
   const [loading, setLoading] = useState(false)
   const [error, setError] = useState('')
   const router = useRouter()
@@ -23,15 +26,18 @@ export default function LoginPage() {
     setError('')
 
     try {
+      if (challengeToken) {
+        completeLogin(await authApi.verifyTwoFactor({ challenge_token: challengeToken, code }))
+        return
+      }
+
       const response = await authApi.login({ email, password })
-      
-      // Use user data from login response
-      if (response.user) {
-        login(response.user, response.access_token)
-        router.push('/dashboard')
-      } else {
-        setError('登录响应格式错误')
+      if ('two_factor_required' in response) {
+        // Ask for the authenticator code before issuing tokens
+        setChallengeToken(response.challenge_token)
+        return
       }
+      completeLogin(response)
     } catch (err: any) {
       console.error('Login error:', err)
       setError(err.response?.data?.error || '登录失败，请重试')
@@ -40,6 +46,16 @@ export default function LoginPage() {
     }
   }
 
+  const completeLogin = (response: TokenResponse) => {
+    // Use user data from login response
+    if (response.user) {
+      login(response.user, response.access_token, response.refresh_token)
+      router.push('/dashboard')
+    } else {
+      setError('登录响应格式错误')
+    }
+  }
+
   return (
     <div className="min-h-screen flex items-center justify-center bg-gray-50 py-12 px-4 sm:px-6 lg:px-8">
       <Card className="w-full max-w-md">
@@ -57,40 +73,60 @@ export default function LoginPage() {
               </div>
             )}
             
-            <div className="space-y-2">
-              <label htmlFor="email" className="text-sm font-medium">
-                邮箱
-              </label>
-              <Input
-                id="email"
-                type="email"
-                placeholder="请输入邮箱"
-                value={email}
-                onChange={(e) => setEmail(e.target.value)}
-                required
-              />
-            </div>
+            {challengeToken ? (
+              <div className="space-y-2">
+                <label htmlFor="code" className="text-sm font-medium">
+                  验证码
+                </label>
+                <Input
+                  id="code"
+                  type="text"
+                  inputMode="numeric"
+                  autoComplete="one-time-code"
+                  placeholder="请输入身份验证器中的验证码或恢复码"
+                  value={code}
+                  onChange={(e) => setCode(e.target.value)}
+                  required
+                />
+              </div>
+            ) : (
+              <>
+                <div className="space-y-2">
+                  <label htmlFor="email" className="text-sm font-medium">
+                    邮箱
+                  </label>
+                  <Input
+                    id="email"
+                    type="email"
+                    placeholder="请输入邮箱"
+                    value={email}
+                    onChange={(e) => setEmail(e.target.value)}
+                    required
+                  />
+                </div>
 
-            <div className="space-y-2">
-              <label htmlFor="password" className="text-sm font-medium">
-                密码
-              </label>
-              <Input
-                id="password"
-                type="password"
-                placeholder="请输入密码"
-                value={password}
-                onChange={(e) => setPassword(e.target.value)}
-                required
-              />
-            </div>
+                <div className="space-y-2">
+                  <label htmlFor="password" className="text-sm font-medium">
+                    密码
+                  </label>
+                  <Input
+                    id="password"
+                    type="password"
+                    placeholder="请输入密码"
+                    value={password}
+                    onChange={(e) => setPassword(e.target.value)}
+                    required
+                  />
+                </div>
+              </>
+            )}
 
             <Button 
               type="submit" 
               className="w-full" 
               disabled={loading}
             >
-              {loading ? '登录中...' : '登录'}
+              {loading ? '登录中...' : challengeToken ? '验证' : '登录'}
             </Button>
           </form>
 
@@ -1,5 +1,6 @@
-import axios from 'axios';
-import { TokenResponse, LoginRequest, RegisterRequest, User, Property, Booking } from '@/types';
+import axios, { AxiosError, InternalAxiosRequestConfig } from 'axios';
+import { TokenResponse, LoginRequest, LoginResponse, TwoFactorLoginRequest, RegisterRequest, User, Property, Booking, ListResponse } from '@/types';
+import { useAuthStore } from '@/store/auth';
 
 const API_BASE_URL = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8081/api/v1';
 
@@ -19,16 +20,60 @@ api.interceptors.request.use((config) => {
   return config;
 });
 
+// Access tokens are short-lived. On a 401 the refresh token is rotated once
+// and the request retried; concurrent requests share the same refresh.
+let refreshing: Promise<string> | null = null;
+
+const rotateTokens = async (): Promise<string> => {
+  const refreshToken = localStorage.getItem('refreshToken');
+  if (!refreshToken) {
+    throw new Error('No refresh token');
+  }
+  // Plain axios, so that a failing refresh does not go through this interceptor.
+  const { data } = await axios.post<TokenResponse>(`${API_BASE_URL}/auth/refresh`, { refresh_token: refreshToken });
+  useAuthStore.getState().setTokens(data.access_token, data.refresh_token);
+  return data.access_token;
+};
+
+api.interceptors.response.use(
+  (response) => response,
+  async (error: AxiosError) => {
+    const config = error.config as (InternalAxiosRequestConfig & { _retried?: boolean }) | undefined;
+    if (error.response?.status !== 401 || !config || config._retried || config.url?.startsWith('/auth/')) {
+      return Promise.reject(error);
+    }
+    config._retried = true;
+
+    if (!refreshing) {
+      refreshing = rotateTokens().finally(() => {
+        refreshing = null;
+      });
+    }
+
+    try {
+      const token = await refreshing;
+      config.headers.Authorization = `Bearer ${token}`;
+      return api(config);
+    } catch {
+      useAuthStore.getState().logout();
+      return Promise.reject(error);
+    }
+  }
+);
+
 // Auth API
 export const authApi = {
-  login: (data: LoginRequest): Promise<TokenResponse> =>
+  login: (data: LoginRequest): Promise<LoginResponse> =>
     api.post('/auth/login', data).then(res => res.data),
+
+  verifyTwoFactor: (data: TwoFactorLoginRequest): Promise<TokenResponse> =>
+    api.post('/auth/2fa/verify', data).then(res => res.data),
   
   register: (data: RegisterRequest): Promise<{ message: string; user: User }> =>
     api.post('/auth/register', data).then(res => res.data),
   
-  refreshToken: (): Promise<TokenResponse> =>
-    api.post('/auth/refresh').then(res => res.data),
+  refreshToken: (refreshToken: string): Promise<TokenResponse> =>
+    api.post('/auth/refresh', { refresh_token: refreshToken }).then(res => res.data),
 };
 
 // User API
@@ -44,11 +89,12 @@ export const userApi = {
 export const propertyApi = {
   getProperties: (params?: {
     limit?: number;
-    skip?: number;
+    cursor?: string;
+    sort?: string;
     city?: string;
     type?: string;
   }): Promise<Property[]> =>
-    api.get('/properties', { params }).then(res => res.data),
+    api.get<ListResponse<Property>>('/properties', { params }).then(res => res.data.data),
   
   getProperty: (id: string): Promise<Property> =>
     api.get(`/properties/${id}`).then(res => res.data),
@@ -67,10 +113,11 @@ export const propertyApi = {
 export const bookingApi = {
   getBookings: (params?: {
     limit?: number;
-    skip?: number;
+    cursor?: string;
+    sort?: string;
     status?: string;
   }): Promise<Booking[]> =>
-    api.get('/bookings', { params }).then(res => res.data),
+    api.get<ListResponse<Booking>>('/bookings', { params }).then(res => res.data.data),
   
   getBooking: (id: string): Promise<Booking> =>
     api.get(`/bookings/${id}`).then(res => res.data),
@@ -82,7 +82,27 @@ export interface RegisterRequest {
 
 export interface TokenResponse {
   access_token: string;
-  refresh_token?: string;
+  refresh_token: string;
   expires_in: number;
   user?: User;
 }
+
+// Returned by login instead of tokens when the user has two-factor
+// authentication enabled; complete it with POST /auth/2fa/verify.
+export interface TwoFactorChallenge {
+  two_factor_required: true;
+  challenge_token: string;
+}
+
+export type LoginResponse = TokenResponse | TwoFactorChallenge;
+
+export interface TwoFactorLoginRequest {
+  challenge_token: string;
+  code: string;
+}
+
+export interface ListResponse<T> {
+  data: T[];
+  next_cursor: string | null;
+  total: number;
+}